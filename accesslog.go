@@ -0,0 +1,223 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventAccess thrown when RPC call is complete and access log is enabled, event context is *AccessEntry.
+const EventAccess = iota + 9200
+
+// AccessEntry describes single RPC call.
+type AccessEntry struct {
+	// Level of the entry as configured in access log.
+	Level string `json:"level"`
+
+	// Time when the call started.
+	Time time.Time `json:"time"`
+
+	// Method is full name of RPC method.
+	Method string `json:"method"`
+
+	// Peer address of the client.
+	Peer string `json:"peer"`
+
+	// Code is call status code.
+	Code string `json:"code"`
+
+	// Duration of the call in seconds.
+	Duration float64 `json:"duration"`
+
+	// RequestSize is total size of received messages in bytes.
+	RequestSize int `json:"request_size"`
+
+	// ResponseSize is total size of sent messages in bytes.
+	ResponseSize int `json:"response_size"`
+
+	// Slow indicates that call took longer than configured threshold.
+	Slow bool `json:"slow,omitempty"`
+
+	// Metadata contains incoming metadata when enabled in access log.
+	Metadata map[string][]string `json:"metadata,omitempty"`
+}
+
+// accessLog reports every RPC call using throw function or writes it to configured output.
+type accessLog struct {
+	cfg   AccessLog
+	throw func(event int, ctx interface{})
+	mu    sync.Mutex
+	out   io.Writer
+}
+
+// newAccessLog creates access log writing entries to configured output, if any.
+func newAccessLog(cfg AccessLog, throw func(event int, ctx interface{})) (*accessLog, error) {
+	a := &accessLog{cfg: cfg, throw: throw}
+
+	switch cfg.Output {
+	case "":
+	case "stdout":
+		a.out = os.Stdout
+	case "stderr":
+		a.out = os.Stderr
+	default:
+		f, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open access log '%s': %s", cfg.Output, err)
+		}
+
+		a.out = f
+	}
+
+	return a, nil
+}
+
+// report creates access entry and reports it.
+func (a *accessLog) report(ctx context.Context, method string, start time.Time, recv, sent int, err error) {
+	e := &AccessEntry{
+		Level:        a.cfg.Level,
+		Time:         start,
+		Method:       method,
+		Peer:         "unknown",
+		Code:         status.Code(err).String(),
+		Duration:     time.Since(start).Seconds(),
+		RequestSize:  recv,
+		ResponseSize: sent,
+	}
+
+	if pr, ok := peer.FromContext(ctx); ok && pr.Addr != nil {
+		e.Peer = pr.Addr.String()
+	}
+
+	if a.cfg.SlowThreshold != 0 && time.Since(start) > a.cfg.SlowThreshold {
+		e.Slow = true
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		e.Metadata = a.metadata(md)
+	}
+
+	if a.out == nil {
+		a.throw(EventAccess, e)
+		return
+	}
+
+	a.write(e)
+}
+
+// metadata returns metadata to be logged, nil when metadata logging is disabled.
+func (a *accessLog) metadata(md metadata.MD) map[string][]string {
+	if !a.cfg.Metadata && len(a.cfg.MetadataKeys) == 0 {
+		return nil
+	}
+
+	logged := make(map[string][]string)
+	if a.cfg.Metadata {
+		for k, v := range md {
+			logged[k] = encodeMetadata(k, v)
+		}
+
+		return logged
+	}
+
+	for _, k := range a.cfg.MetadataKeys {
+		k = strings.ToLower(k)
+		if v := md.Get(k); len(v) != 0 {
+			logged[k] = encodeMetadata(k, v)
+		}
+	}
+
+	return logged
+}
+
+// write writes entry to the output as JSON line.
+func (a *accessLog) write(e *AccessEntry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.out.Write(append(data, '\n'))
+}
+
+// unaryInterceptor logs unary calls.
+func (a *accessLog) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	a.report(ctx, info.FullMethod, start, messageSize(req), messageSize(resp), err)
+
+	return resp, err
+}
+
+// streamInterceptor logs streaming calls.
+func (a *accessLog) streamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+	s := &accessStream{ServerStream: ss}
+	err := handler(srv, s)
+	a.report(ss.Context(), info.FullMethod, start, s.recv, s.sent, err)
+
+	return err
+}
+
+// accessStream counts size of stream messages (used by access log and call events).
+type accessStream struct {
+	grpc.ServerStream
+	recv, sent int
+}
+
+// SendMsg sends message and counts it's size.
+func (s *accessStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+
+	s.sent += messageSize(m)
+	return nil
+}
+
+// RecvMsg receives message and counts it's size.
+func (s *accessStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	s.recv += messageSize(m)
+	return nil
+}
+
+// messageSize returns size of proxied or protobuf message.
+func messageSize(m interface{}) int {
+	switch m := m.(type) {
+	case rawMessage:
+		return len(m)
+	case *rawMessage:
+		return len(*m)
+	case proto.Message:
+		return proto.Size(m)
+	}
+
+	return 0
+}
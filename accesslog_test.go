@@ -0,0 +1,155 @@
+package grpc
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_AccessLog_Unary(t *testing.T) {
+	var entry *AccessEntry
+	a := &accessLog{
+		cfg: AccessLog{Level: "info", Metadata: true},
+		throw: func(event int, ctx interface{}) {
+			assert.Equal(t, EventAccess, event)
+			entry = ctx.(*AccessEntry)
+		},
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("key", "value", "data-bin", "\x01"))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9000}})
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+	_, err := a.unaryInterceptor(ctx, rawMessage("request"), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return rawMessage("ok"), nil
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "info", entry.Level)
+	assert.Equal(t, "/service.Test/Echo", entry.Method)
+	assert.Equal(t, "127.0.0.1:9000", entry.Peer)
+	assert.Equal(t, "OK", entry.Code)
+	assert.Equal(t, 7, entry.RequestSize)
+	assert.Equal(t, 2, entry.ResponseSize)
+	assert.False(t, entry.Slow)
+	assert.Equal(t, []string{"value"}, entry.Metadata["key"])
+	assert.Equal(t, []string{"AQ=="}, entry.Metadata["data-bin"])
+}
+
+func Test_AccessLog_Slow(t *testing.T) {
+	var entry *AccessEntry
+	a := &accessLog{
+		cfg:   AccessLog{Level: "warn", SlowThreshold: time.Millisecond},
+		throw: func(event int, ctx interface{}) { entry = ctx.(*AccessEntry) },
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("key", "value"))
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+	_, err := a.unaryInterceptor(ctx, rawMessage("request"), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		time.Sleep(time.Millisecond * 5)
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	assert.Error(t, err)
+
+	assert.Equal(t, "warn", entry.Level)
+	assert.Equal(t, "unknown", entry.Peer)
+	assert.Equal(t, "NotFound", entry.Code)
+	assert.Equal(t, 0, entry.ResponseSize)
+	assert.True(t, entry.Slow)
+	assert.True(t, entry.Duration >= 0.005)
+	assert.Nil(t, entry.Metadata)
+}
+
+func Test_AccessLog_Stream(t *testing.T) {
+	var entry *AccessEntry
+	a := &accessLog{
+		cfg:   AccessLog{Level: "info"},
+		throw: func(event int, ctx interface{}) { entry = ctx.(*AccessEntry) },
+	}
+
+	info := &grpc.StreamServerInfo{FullMethod: "/service.Test/Stream"}
+	s := &mockStream{ctx: context.Background(), in: [][]byte{[]byte("a"), []byte("bc")}}
+
+	err := a.streamInterceptor(nil, s, info, func(srv interface{}, stream grpc.ServerStream) error {
+		in, err := recvFrames(stream)
+		if err != nil {
+			return err
+		}
+
+		return sendFrames(stream, in)
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "/service.Test/Stream", entry.Method)
+	assert.Equal(t, 3, entry.RequestSize)
+	assert.Equal(t, 3, entry.ResponseSize)
+}
+
+func Test_AccessLog_MetadataKeys(t *testing.T) {
+	var entry *AccessEntry
+	a := &accessLog{
+		cfg:   AccessLog{Level: "info", MetadataKeys: []string{"X-Request-Id", "missing"}},
+		throw: func(event int, ctx interface{}) { entry = ctx.(*AccessEntry) },
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-request-id", "abc",
+		"authorization", "secret",
+	))
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+	_, err := a.unaryInterceptor(ctx, rawMessage("request"), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return rawMessage("ok"), nil
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{"x-request-id": {"abc"}}, entry.Metadata)
+}
+
+func Test_AccessLog_Output(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "access.log")
+	a, err := newAccessLog(AccessLog{Level: "info", Output: output}, func(event int, ctx interface{}) {
+		t.Error("entry must not be thrown when output is configured")
+	})
+	assert.NoError(t, err)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+	for i := 0; i < 2; i++ {
+		_, err = a.unaryInterceptor(context.Background(), rawMessage("request"), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return rawMessage("ok"), nil
+		})
+		assert.NoError(t, err)
+	}
+
+	data, err := ioutil.ReadFile(output)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+
+	entry := &AccessEntry{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), entry))
+	assert.Equal(t, "/service.Test/Echo", entry.Method)
+	assert.Equal(t, "OK", entry.Code)
+	assert.Equal(t, 7, entry.RequestSize)
+
+	_, err = newAccessLog(AccessLog{Output: filepath.Join(dir, "missing", "access.log")}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"crypto/subtle"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+	"time"
+)
+
+// authenticator rejects calls without valid shared token or JWT with Unauthenticated status.
+type authenticator struct {
+	header  string
+	tokens  [][]byte
+	jwt     *jwtVerifier
+	exclude map[string]bool
+}
+
+// claimsKey is context key of verified JWT claims.
+type claimsKey struct{}
+
+// claimsFromContext returns claims of the call authenticated by JWT, nil otherwise.
+func claimsFromContext(ctx context.Context) *claims {
+	c, _ := ctx.Value(claimsKey{}).(*claims)
+	return c
+}
+
+// newAuthenticator creates authenticator based on given configuration.
+func newAuthenticator(cfg Auth) (*authenticator, error) {
+	tokens, err := cfg.tokens()
+	if err != nil {
+		return nil, err
+	}
+
+	a := &authenticator{header: cfg.header(), exclude: make(map[string]bool)}
+	for _, token := range tokens {
+		a.tokens = append(a.tokens, []byte(token))
+	}
+
+	if cfg.JWKS != "" {
+		a.jwt = &jwtVerifier{
+			keys:     newKeySet(cfg.JWKS, cfg.jwksRefresh()),
+			issuer:   cfg.Issuer,
+			audience: cfg.Audience,
+			now:      time.Now,
+		}
+	}
+
+	for _, method := range cfg.Exclude {
+		a.exclude[method] = true
+	}
+
+	return a, nil
+}
+
+// authenticate checks token of the call, claims of JWT tokens are exposed via returned context.
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if a.exclude[method] {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(a.header) {
+		token := strings.TrimSpace(strings.TrimPrefix(v, "Bearer "))
+		for _, expected := range a.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), expected) == 1 {
+				return ctx, nil
+			}
+		}
+
+		if a.jwt != nil && strings.Count(token, ".") == 2 {
+			c, err := a.jwt.verify(token)
+			if err != nil {
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+
+			return context.WithValue(ctx, claimsKey{}, c), nil
+		}
+	}
+
+	return nil, status.Error(codes.Unauthenticated, "invalid or missing token")
+}
+
+// unaryInterceptor authenticates unary calls.
+func (a *authenticator) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// streamInterceptor authenticates streaming calls.
+func (a *authenticator) streamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+}
+
+// authStream exposes claims of the call via stream context.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns stream context carrying the claims.
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,92 @@
+package grpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"os"
+	"testing"
+)
+
+func authContext(header, token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(header, token))
+}
+
+func Test_Auth_Tokens(t *testing.T) {
+	os.Setenv("GRPC_TEST_AUTH_TOKEN", "secret")
+	defer os.Unsetenv("GRPC_TEST_AUTH_TOKEN")
+
+	cfg := Auth{Tokens: []string{"env:GRPC_TEST_AUTH_TOKEN", "inline"}}
+	tokens, err := cfg.tokens()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"secret", "inline"}, tokens)
+
+	_, err = (&Auth{Tokens: []string{"env:GRPC_TEST_AUTH_MISSING"}}).tokens()
+	assert.Error(t, err)
+
+	_, err = (&Auth{Tokens: []string{""}}).tokens()
+	assert.Error(t, err)
+}
+
+func Test_Auth_Authenticate(t *testing.T) {
+	a, err := newAuthenticator(Auth{
+		Tokens:  []string{"old", "new"},
+		Exclude: []string{"/grpc.health.v1.Health/Check"},
+	})
+	assert.NoError(t, err)
+
+	_, err = a.authenticate(authContext("authorization", "old"), "/app.Test/Echo")
+	assert.NoError(t, err)
+
+	_, err = a.authenticate(authContext("authorization", "Bearer new"), "/app.Test/Echo")
+	assert.NoError(t, err)
+
+	_, err = a.authenticate(context.Background(), "/grpc.health.v1.Health/Check")
+	assert.NoError(t, err)
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		authContext("authorization", "invalid"),
+		authContext("authorization", "Bearer "),
+		authContext("x-token", "old"),
+	} {
+		_, err = a.authenticate(ctx, "/app.Test/Echo")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+}
+
+func Test_Auth_Header(t *testing.T) {
+	a, err := newAuthenticator(Auth{Tokens: []string{"secret"}, Header: "X-Token"})
+	assert.NoError(t, err)
+
+	_, err = a.authenticate(authContext("x-token", "secret"), "/app.Test/Echo")
+	assert.NoError(t, err)
+
+	_, err = a.authenticate(authContext("authorization", "secret"), "/app.Test/Echo")
+	assert.Error(t, err)
+}
+
+func Test_Auth_Interceptor(t *testing.T) {
+	a, err := newAuthenticator(Auth{Tokens: []string{"secret"}})
+	assert.NoError(t, err)
+
+	called := false
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return "ok", nil
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/app.Test/Echo"}
+
+	_, err = a.unaryInterceptor(context.Background(), nil, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.False(t, called)
+
+	out, err := a.unaryInterceptor(authContext("authorization", "secret"), nil, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", out)
+	assert.True(t, called)
+}
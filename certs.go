@@ -0,0 +1,48 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"sync/atomic"
+)
+
+const (
+	// EventCertReload thrown when TLS certificate has been reloaded from the disk.
+	EventCertReload = iota + 9000
+
+	// EventCertError thrown when TLS certificate can not be reloaded, server keeps using previous certificate.
+	EventCertError
+)
+
+// certHolder provides hot-swappable TLS certificate, new certificate is used for every new connection
+// while established connections remain untouched.
+type certHolder struct {
+	cert, key string
+	value     atomic.Value
+}
+
+// holdCertificate replaces static certificate of the tls config with hot-swappable holder.
+func holdCertificate(cfg *tls.Config, cert, key string) *certHolder {
+	h := &certHolder{cert: cert, key: key}
+	h.value.Store(&cfg.Certificates[0])
+
+	cfg.Certificates = nil
+	cfg.GetCertificate = h.getCertificate
+
+	return h
+}
+
+// reload loads certificate pair from the disk, previous certificate remains active in case of error.
+func (h *certHolder) reload() error {
+	cert, err := tls.LoadX509KeyPair(h.cert, h.key)
+	if err != nil {
+		return err
+	}
+
+	h.value.Store(&cert)
+	return nil
+}
+
+// getCertificate returns current certificate, compatible with tls.Config.GetCertificate.
+func (h *certHolder) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return h.value.Load().(*tls.Certificate), nil
+}
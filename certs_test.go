@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_CertHolder_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cert, key := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	copyFile(t, "tests/server.crt", cert)
+	copyFile(t, "tests/server.key", key)
+
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	assert.NoError(t, err)
+
+	cfg := &tls.Config{Certificates: []tls.Certificate{pair}}
+	h := holdCertificate(cfg, cert, key)
+
+	assert.Len(t, cfg.Certificates, 0)
+	assert.NotNil(t, cfg.GetCertificate)
+
+	current, err := cfg.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, pair.Certificate, current.Certificate)
+
+	// broken pair must not replace active certificate
+	assert.NoError(t, ioutil.WriteFile(cert, []byte("invalid"), 0644))
+	assert.Error(t, h.reload())
+
+	active, err := cfg.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.True(t, current == active)
+
+	copyFile(t, "tests/server.crt", cert)
+	assert.NoError(t, h.reload())
+
+	reloaded, err := cfg.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.False(t, current == reloaded)
+	assert.Equal(t, pair.Certificate, reloaded.Certificate)
+}
+
+func copyFile(t *testing.T, from, to string) {
+	data, err := ioutil.ReadFile(from)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(to, data, 0644))
+}
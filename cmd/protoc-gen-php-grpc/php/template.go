@@ -46,7 +46,7 @@ use {{ $n }};
 interface {{ .Service.Name | interface }} extends GRPC\ServiceInterface
 {
     // GRPC specific service name.
-    public const NAME = "{{ .File.Package }}.{{ .Service.Name }}";{{ "\n" }}
+    public const NAME = "{{ if .File.GetPackage }}{{ .File.GetPackage }}.{{ end }}{{ .Service.Name }}";{{ "\n" }}
 {{- range $m := .Service.Method}}
     /**
     * @param GRPC\ContextInterface $ctx
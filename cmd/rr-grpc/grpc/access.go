@@ -0,0 +1,76 @@
+// Copyright (c) 2018 SpiralScout
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package grpc
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	rrpc "github.com/spiral/php-grpc"
+	rr "github.com/spiral/roadrunner/cmd/rr/cmd"
+)
+
+func init() {
+	cobra.OnInitialize(func() {
+		svc, _ := rr.Container.Get(rrpc.ID)
+		if svc, ok := svc.(*rrpc.Service); ok {
+			access := &accessLogger{logger: &logrus.Logger{
+				Out:       rr.Logger.Out,
+				Formatter: &logrus.JSONFormatter{},
+				Hooks:     make(logrus.LevelHooks),
+				Level:     logrus.DebugLevel,
+			}}
+			svc.AddListener(access.listener)
+		}
+	})
+}
+
+// accessLogger writes access log entries as JSON lines.
+type accessLogger struct{ logger *logrus.Logger }
+
+// listener handles access log events.
+func (a *accessLogger) listener(event int, ctx interface{}) {
+	if event != rrpc.EventAccess {
+		return
+	}
+
+	e := ctx.(*rrpc.AccessEntry)
+
+	level, err := logrus.ParseLevel(e.Level)
+	if err != nil {
+		level = logrus.InfoLevel
+	}
+
+	fields := logrus.Fields{
+		"method":        e.Method,
+		"peer":          e.Peer,
+		"code":          e.Code,
+		"duration":      e.Duration,
+		"request_size":  e.RequestSize,
+		"response_size": e.ResponseSize,
+		"slow":          e.Slow,
+	}
+
+	if e.Metadata != nil {
+		fields["metadata"] = e.Metadata
+	}
+
+	a.logger.WithFields(fields).WithTime(e.Time).Log(level, "grpc access")
+}
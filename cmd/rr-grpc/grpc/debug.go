@@ -25,6 +25,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	rrpc "github.com/spiral/php-grpc"
+	"github.com/spiral/roadrunner"
 	rr "github.com/spiral/roadrunner/cmd/rr/cmd"
 	"github.com/spiral/roadrunner/cmd/util"
 	"golang.org/x/net/context"
@@ -42,7 +43,7 @@ func init() {
 			if svc, ok := svc.(*rrpc.Service); ok {
 				debug := &debugger{logger: rr.Logger}
 				svc.AddListener(debug.listener)
-				svc.AddOption(grpc.UnaryInterceptor(debug.interceptor))
+				svc.AddUnaryInterceptor(debug.interceptor)
 			}
 		}
 	})
@@ -57,6 +58,58 @@ func (d *debugger) listener(event int, ctx interface{}) {
 		// handler by default debug package
 		return
 	}
+
+	switch event {
+	case rrpc.EventCertReload:
+		d.logger.Info(util.Sprintf("<cyan+h>tls</reset> certificate <white+hb>%s</reset> reloaded", ctx))
+	case rrpc.EventCertError:
+		d.logger.Error(util.Sprintf("<cyan+h>tls</reset> <red>%s</reset>", ctx))
+	case rrpc.EventForceStop:
+		d.logger.Warning(util.Sprintf("<cyan+h>grpc</reset> <yellow>%s</reset> stopped forcibly", ctx))
+	case rrpc.EventWarmupError:
+		d.logger.Warning(util.Sprintf("<cyan+h>grpc</reset> <yellow>%s</reset>", ctx))
+	case rrpc.EventRecoverError:
+		d.logger.Error(util.Sprintf("<cyan+h>grpc</reset> <red>%s</reset>", ctx))
+	case rrpc.EventUnresolvedImport:
+		d.logger.Warning(util.Sprintf("<cyan+h>grpc</reset> import skipped: <yellow>%s</reset>", ctx))
+	case rrpc.EventMaxMemory:
+		e := ctx.(roadrunner.WorkerError)
+		d.logger.Warning(util.Sprintf("<white+hb>%v</reset> <yellow>%s</reset>", *e.Worker.Pid, e.Caused))
+	case rrpc.EventWorkerStop:
+		w := ctx.(*rrpc.WorkerContext)
+		if w.Error != nil {
+			d.logger.Warning(util.Sprintf("<white+hb>%v</reset> %s pool worker <yellow>%s</reset>: %s", w.Pid, w.Pool, w.Reason, w.Error))
+		} else {
+			d.logger.Debug(util.Sprintf("<white+hb>%v</reset> %s pool worker <yellow>%s</reset>", w.Pid, w.Pool, w.Reason))
+		}
+	case rrpc.EventConnOpen:
+		c := ctx.(*rrpc.ConnContext)
+		d.logger.Debug(util.Sprintf("<cyan+h>%s</reset> connected to <white+hb>%s</reset>", c.RemoteAddr, c.LocalAddr))
+	case rrpc.EventConnClose:
+		c := ctx.(*rrpc.ConnContext)
+		d.logger.Debug(util.Sprintf("<cyan+h>%s</reset> disconnected", c.RemoteAddr))
+	case rrpc.EventHandshakeError:
+		c := ctx.(*rrpc.ConnContext)
+		d.logger.Warning(util.Sprintf("<cyan+h>%s</reset> tls handshake <yellow>%s</reset>", c.RemoteAddr, c.Error))
+	case rrpc.EventPanic:
+		p := ctx.(*rrpc.PanicContext)
+		d.logger.Error(util.Sprintf(
+			"<cyan+h>grpc</reset> <red+hb>%s</reset> panic: <red>%v</reset>\n%s",
+			p.Method,
+			p.Value,
+			p.Stack,
+		))
+	case rrpc.EventSpan:
+		s := ctx.(*rrpc.Span)
+		d.logger.Debug(util.Sprintf(
+			"<cyan+h>trace</reset> %s %s %s <white+hb>%s</reset> pid %v",
+			s.Traceparent(),
+			s.Code.String(),
+			elapsed(s.Duration),
+			s.Method,
+			s.PID,
+		))
+	}
 }
 
 // call info
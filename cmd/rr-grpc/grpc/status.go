@@ -0,0 +1,64 @@
+// Copyright (c) 2018 SpiralScout
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package grpc
+
+import (
+	"github.com/spf13/cobra"
+	rrpc "github.com/spiral/php-grpc"
+	rr "github.com/spiral/roadrunner/cmd/rr/cmd"
+	"github.com/spiral/roadrunner/cmd/util"
+	"strings"
+)
+
+func init() {
+	rr.CLI.AddCommand(&cobra.Command{
+		Use:   "grpc:status",
+		Short: "Show configuration loaded by the GRPC service",
+		RunE:  statusHandler,
+	})
+}
+
+func statusHandler(cmd *cobra.Command, args []string) error {
+	client, err := util.RPCClient(rr.Container)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var r rrpc.Status
+	if err := client.Call("grpc.Status", true, &r); err != nil {
+		return err
+	}
+
+	tls := "<red>disabled</reset>"
+	if r.TLS {
+		tls = "<green>enabled</reset>"
+	}
+
+	util.Printf("<white+hb>listen</reset>: %s\n", strings.Join(r.Listen, ", "))
+	util.Printf("<white+hb>tls</reset>: " + tls + "\n")
+	util.Printf("<white+hb>codec</reset>: %s\n", r.Codec)
+	util.Printf("<white+hb>proto</reset>: %s\n", strings.Join(r.Proto, ", "))
+	util.Printf("<white+hb>services</reset>: %d (%d methods)\n", r.Services, r.Methods)
+	util.Printf("<white+hb>workers</reset>: %d\n", r.Workers)
+
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2018 SpiralScout
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package grpc
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	rrpc "github.com/spiral/php-grpc"
+	rr "github.com/spiral/roadrunner/cmd/rr/cmd"
+)
+
+func init() {
+	cobra.OnInitialize(func() {
+		if rr.Debug {
+			// debugger reports every event
+			return
+		}
+
+		svc, _ := rr.Container.Get(rrpc.ID)
+		if svc, ok := svc.(*rrpc.Service); ok {
+			w := &warner{logger: rr.Logger}
+			svc.AddListener(w.listener)
+		}
+	})
+}
+
+// warner logs events requiring attention when debug mode is disabled.
+type warner struct{ logger *logrus.Logger }
+
+// listener handles stop, panic, warmup, pool recovery, import and handshake events.
+func (w *warner) listener(event int, ctx interface{}) {
+	switch event {
+	case rrpc.EventForceStop:
+		w.logger.Warningf("grpc %s stopped forcibly after graceful timeout", ctx)
+	case rrpc.EventPanic:
+		p := ctx.(*rrpc.PanicContext)
+		w.logger.Errorf("grpc %s panic: %v\n%s", p.Method, p.Value, p.Stack)
+	case rrpc.EventWarmupError:
+		w.logger.Warning(ctx)
+	case rrpc.EventRecoverError:
+		w.logger.Error(ctx)
+	case rrpc.EventUnresolvedImport:
+		w.logger.Warningf("grpc import skipped: %s", ctx)
+	case rrpc.EventHandshakeError:
+		c := ctx.(*rrpc.ConnContext)
+		w.logger.Warningf("grpc tls handshake with %s failed: %s", c.RemoteAddr, c.Error)
+	}
+}
@@ -21,11 +21,13 @@
 package grpc
 
 import (
+	"fmt"
 	tm "github.com/buger/goterm"
 	"github.com/spf13/cobra"
 	rrpc "github.com/spiral/php-grpc"
 	rr "github.com/spiral/roadrunner/cmd/rr/cmd"
 	"github.com/spiral/roadrunner/cmd/util"
+	rrutil "github.com/spiral/roadrunner/util"
 	"net/rpc"
 	"os"
 	"os/signal"
@@ -96,5 +98,16 @@ func showWorkers(client *rpc.Client) {
 		panic(err)
 	}
 
-	util.WorkerTable(r.Workers).Render()
+	states := make([]*rrutil.State, 0, len(r.Workers))
+	for _, w := range r.Workers {
+		states = append(states, &w.State)
+	}
+
+	util.WorkerTable(states).Render()
+
+	for _, w := range r.Workers {
+		if w.Error != "" {
+			fmt.Println(util.Sprintf("<white+hb>%v</reset>: <red>%s</reset>", w.Pid, w.Error))
+		}
+	}
 }
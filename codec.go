@@ -4,6 +4,9 @@ import (
 	"google.golang.org/grpc/encoding"
 )
 
+// rawMessage carries message bytes encoded by the client or PHP worker, codec passes them between the
+// transport and workers byte to byte. Workers receive request messages exactly as sent by the client and
+// their responses reach the client exactly as produced by the worker, messages are never decoded by the server.
 type rawMessage []byte
 
 func (r rawMessage) Reset()       {}
@@ -0,0 +1,141 @@
+package grpc
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"sync/atomic"
+)
+
+// semaphore limits number of concurrent calls, calls exceeding the limit wait in the queue of limited size.
+type semaphore struct {
+	slots   chan struct{}
+	queue   int32
+	waiting int32
+}
+
+// newSemaphore creates semaphore allowing limit of concurrent calls and queue of waiting calls.
+func newSemaphore(limit, queue int) *semaphore {
+	return &semaphore{slots: make(chan struct{}, limit), queue: int32(queue)}
+}
+
+// acquire occupies the slot, waits for a free slot when the queue is not full. Waiting is interrupted once
+// the call is cancelled or it's deadline is reached.
+func (s *semaphore) acquire(ctx context.Context) bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if atomic.AddInt32(&s.waiting, 1) > s.queue {
+		atomic.AddInt32(&s.waiting, -1)
+		return false
+	}
+	defer atomic.AddInt32(&s.waiting, -1)
+
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees the slot.
+func (s *semaphore) release() {
+	<-s.slots
+}
+
+// concurrencyLimiter limits number of concurrent calls of individual methods, calls exceeding the limit and
+// the queue are rejected with ResourceExhausted status.
+type concurrencyLimiter struct {
+	mu      sync.Mutex
+	methods map[string]*semaphore
+
+	// limit of every other method
+	all *MethodConcurrency
+}
+
+// newConcurrencyLimiter creates limiter based on given configuration.
+func newConcurrencyLimiter(cfg []MethodConcurrency) *concurrencyLimiter {
+	cl := &concurrencyLimiter{methods: make(map[string]*semaphore)}
+	for i, m := range cfg {
+		if m.Method == "*" {
+			cl.all = &cfg[i]
+			continue
+		}
+
+		cl.methods[m.Method] = newSemaphore(m.Limit, m.Queue)
+	}
+
+	return cl
+}
+
+// semaphore returns semaphore of the method, nil when method is not limited.
+func (cl *concurrencyLimiter) semaphore(method string) *semaphore {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if s, ok := cl.methods[method]; ok || cl.all == nil {
+		return s
+	}
+
+	// methods covered by "*" are limited separately
+	s := newSemaphore(cl.all.Limit, cl.all.Queue)
+	cl.methods[method] = s
+
+	return s
+}
+
+// acquire occupies the slot of the method, returns function releasing the slot.
+func (cl *concurrencyLimiter) acquire(ctx context.Context, method string) (func(), error) {
+	s := cl.semaphore(method)
+	if s == nil {
+		return func() {}, nil
+	}
+
+	if !s.acquire(ctx) {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+
+		return nil, status.Errorf(codes.ResourceExhausted, "concurrency limit of method %s exceeded", method)
+	}
+
+	return s.release, nil
+}
+
+// unaryInterceptor limits concurrent unary calls.
+func (cl *concurrencyLimiter) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	release, err := cl.acquire(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return handler(ctx, req)
+}
+
+// streamInterceptor limits concurrent streaming calls, the slot is occupied until the stream is complete.
+func (cl *concurrencyLimiter) streamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	release, err := cl.acquire(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return handler(srv, ss)
+}
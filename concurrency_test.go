@@ -0,0 +1,132 @@
+package grpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Semaphore(t *testing.T) {
+	s := newSemaphore(1, 1)
+	assert.True(t, s.acquire(context.Background()))
+
+	// single call waits in the queue
+	acquired := make(chan bool)
+	go func() { acquired <- s.acquire(context.Background()) }()
+
+	for i := 0; i < 100 && atomic.LoadInt32(&s.waiting) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	// queue is full
+	assert.False(t, s.acquire(context.Background()))
+
+	s.release()
+	assert.True(t, <-acquired)
+
+	// waiting is interrupted by the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, s.acquire(ctx))
+
+	s.release()
+	assert.True(t, s.acquire(context.Background()))
+}
+
+func Test_ConcurrencyLimiter(t *testing.T) {
+	cl := newConcurrencyLimiter([]MethodConcurrency{
+		{Method: "/app.Service/Heavy", Limit: 1},
+		{Method: "*", Limit: 2},
+	})
+
+	release, err := cl.acquire(context.Background(), "/app.Service/Heavy")
+	assert.NoError(t, err)
+
+	_, err = cl.acquire(context.Background(), "/app.Service/Heavy")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, err.Error(), "/app.Service/Heavy")
+
+	release()
+	release, err = cl.acquire(context.Background(), "/app.Service/Heavy")
+	assert.NoError(t, err)
+	release()
+
+	// other methods are limited separately
+	for i := 0; i < 2; i++ {
+		_, err = cl.acquire(context.Background(), "/app.Service/A")
+		assert.NoError(t, err)
+	}
+	_, err = cl.acquire(context.Background(), "/app.Service/A")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = cl.acquire(context.Background(), "/app.Service/B")
+	assert.NoError(t, err)
+
+	// methods are not limited without "*"
+	cl = newConcurrencyLimiter([]MethodConcurrency{{Method: "/app.Service/Heavy", Limit: 1}})
+	for i := 0; i < 3; i++ {
+		_, err = cl.acquire(context.Background(), "/app.Service/A")
+		assert.NoError(t, err)
+	}
+}
+
+func Test_ConcurrencyLimiter_Queue(t *testing.T) {
+	cl := newConcurrencyLimiter([]MethodConcurrency{{Method: "*", Limit: 1, Queue: 1}})
+
+	release, err := cl.acquire(context.Background(), "/app.Service/A")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = cl.acquire(ctx, "/app.Service/A")
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	release()
+}
+
+func Test_ConcurrencyLimiter_Interceptors(t *testing.T) {
+	cl := newConcurrencyLimiter([]MethodConcurrency{{Method: "/app.Service/A", Limit: 1}})
+	info := &grpc.UnaryServerInfo{FullMethod: "/app.Service/A"}
+
+	// slot is occupied during the call
+	_, err := cl.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, err := cl.unaryInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+
+		return nil, err
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// and released once the call is complete
+	_, err = cl.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	assert.NoError(t, err)
+
+	ss := &mockStream{ctx: context.Background()}
+	sinfo := &grpc.StreamServerInfo{FullMethod: "/app.Service/A"}
+	err = cl.streamInterceptor(nil, ss, sinfo, func(srv interface{}, stream grpc.ServerStream) error {
+		return cl.streamInterceptor(nil, ss, sinfo, func(srv interface{}, stream grpc.ServerStream) error { return nil })
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func Test_Config_Concurrency(t *testing.T) {
+	assert.NoError(t, (&MethodConcurrency{Method: "*", Limit: 1}).Valid())
+	assert.NoError(t, (&MethodConcurrency{Method: "/app.Service/A", Limit: 10, Queue: 100}).Valid())
+
+	for _, m := range []MethodConcurrency{
+		{Method: "", Limit: 1},
+		{Method: "A", Limit: 1},
+		{Method: "/app.Service/A"},
+		{Method: "/app.Service/A", Limit: 1, Queue: -1},
+	} {
+		assert.Error(t, m.Valid(), m.Method)
+	}
+}
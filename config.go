@@ -1,91 +1,679 @@
 package grpc
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/spiral/roadrunner"
 	"github.com/spiral/roadrunner/service"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/keepalive"
+	"io/ioutil"
+	"math"
 	"net"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
 // Config describes GRPC service configuration.
 type Config struct {
-	// Address to listen.
+	// Address to listen, tcp://:9001 or unix://grpc.sock.
 	Listen string
 
-	// Proto file associated with the service.
+	// Addresses defines additional addresses to listen, the same services and TLS settings are used on
+	// every address.
+	Addresses []string
+
+	// Endpoints defines additional addresses to listen with their own TLS settings (public TLS port next to
+	// internal plaintext one), the same services are served on every endpoint.
+	Endpoints []Endpoint
+
+	// SocketPermissions defines octal file mode of unix socket (for example "0660"), socket removed
+	// once the server is stopped.
+	SocketPermissions string
+
+	// Backlog defines maximal length of the queue of pending connections, zero means system default
+	// (net.core.somaxconn on Linux). Linux only.
+	Backlog int
+
+	// ReusePort enables SO_REUSEPORT allowing multiple processes to listen on the same tcp port, the kernel
+	// balances new connections between them. Linux only.
+	ReusePort bool
+
+	// Proto file associated with the service, glob patterns are supported ("proto/*.proto").
 	Proto string
 
+	// Protos defines additional proto files (or glob patterns) associated with the service.
+	Protos []string
+
+	// ImportPaths defines include directories used to resolve proto imports (same as protoc -I), directory of
+	// the proto file is always searched first.
+	ImportPaths []string
+
+	// SkipUnresolvedImports skips proto imports which can not be resolved (services declared by them are not
+	// served) reporting them by EventUnresolvedImport. Unresolved imports fail startup and proto reload otherwise.
+	SkipUnresolvedImports bool
+
+	// ExposeServices lists services ("app.Service", patterns like "app.public.*" are supported) registered by the
+	// server, every parsed service is registered when empty. Calls of other services fail with Unimplemented status.
+	ExposeServices []string
+
+	// ExcludeServices lists services (or patterns) which are not registered, takes precedence over ExposeServices.
+	ExcludeServices []string
+
 	// TLS defined authentication method (TLS for now).
 	TLS TLS
 
+	// Codec defines name of registered codec (google.golang.org/grpc/encoding) used to encode messages of
+	// non proxied services, messages of proxied services are passed to workers as is. Defaults to "proto".
+	Codec string
+
+	// Compression defines compressor used for every response ("gzip"), empty value enables compression only
+	// for clients sending compressed requests (responses use the same encoding as the request). Compressed
+	// requests are always accepted and passed to workers decompressed.
+	Compression string
+
+	// RecoverPool keeps the server running when worker pool can not be rebuilt and restarts the pool in background,
+	// calls fail with Unavailable status (health status is NOT_SERVING) until workers are restored, failed restarts
+	// are reported by EventRecoverError. Server is stopped on pool failure otherwise.
+	RecoverPool bool
+
+	// StreamSessions serves client streaming methods by dedicated session workers (same as bidirectional and
+	// server streams), client messages are fed to the worker one by one as they arrive. Client streaming methods
+	// are served by pool workers receiving all messages in one payload otherwise.
+	StreamSessions bool
+
+	// BufferedStreams serves server streaming methods by pool workers returning all messages in one payload, the
+	// whole response is held in memory. Server streams are served by session workers sending messages one by one
+	// as they are produced otherwise.
+	BufferedStreams bool
+
+	// GracefulTimeout limits the time given to active calls to complete once the service is stopped, remaining
+	// connections are closed and workers killed after the timeout. Zero waits for every call to complete.
+	GracefulTimeout time.Duration
+
+	// KillOnCancel lists methods ("/app.Service/Report") served by dedicated session workers killed as soon as
+	// the client cancels the call. Pool workers can not be interrupted and always complete cancelled calls.
+	KillOnCancel []string
+
+	// MaxSessions limits number of concurrently running session workers (bidirectional and server streams, client
+	// streams served with StreamSessions and KillOnCancel calls), calls exceeding the limit are rejected with
+	// ResourceExhausted status. Zero means no limit.
+	MaxSessions int
+
+	// MaxExecutionTime limits execution time of every method independently of client deadlines (shorter client
+	// deadline is respected), workers of calls exceeding the limit are killed and calls fail with DeadlineExceeded
+	// status. Zero means no limit. Streams served by session workers are not limited.
+	MaxExecutionTime time.Duration
+
+	// MaxJobs defines number of calls executed by pool worker before it's replaced, overrides pool maxJobs of
+	// workers configuration when set. Zero means no limit.
+	MaxJobs int64
+
+	// MaxMemory limits memory usage of pool workers ("128MB"), workers exceeding the limit are replaced once they
+	// complete the call. Empty means no limit, session workers are not limited.
+	MaxMemory string
+
+	// Timeouts overrides MaxExecutionTime of individual methods, method "*" overrides it for every other method.
+	Timeouts []MethodTimeout
+
+	// Retry configures retries of calls failed due to worker errors.
+	Retry Retry
+
+	// ForwardedHeader defines metadata key ("x-forwarded-for") trusted to carry the client IP when the server
+	// is behind a proxy. First address of the header is passed to workers as :client.ip and takes precedence
+	// over the peer IP, the header is ignored when empty. Only enable it when every client connects through
+	// a proxy overwriting the header, clients can forge it otherwise.
+	ForwardedHeader string
+
+	// ValidateMethods asks PHP worker for implemented methods on start, service fails to start when any of
+	// proxied methods is not implemented (requires spiral/php-grpc worker reporting it's methods).
+	ValidateMethods bool
+
+	// ValidateMessages checks proto encoded messages against descriptors of proto files: malformed requests are
+	// rejected with InvalidArgument status before reaching workers, malformed responses fail with Internal status.
+	// Requests of client streaming calls and responses of server streaming calls are not checked. Disabled by
+	// default, messages are passed as is.
+	ValidateMessages bool
+
+	// Warmup configures synthetic calls priming workers (opcache, autoloaders) before the server accepts calls.
+	Warmup Warmup
+
+	// Health enables standard grpc.health.v1.Health service reporting status of the server and
+	// every proxied service.
+	Health bool
+
+	// Reflection enables server reflection service (grpcurl, Postman and etc). Reflection exposes the
+	// complete API surface of the server, keep it disabled in production.
+	Reflection bool
+
+	// MaxRecvMsgSize defines maximal size of incoming message in bytes or with KB, MB, GB suffix ("16MB"),
+	// empty means gRPC default (4MB). Larger messages are rejected with ResourceExhausted status.
+	MaxRecvMsgSize string
+
+	// MaxSendMsgSize defines maximal size of outgoing message, empty means gRPC default.
+	MaxSendMsgSize string
+
+	// MaxRequestBytes limits size of requests passed to PHP workers ("1MB"), larger requests are rejected with
+	// InvalidArgument status before reaching workers. Unlike MaxRecvMsgSize the limit applies to the complete
+	// request of pool streaming calls (all client messages), messages of session streams are not limited.
+	MaxRequestBytes string
+
+	// ReadBufferSize defines size of connection read buffer ("64KB"), larger buffers improve throughput of
+	// high-bandwidth links. Empty means gRPC default (32KB).
+	ReadBufferSize string
+
+	// WriteBufferSize defines size of connection write buffer, empty means gRPC default (32KB).
+	WriteBufferSize string
+
+	// MaxConcurrentStreams limits number of concurrent calls per client connection, zero means no limit.
+	// Calls exceeding the limit wait for active calls to complete (HTTP/2 flow control) instead of failing.
+	// Every unary (and pool streaming) call occupies a pool worker, keep the limit below the number of pool
+	// workers (pool.numWorkers) so a single connection can not occupy the whole pool. Limits above the pool
+	// size do not protect workers, excess calls wait for a free worker and fail once pool.allocateTimeout is
+	// reached. Bidirectional (and session) streams are served by dedicated workers outside of the pool.
+	MaxConcurrentStreams uint32
+
+	// ConnectionTimeout limits the time new connection is given to become usable (TLS and HTTP/2 handshake),
+	// connections of clients failing to complete the handshake in time are closed. It does not limit calls,
+	// see MaxExecutionTime. Zero means gRPC default (120 seconds).
+	ConnectionTimeout time.Duration
+
+	// NumStreamWorkers defines number of goroutines reused to serve incoming calls, calls arriving while all of
+	// them are busy are served by new goroutines as without the option. Reduces goroutine allocations under high
+	// load, does not limit the number of concurrent calls (see MaxConcurrentStreams). Zero spawns new goroutine
+	// for every call (gRPC default).
+	NumStreamWorkers int
+
+	// Keepalive configures connection keepalive and ping enforcement.
+	Keepalive Keepalive
+
+	// Metrics configures prometheus metrics of RPC calls and worker pool.
+	Metrics Metrics
+
+	// Tracing configures tracing of RPC calls.
+	Tracing Tracing
+
+	// AccessLog configures access log of RPC calls.
+	AccessLog AccessLog
+
+	// Auth configures shared token authentication of RPC calls.
+	Auth Auth
+
+	// RateLimit configures rate limits of RPC calls.
+	RateLimit RateLimit
+
+	// Concurrency limits number of concurrent calls of individual methods, protects the worker pool from
+	// stampede on a single expensive method.
+	Concurrency []MethodConcurrency
+
+	// Recovery configures errors returned by calls recovered from panic.
+	Recovery Recovery
+
+	// GRPCWeb configures gRPC-Web endpoint for browser clients.
+	GRPCWeb GRPCWeb
+
 	// Workers configures roadrunner grpc and worker pool.
 	Workers *roadrunner.ServerConfig
+
+	// Pools defines dedicated worker pools serving listed methods, so slow methods can not starve the others.
+	// Calls of methods not listed are served by Workers pool.
+	Pools []MethodPool
 }
 
-// TLS defines auth credentials.
-type TLS struct {
-	// Key defined private server key.
-	Key string
+// Metrics defines prometheus metrics configuration. Metrics are collected in the registry returned by
+// Service.Registry(), set Address to expose them or mount the registry on your own /metrics endpoint.
+type Metrics struct {
+	// Enable enables metrics collection.
+	Enable bool
 
-	// Cert is https certificate.
-	Cert string
+	// Namespace prefixes every metric name, defaults to "rr_grpc".
+	Namespace string
+
+	// Address to serve /metrics endpoint on (for example "localhost:2112"), empty disables the endpoint.
+	Address string
 }
 
-// Hydrate the config and validate it's values.
-func (c *Config) Hydrate(cfg service.Config) error {
-	if c.Workers == nil {
-		c.Workers = &roadrunner.ServerConfig{}
+// Tracing defines tracing configuration. Calls continue inbound W3C trace context (OpenTelemetry default
+// propagation), complete spans are delivered to service listeners with EventSpan event and to the provider
+// set by Service.SetTracerProvider.
+type Tracing struct {
+	// Enable enables tracing of RPC calls.
+	Enable bool
+}
+
+// AccessLog defines access log configuration. Every call is reported to service listeners with EventAccess
+// event, rr-grpc writes the entries to the log as JSON lines.
+type AccessLog struct {
+	// Enable enables access log.
+	Enable bool
+
+	// Level of log entries ("debug", "info", "warn" or "error"), defaults to "info".
+	Level string
+
+	// Metadata includes incoming metadata into log entries, metadata might contain credentials.
+	Metadata bool
+
+	// MetadataKeys includes only listed metadata keys into log entries (even when Metadata is disabled).
+	MetadataKeys []string
+
+	// Output defines where entries are written as JSON lines ("stdout", "stderr" or file path). Entries are
+	// delivered to service listeners (EventAccess) instead when empty, rr-grpc writes them to it's log.
+	Output string
+
+	// SlowThreshold flags calls taking longer than given duration, zero disables the flag.
+	SlowThreshold time.Duration
+}
+
+// Valid validates access log configuration.
+func (a *AccessLog) Valid() error {
+	switch a.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("invalid access log level '%s'", a.Level)
 	}
 
-	c.Workers.InitDefaults()
-	if err := cfg.Unmarshal(c); err != nil {
+	if a.SlowThreshold < 0 {
+		return errors.New("access log slow threshold must not be negative")
+	}
+
+	return nil
+}
+
+// GRPCWeb defines gRPC-Web endpoint serving proxied (and every other registered) service over HTTP/1.1.
+// Both binary (application/grpc-web) and text (application/grpc-web-text) formats are supported, endpoint
+// uses TLS configuration of the server. Client streaming calls are not supported by browsers.
+type GRPCWeb struct {
+	// Address to serve gRPC-Web calls on ("localhost:8080"), empty disables gRPC-Web.
+	Address string
+
+	// AllowedOrigins lists origins allowed to make cross origin calls ("https://app.example.com"), "*" allows
+	// any origin. Cross origin calls are rejected when empty.
+	AllowedOrigins []string
+}
+
+// Warmup defines calls issued to every pool worker once the pool is started, failed calls are reported to service
+// listeners with EventWarmupError event and do not prevent the server from starting.
+type Warmup struct {
+	// Count is number of warmup calls per worker, zero disables warmup.
+	Count int
+
+	// Method is full name of the method ("/app.Service/Warmup") called with empty message, workers receive
+	// built-in no-op call when empty.
+	Method string
+}
+
+// name returns name of warmup method.
+func (w *Warmup) name() string {
+	if w.Method == "" {
+		return warmupMethod
+	}
+
+	return w.Method
+}
+
+// Valid validates warmup configuration.
+func (w *Warmup) Valid() error {
+	if w.Count < 0 {
+		return errors.New("warmup count must not be negative")
+	}
+
+	if w.Method != "" && (!strings.HasPrefix(w.Method, "/") || strings.Count(w.Method, "/") != 2) {
+		return fmt.Errorf("invalid warmup method '%s', expected /package.Service/Method", w.Method)
+	}
+
+	return nil
+}
+
+// Recovery defines handling of panics raised by proxies and interceptors. Panicked calls fail with Internal
+// status, panics are delivered to service listeners with EventPanic event.
+type Recovery struct {
+	// Message returned to the client, defaults to "internal error".
+	Message string
+
+	// Stack includes panic value and stack trace into returned message, use for debugging only.
+	Stack bool
+}
+
+// message returns error message of panicked calls.
+func (r *Recovery) message() string {
+	if r.Message == "" {
+		return "internal error"
+	}
+
+	return r.Message
+}
+
+// Auth defines shared token or JWT authentication, calls without valid token are rejected with Unauthenticated
+// status before reaching PHP workers.
+type Auth struct {
+	// Tokens lists valid tokens (multiple tokens allow rotation), "env:NAME" reads the token from environment
+	// variable NAME. Authentication is disabled when empty.
+	Tokens []string
+
+	// Header defines metadata key carrying the token, defaults to "authorization". Bearer prefix is optional.
+	Header string
+
+	// Exclude lists methods callable without token ("/grpc.health.v1.Health/Check").
+	Exclude []string
+
+	// JWKS defines URL of JSON Web Key Set used to verify JWT tokens (RS256, ES256). Subject and scopes of
+	// verified tokens are passed to the worker as ":auth.subject" and ":auth.scopes".
+	JWKS string
+
+	// JWKSRefresh defines how often the key set is fetched again, defaults to 1 hour.
+	JWKSRefresh time.Duration
+
+	// Issuer requires "iss" claim of JWT tokens to match, not checked when empty.
+	Issuer string
+
+	// Audience requires "aud" claim of JWT tokens to contain the value, not checked when empty.
+	Audience string
+}
+
+// Enabled returns true if calls must be authenticated.
+func (a *Auth) Enabled() bool {
+	return len(a.Tokens) != 0 || a.JWKS != ""
+}
+
+// Valid validates auth configuration.
+func (a *Auth) Valid() error {
+	if _, err := a.tokens(); err != nil {
 		return err
 	}
-	c.Workers.UpscaleDurations()
 
-	return c.Valid()
+	if a.JWKS != "" {
+		if u, err := url.Parse(a.JWKS); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid auth jwks url '%s'", a.JWKS)
+		}
+	}
+
+	if a.JWKSRefresh < 0 {
+		return errors.New("auth jwks refresh must not be negative")
+	}
+
+	return nil
 }
 
-// Valid validates the configuration.
-func (c *Config) Valid() error {
-	if c.Proto == "" {
-		return errors.New("proto file is required")
+// jwksRefresh returns how often the key set is fetched again.
+func (a *Auth) jwksRefresh() time.Duration {
+	if a.JWKSRefresh == 0 {
+		return time.Hour
 	}
 
-	if _, err := os.Stat(c.Proto); err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("proto file '%s' does not exists", c.Proto)
+	return a.JWKSRefresh
+}
+
+// header returns metadata key carrying the token.
+func (a *Auth) header() string {
+	if a.Header == "" {
+		return "authorization"
+	}
+
+	return strings.ToLower(a.Header)
+}
+
+// tokens returns valid tokens, references of environment variables are resolved.
+func (a *Auth) tokens() ([]string, error) {
+	tokens := make([]string, 0, len(a.Tokens))
+	for _, token := range a.Tokens {
+		if strings.HasPrefix(token, "env:") {
+			name := strings.TrimPrefix(token, "env:")
+			value, ok := os.LookupEnv(name)
+			if !ok || value == "" {
+				return nil, fmt.Errorf("auth token environment variable '%s' is not set", name)
+			}
+
+			token = value
 		}
 
-		return err
+		if token == "" {
+			return nil, errors.New("auth token must not be empty")
+		}
+
+		tokens = append(tokens, token)
 	}
 
-	if err := c.Workers.Pool.Valid(); err != nil {
-		return err
+	return tokens, nil
+}
+
+// RateLimit defines token bucket limits of RPC calls, limits are expressed as number of calls per second,
+// minute or hour ("1000/s", "60/m"). Calls exceeding the limit are rejected with ResourceExhausted status
+// without reaching PHP workers.
+type RateLimit struct {
+	// Methods defines limits of individual methods, method "*" limits every other method separately.
+	Methods []MethodLimit
+
+	// Client limits calls of every client.
+	Client string
+
+	// ClientKey defines metadata key identifying the client (api key, user id), clients are identified by
+	// peer IP address when key is empty or missing in the call metadata.
+	ClientKey string
+}
+
+// MethodLimit defines rate limit of the method.
+type MethodLimit struct {
+	// Method is full method name ("/app.Service/Method").
+	Method string
+
+	// Limit is allowed rate of calls ("1000/s").
+	Limit string
+
+	// Burst defines number of calls allowed at once, defaults to the number of calls of the limit period.
+	Burst int
+
+	// PerClient limits calls of every client separately (clients are identified same as by Client limit).
+	PerClient bool
+}
+
+// MethodConcurrency defines maximal number of concurrent calls of the method. Calls exceeding the limit wait for
+// a free slot in the queue (until their deadline), calls exceeding the queue are rejected with ResourceExhausted
+// status without reaching PHP workers.
+type MethodConcurrency struct {
+	// Method is full method name ("/app.Service/Method"), method "*" limits every other method separately.
+	Method string
+
+	// Limit is maximal number of calls executed at once.
+	Limit int
+
+	// Queue defines number of calls allowed to wait for a free slot, zero rejects calls exceeding the limit
+	// immediately.
+	Queue int
+}
+
+// Valid validates concurrency limit of the method.
+func (m *MethodConcurrency) Valid() error {
+	if m.Method != "*" && (!strings.HasPrefix(m.Method, "/") || strings.Count(m.Method, "/") != 2) {
+		return fmt.Errorf("invalid method name '%s', expected /package.Service/Method or *", m.Method)
 	}
 
-	if !strings.Contains(c.Listen, ":") {
-		return errors.New("mailformed grpc grpc address")
+	if m.Limit <= 0 {
+		return fmt.Errorf("concurrency limit of method '%s' must be positive", m.Method)
 	}
 
-	if c.EnableTLS() {
-		if _, err := os.Stat(c.TLS.Key); err != nil {
-			if os.IsNotExist(err) {
-				return fmt.Errorf("key file '%s' does not exists", c.TLS.Key)
+	if m.Queue < 0 {
+		return fmt.Errorf("concurrency queue of method '%s' must not be negative", m.Method)
+	}
+
+	return nil
+}
+
+// MethodTimeout defines maximal execution time of the method.
+type MethodTimeout struct {
+	// Method is full method name ("/app.Service/Method") or "*".
+	Method string
+
+	// Timeout is maximal execution time ("30s").
+	Timeout time.Duration
+}
+
+// MethodPool defines dedicated worker pool of methods, pool shares relay and environment of Workers.
+type MethodPool struct {
+	// Name identifies the pool in worker statistics.
+	Name string
+
+	// Methods lists methods ("/app.Service/Method") or services ("/app.Service/*") served by the pool.
+	Methods []string
+
+	// Command overrides worker command of the pool, defaults to the command of Workers.
+	Command string
+
+	// Pool defines number of workers and timeouts of the pool, timeouts default to timeouts of Workers pool.
+	Pool *roadrunner.Config
+}
+
+// serves returns true if the pool serves the method ("/app.Service/Method").
+func (p *MethodPool) serves(method string) bool {
+	for _, m := range p.Methods {
+		if m == method || (strings.HasSuffix(m, "/*") && strings.HasPrefix(method, strings.TrimSuffix(m, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// initDefaults sets missing pool settings using Workers configuration and converts timeouts to seconds.
+func (p *MethodPool) initDefaults(workers *roadrunner.ServerConfig) {
+	if p.Pool == nil {
+		p.Pool = &roadrunner.Config{}
+	}
+
+	if p.Pool.AllocateTimeout == 0 {
+		p.Pool.AllocateTimeout = workers.Pool.AllocateTimeout
+	} else if p.Pool.AllocateTimeout < time.Microsecond {
+		p.Pool.AllocateTimeout = time.Second * time.Duration(p.Pool.AllocateTimeout.Nanoseconds())
+	}
+
+	if p.Pool.DestroyTimeout == 0 {
+		p.Pool.DestroyTimeout = workers.Pool.DestroyTimeout
+	} else if p.Pool.DestroyTimeout < time.Microsecond {
+		p.Pool.DestroyTimeout = time.Second * time.Duration(p.Pool.DestroyTimeout.Nanoseconds())
+	}
+}
+
+// serverConfig returns server configuration of the pool based on Workers configuration (relay and environment).
+func (p *MethodPool) serverConfig(workers *roadrunner.ServerConfig) *roadrunner.ServerConfig {
+	cfg := *workers
+	cfg.Pool = p.Pool
+	if p.Command != "" {
+		cfg.Command = p.Command
+	}
+
+	return &cfg
+}
+
+// validPools validates dedicated pools, every method can be served by single pool only.
+func (c *Config) validPools() error {
+	names := make(map[string]bool)
+	methods := make(map[string]string)
+
+	for _, p := range c.Pools {
+		if p.Name == "" {
+			return errors.New("worker pool name is required")
+		}
+
+		if names[p.Name] || p.Name == defaultPool {
+			return fmt.Errorf("worker pool '%s' is declared multiple times", p.Name)
+		}
+		names[p.Name] = true
+
+		if len(p.Methods) == 0 {
+			return fmt.Errorf("worker pool '%s' does not serve any method", p.Name)
+		}
+
+		for _, m := range p.Methods {
+			if !strings.HasPrefix(m, "/") || strings.Count(m, "/") != 2 {
+				return fmt.Errorf("invalid method name '%s', expected /package.Service/Method", m)
 			}
 
+			if prev, ok := methods[m]; ok {
+				return fmt.Errorf("method '%s' is served by worker pools '%s' and '%s'", m, prev, p.Name)
+			}
+			methods[m] = p.Name
+		}
+
+		if p.Pool == nil {
+			return fmt.Errorf("worker pool '%s' is not configured", p.Name)
+		}
+
+		if err := p.Pool.Valid(); err != nil {
+			return fmt.Errorf("worker pool '%s': %s", p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Retry defines retries of calls failed due to worker or pool errors (worker crashed or replaced, allocate
+// timeout). Errors returned by the application are never retried, calls served by session workers are not
+// retried. Retries stop once the client deadline would be exceeded.
+type Retry struct {
+	// Attempts defines maximal number of retries, zero disables retries.
+	Attempts int
+
+	// Backoff defines delay before the first retry, doubled with every next retry. Defaults to 100ms.
+	Backoff time.Duration
+
+	// Methods lists idempotent methods ("/app.Service/Get") safe to retry, "*" allows every method.
+	Methods []string
+}
+
+// backoff returns delay before the first retry.
+func (r *Retry) backoff() time.Duration {
+	if r.Backoff == 0 {
+		return time.Millisecond * 100
+	}
+
+	return r.Backoff
+}
+
+// Valid validates retry options.
+func (r *Retry) Valid() error {
+	if r.Attempts < 0 {
+		return errors.New("retry attempts must not be negative")
+	}
+
+	if r.Backoff < 0 {
+		return errors.New("retry backoff must not be negative")
+	}
+
+	for _, method := range r.Methods {
+		if method != "*" && (!strings.HasPrefix(method, "/") || strings.Count(method, "/") != 2) {
+			return fmt.Errorf("invalid method name '%s', expected /package.Service/Method", method)
+		}
+	}
+
+	return nil
+}
+
+// Valid validates rate limits.
+func (r *RateLimit) Valid() error {
+	for _, m := range r.Methods {
+		if m.Method == "" {
+			return errors.New("rate limit method is required")
+		}
+
+		if _, _, err := parseRate(m.Limit); err != nil {
 			return err
 		}
 
-		if _, err := os.Stat(c.TLS.Cert); err != nil {
-			if os.IsNotExist(err) {
-				return fmt.Errorf("cert file '%s' does not exists", c.TLS.Cert)
-			}
+		if m.Burst < 0 {
+			return fmt.Errorf("rate limit burst of method '%s' must not be negative", m.Method)
+		}
+	}
 
+	if r.Client != "" {
+		if _, _, err := parseRate(r.Client); err != nil {
 			return err
 		}
 	}
@@ -93,21 +681,807 @@ func (c *Config) Valid() error {
 	return nil
 }
 
-// Listener creates new rpc socket Listener.
-func (c *Config) Listener() (net.Listener, error) {
-	dsn := strings.Split(c.Listen, "://")
-	if len(dsn) != 2 {
-		return nil, errors.New("invalid socket DSN (tcp://:6001, unix://rpc.sock)")
+// Enabled returns true if any of the limits is defined.
+func (r *RateLimit) Enabled() bool {
+	return len(r.Methods) != 0 || r.Client != ""
+}
+
+// Keepalive defines server keepalive parameters and enforcement policy, zero values fallback to gRPC defaults.
+// Durations can be specified as strings ("30s", "5m").
+type Keepalive struct {
+	// MaxConnectionIdle closes connections idle for given duration.
+	MaxConnectionIdle time.Duration
+
+	// MaxConnectionAge closes connections after given duration.
+	MaxConnectionAge time.Duration
+
+	// MaxConnectionAgeGrace defines time given to pending RPCs to complete before connection is closed
+	// due to MaxConnectionAge.
+	MaxConnectionAgeGrace time.Duration
+
+	// Time defines how often the server pings idle clients.
+	Time time.Duration
+
+	// Timeout defines how long the server waits for ping response before closing the connection.
+	Timeout time.Duration
+
+	// MinTime defines minimal interval between client pings, clients pinging more frequently are disconnected.
+	MinTime time.Duration
+
+	// PermitWithoutStream allows client pings when there are no active streams.
+	PermitWithoutStream bool
+}
+
+// Endpoint defines address to listen with it's own TLS settings.
+type Endpoint struct {
+	// Address to listen, tcp://:9002 or unix://grpc.sock.
+	Address string
+
+	// TLS settings of the endpoint, connections are not encrypted when certificate and key are not set.
+	TLS TLS
+}
+
+// Valid validates the address and TLS settings of the endpoint.
+func (e *Endpoint) Valid() error {
+	if err := validateListen(e.Address); err != nil {
+		return err
+	}
+
+	if !e.TLS.Enabled() {
+		return nil
 	}
 
-	if dsn[0] == "unix" {
-		syscall.Unlink(dsn[1])
+	if problems := e.TLS.problems(); len(problems) != 0 {
+		return fmt.Errorf("endpoint %s: %s", e.Address, problems[0])
 	}
 
-	return net.Listen(dsn[0], dsn[1])
+	return nil
 }
 
-// EnableTLS returns true if rr must listen TLS connections.
-func (c *Config) EnableTLS() bool {
-	return c.TLS.Key != "" || c.TLS.Cert != ""
+// TLS defines auth credentials.
+type TLS struct {
+	// Key defined private server key.
+	Key string
+
+	// Cert is https certificate.
+	Cert string
+
+	// RootCA points to the PEM bundle used to verify client certificates. Enables mutual TLS.
+	RootCA string
+
+	// ClientAuth defines the client certificate policy: "request", "require" or "verify".
+	// Defaults to "verify" when RootCA is set.
+	ClientAuth string
+
+	// MinVersion defines minimal accepted TLS version ("1.0", "1.1", "1.2", "1.3"). Defaults to 1.2.
+	MinVersion string
+
+	// MaxVersion defines maximal accepted TLS version. Defaults to the highest version supported by Go.
+	MaxVersion string
+
+	// CipherSuites restricts cipher suites of TLS 1.0-1.2 connections ("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"),
+	// only secure suites are accepted. Defaults to Go defaults, TLS 1.3 suites are not configurable.
+	CipherSuites []string
+}
+
+// Hydrate the config, values are validated by Init once environment overrides are applied.
+func (c *Config) Hydrate(cfg service.Config) error {
+	if c.Workers == nil {
+		c.Workers = &roadrunner.ServerConfig{}
+	}
+
+	c.Workers.InitDefaults()
+	c.Metrics.Namespace = "rr_grpc"
+	c.AccessLog.Level = "info"
+	if err := cfg.Unmarshal(c); err != nil {
+		return err
+	}
+	c.Workers.UpscaleDurations()
+
+	if c.MaxJobs != 0 {
+		c.Workers.Pool.MaxJobs = c.MaxJobs
+	}
+
+	for i := range c.Pools {
+		c.Pools[i].initDefaults(c.Workers)
+	}
+
+	return nil
+}
+
+// validateListen checks listen DSN (tcp://:9001 or unix://grpc.sock).
+func validateListen(address string) error {
+	dsn := strings.Split(address, "://")
+	if len(dsn) != 2 || dsn[1] == "" {
+		return fmt.Errorf("invalid listen address '%s', expected tcp://host:port or unix://file.sock", address)
+	}
+
+	switch dsn[0] {
+	case "unix":
+		return nil
+	case "tcp", "tcp4", "tcp6":
+		_, port, err := net.SplitHostPort(dsn[1])
+		if err != nil {
+			return fmt.Errorf("invalid listen address '%s': %s", address, err)
+		}
+
+		if p, err := strconv.ParseUint(port, 10, 16); err != nil || (p == 0 && port != "0") {
+			return fmt.Errorf("invalid listen port '%s'", port)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported listen network '%s', expected tcp or unix", dsn[0])
+}
+
+// problems returns every problem of TLS certificate, key and options.
+func (t *TLS) problems() []string {
+	problems := make([]string, 0)
+	if t.Cert == "" {
+		problems = append(problems, "tls cert is required when tls key is set")
+	} else if _, err := ioutil.ReadFile(t.Cert); err != nil {
+		problems = append(problems, fmt.Sprintf("unable to read tls cert: %s", err))
+	}
+
+	if t.Key == "" {
+		problems = append(problems, "tls key is required when tls cert is set")
+	} else if _, err := ioutil.ReadFile(t.Key); err != nil {
+		problems = append(problems, fmt.Sprintf("unable to read tls key: %s", err))
+	}
+
+	if len(problems) == 0 {
+		if _, err := tls.LoadX509KeyPair(t.Cert, t.Key); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid tls cert or key: %s", err))
+		}
+	}
+
+	if t.RootCA != "" {
+		if _, err := t.clientCAs(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if _, err := t.clientAuth(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if _, _, err := t.versions(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if _, err := t.cipherSuites(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	return problems
+}
+
+// Enabled returns true when both or any of certificate and key are set.
+func (t *TLS) Enabled() bool {
+	return t.Key != "" || t.Cert != ""
+}
+
+// Valid checks that certificate, key and root CA files exist and TLS options are valid.
+func (t *TLS) Valid() error {
+	if problems := t.problems(); len(problems) != 0 {
+		return errors.New(problems[0])
+	}
+
+	return nil
+}
+
+// overrideEnv overrides configuration values with environment variables (GRPC_LISTEN, GRPC_PROTO, GRPC_TLS_KEY,
+// GRPC_TLS_CERT, GRPC_TLS_ROOT_CA). Environment takes precedence over the config file, given values (environment
+// service) take precedence over the process environment.
+func (c *Config) overrideEnv(values map[string]string) {
+	overrides := map[string]*string{
+		"GRPC_LISTEN":      &c.Listen,
+		"GRPC_PROTO":       &c.Proto,
+		"GRPC_TLS_KEY":     &c.TLS.Key,
+		"GRPC_TLS_CERT":    &c.TLS.Cert,
+		"GRPC_TLS_ROOT_CA": &c.TLS.RootCA,
+	}
+
+	for key, field := range overrides {
+		if value, ok := values[key]; ok {
+			*field = value
+		} else if value, ok := os.LookupEnv(key); ok {
+			*field = value
+		}
+	}
+}
+
+// exposed returns true if service with the given full name ("app.Service") must be registered.
+func (c *Config) exposed(service string) bool {
+	for _, pattern := range c.ExcludeServices {
+		if ok, _ := path.Match(pattern, service); ok {
+			return false
+		}
+	}
+
+	if len(c.ExposeServices) == 0 {
+		return true
+	}
+
+	for _, pattern := range c.ExposeServices {
+		if ok, _ := path.Match(pattern, service); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// codecName returns name of the configured codec.
+func (c *Config) codecName() string {
+	if c.Codec == "" {
+		return "proto"
+	}
+
+	return c.Codec
+}
+
+// Valid checks that proto files exist, listen addresses are well formed, TLS certificate and key are both set and
+// readable and the rest of the options are valid. Error lists every found problem, proto files are parsed on Serve.
+func (c *Config) Valid() error {
+	problems := make([]string, 0)
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if c.Proto == "" && len(c.Protos) == 0 {
+		problems = append(problems, "proto file is required (proto: service.proto)")
+	} else if _, err := c.ProtoFiles(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	for _, dir := range c.ImportPaths {
+		if _, err := os.Stat(dir); err != nil {
+			problems = append(problems, fmt.Sprintf("import path '%s' does not exists", dir))
+		}
+	}
+
+	for _, pattern := range append(append([]string{}, c.ExposeServices...), c.ExcludeServices...) {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			problems = append(problems, fmt.Sprintf("invalid service pattern '%s'", pattern))
+		}
+	}
+
+	if c.Workers == nil || c.Workers.Pool == nil {
+		problems = append(problems, "workers are not configured")
+	} else {
+		check(c.Workers.Pool.Valid())
+	}
+
+	found := make(map[string]bool)
+	for _, address := range c.listenAddresses() {
+		if err := validateListen(address); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		if found[address] {
+			problems = append(problems, fmt.Sprintf("duplicate listen address '%s'", address))
+		}
+		found[address] = true
+
+		if c.ReusePort && !strings.HasPrefix(address, "tcp") {
+			problems = append(problems, "reuse port requires tcp socket")
+		}
+	}
+
+	_, err := c.socketMode()
+	check(err)
+
+	if c.Backlog < 0 {
+		problems = append(problems, "listen backlog must not be negative")
+	}
+
+	check(c.listenerSupported())
+
+	if c.Compression != "" && c.Compression != "gzip" {
+		problems = append(problems, fmt.Sprintf("unsupported compression '%s'", c.Compression))
+	}
+
+	for _, size := range []string{c.MaxRecvMsgSize, c.MaxSendMsgSize, c.MaxRequestBytes, c.MaxMemory} {
+		_, err := parseSize(size)
+		check(err)
+	}
+
+	for _, size := range []string{c.ReadBufferSize, c.WriteBufferSize} {
+		if v, err := parseSize(size); err != nil || (size != "" && v <= 0) {
+			problems = append(problems, fmt.Sprintf("invalid buffer size '%s', positive size expected", size))
+		}
+	}
+
+	check(c.Keepalive.Valid())
+
+	if c.AccessLog.Enable {
+		check(c.AccessLog.Valid())
+	}
+
+	check(c.RateLimit.Valid())
+
+	for _, m := range c.Concurrency {
+		check(m.Valid())
+	}
+
+	check(c.Auth.Valid())
+	check(c.Warmup.Valid())
+
+	if c.GracefulTimeout < 0 {
+		problems = append(problems, "graceful timeout must not be negative")
+	}
+
+	if c.ConnectionTimeout < 0 {
+		problems = append(problems, "connection timeout must not be negative")
+	}
+
+	if c.NumStreamWorkers < 0 || int64(c.NumStreamWorkers) > math.MaxUint32 {
+		problems = append(problems, fmt.Sprintf("invalid number of stream workers %v", c.NumStreamWorkers))
+	}
+
+	for _, method := range c.KillOnCancel {
+		if !strings.HasPrefix(method, "/") || strings.Count(method, "/") != 2 {
+			problems = append(problems, fmt.Sprintf("invalid method name '%s', expected /package.Service/Method", method))
+		}
+	}
+
+	if c.MaxSessions < 0 {
+		problems = append(problems, "max sessions must not be negative")
+	}
+
+	if c.MaxExecutionTime < 0 {
+		problems = append(problems, "max execution time must not be negative")
+	}
+
+	if c.MaxJobs < 0 {
+		problems = append(problems, "max jobs must not be negative")
+	}
+
+	for _, t := range c.Timeouts {
+		if t.Method != "*" && (!strings.HasPrefix(t.Method, "/") || strings.Count(t.Method, "/") != 2) {
+			problems = append(problems, fmt.Sprintf("invalid method name '%s', expected /package.Service/Method", t.Method))
+		}
+
+		if t.Timeout <= 0 {
+			problems = append(problems, fmt.Sprintf("timeout of method '%s' must be positive", t.Method))
+		}
+	}
+
+	check(c.Retry.Valid())
+	check(c.validPools())
+
+	if c.EnableTLS() {
+		problems = append(problems, c.TLS.problems()...)
+	}
+
+	for _, e := range c.Endpoints {
+		check(e.Valid())
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid grpc configuration:\n - %s", strings.Join(problems, "\n - "))
+}
+
+// ProtoFiles returns list of unique proto files matched by Proto and Protos patterns.
+func (c *Config) ProtoFiles() ([]string, error) {
+	files := make([]string, 0)
+	found := make(map[string]bool)
+
+	for _, pattern := range append([]string{c.Proto}, c.Protos...) {
+		if pattern == "" {
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proto pattern '%s': %s", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("proto file '%s' does not exists", pattern)
+		}
+
+		for _, file := range matches {
+			if !found[file] {
+				found[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// importPaths returns include directories for the given proto file, every directory is listed once.
+func (c *Config) importPaths(file string) []string {
+	paths := make([]string, 0, len(c.ImportPaths)+1)
+	known := make(map[string]bool)
+
+	for _, dir := range append([]string{filepath.Dir(file)}, c.ImportPaths...) {
+		if dir = filepath.Clean(dir); !known[dir] {
+			known[dir] = true
+			paths = append(paths, dir)
+		}
+	}
+
+	return paths
+}
+
+// listenAddresses returns every address to listen, Listen goes first.
+func (c *Config) listenAddresses() []string {
+	return append([]string{c.Listen}, c.Addresses...)
+}
+
+// Listener creates new rpc socket Listener.
+func (c *Config) Listener() (net.Listener, error) {
+	return c.listener(c.Listen)
+}
+
+// Listeners creates listeners of all configured addresses, created listeners are closed on error.
+func (c *Config) Listeners() ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(c.Addresses)+1)
+	for _, address := range c.listenAddresses() {
+		ln, err := c.listener(address)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+
+			return nil, err
+		}
+
+		listeners = append(listeners, ln)
+	}
+
+	return listeners, nil
+}
+
+// listener creates socket listener of the given address.
+func (c *Config) listener(address string) (net.Listener, error) {
+	dsn := strings.Split(address, "://")
+	if len(dsn) != 2 {
+		return nil, errors.New("invalid socket DSN (tcp://:6001, unix://rpc.sock)")
+	}
+
+	if err := c.listenerSupported(); err != nil {
+		return nil, err
+	}
+
+	lc := &net.ListenConfig{}
+	if c.ReusePort {
+		lc.Control = reusePort
+	}
+
+	if dsn[0] != "unix" {
+		return c.listen(lc, dsn[0], dsn[1])
+	}
+
+	mode, err := c.socketMode()
+	if err != nil {
+		return nil, err
+	}
+
+	// remove stale socket
+	syscall.Unlink(dsn[1])
+
+	ln, err := c.listen(lc, dsn[0], dsn[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != 0 {
+		if err := os.Chmod(dsn[1], mode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+
+	return ln, nil
+}
+
+// listen creates listener and applies configured backlog.
+func (c *Config) listen(lc *net.ListenConfig, network, address string) (net.Listener, error) {
+	ln, err := lc.Listen(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Backlog != 0 {
+		if err := setBacklog(ln, c.Backlog); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+
+	return ln, nil
+}
+
+// listenerSupported returns error when listener options are not supported by the platform.
+func (c *Config) listenerSupported() error {
+	if c.ReusePort && !reusePortSupported {
+		return fmt.Errorf("reuse port is not supported on %s", runtime.GOOS)
+	}
+
+	if c.Backlog != 0 && !backlogSupported {
+		return fmt.Errorf("listen backlog is not supported on %s", runtime.GOOS)
+	}
+
+	return nil
+}
+
+// socketMode parses unix socket permissions, zero means default permissions.
+func (c *Config) socketMode() (os.FileMode, error) {
+	if c.SocketPermissions == "" {
+		return 0, nil
+	}
+
+	mode, err := strconv.ParseUint(c.SocketPermissions, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid socket permissions '%s'", c.SocketPermissions)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// EnableTLS returns true if rr must listen TLS connections.
+func (c *Config) EnableTLS() bool {
+	return c.TLS.Enabled()
+}
+
+// Valid validates keepalive durations.
+func (k *Keepalive) Valid() error {
+	durations := []time.Duration{
+		k.MaxConnectionIdle,
+		k.MaxConnectionAge,
+		k.MaxConnectionAgeGrace,
+		k.Time,
+		k.Timeout,
+		k.MinTime,
+	}
+
+	for _, d := range durations {
+		if d < 0 {
+			return errors.New("keepalive durations must not be negative")
+		}
+	}
+
+	return nil
+}
+
+// ServerParameters returns keepalive server parameters or nil if none configured.
+func (k *Keepalive) ServerParameters() *keepalive.ServerParameters {
+	params := keepalive.ServerParameters{
+		MaxConnectionIdle:     k.MaxConnectionIdle,
+		MaxConnectionAge:      k.MaxConnectionAge,
+		MaxConnectionAgeGrace: k.MaxConnectionAgeGrace,
+		Time:                  k.Time,
+		Timeout:               k.Timeout,
+	}
+
+	if params == (keepalive.ServerParameters{}) {
+		return nil
+	}
+
+	return &params
+}
+
+// EnforcementPolicy returns keepalive enforcement policy or nil if none configured.
+func (k *Keepalive) EnforcementPolicy() *keepalive.EnforcementPolicy {
+	if k.MinTime == 0 && !k.PermitWithoutStream {
+		return nil
+	}
+
+	return &keepalive.EnforcementPolicy{MinTime: k.MinTime, PermitWithoutStream: k.PermitWithoutStream}
+}
+
+// TLSConfig creates tls configuration based on given certificates and client auth options.
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	return c.TLS.Config()
+}
+
+// Config creates tls configuration based on given certificates and client auth options.
+func (t *TLS) Config() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if cfg.ClientAuth, err = t.clientAuth(); err != nil {
+		return nil, err
+	}
+
+	if t.RootCA != "" {
+		if cfg.ClientCAs, err = t.clientCAs(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.MinVersion, cfg.MaxVersion, err = t.versions(); err != nil {
+		return nil, err
+	}
+
+	if cfg.CipherSuites, err = t.cipherSuites(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// clientCAs loads the certificate pool used to verify client certificates.
+func (t *TLS) clientCAs() (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(t.RootCA)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("root CA file '%s' does not exists", t.RootCA)
+		}
+
+		return nil, fmt.Errorf("unable to read root CA file '%s': %s", t.RootCA, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("root CA file '%s' does not contain any valid certificate", t.RootCA)
+	}
+
+	return pool, nil
+}
+
+// clientAuth resolves client certificate policy.
+func (t *TLS) clientAuth() (tls.ClientAuthType, error) {
+	switch t.ClientAuth {
+	case "":
+		if t.RootCA != "" {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify":
+		if t.RootCA == "" {
+			return tls.NoClientCert, errors.New("client certificate verification requires root CA")
+		}
+
+		return tls.RequireAndVerifyClientCert, nil
+	}
+
+	return tls.NoClientCert, fmt.Errorf("invalid client auth type '%s' (request, require, verify)", t.ClientAuth)
+}
+
+// versions resolves min and max TLS versions.
+func (t *TLS) versions() (min, max uint16, err error) {
+	if min, err = tlsVersion(t.MinVersion, tls.VersionTLS12); err != nil {
+		return 0, 0, err
+	}
+
+	if max, err = tlsVersion(t.MaxVersion, 0); err != nil {
+		return 0, 0, err
+	}
+
+	if max != 0 && max < min {
+		return 0, 0, fmt.Errorf("max TLS version %s is lower than min version", t.MaxVersion)
+	}
+
+	return min, max, nil
+}
+
+// cipherSuites resolves configured cipher suite names, nil means Go defaults.
+func (t *TLS) cipherSuites() ([]uint16, error) {
+	if len(t.CipherSuites) == 0 {
+		return nil, nil
+	}
+
+	suites := make([]uint16, 0, len(t.CipherSuites))
+	for _, name := range t.CipherSuites {
+		suite, err := cipherSuite(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+
+		suites = append(suites, suite)
+	}
+
+	return suites, nil
+}
+
+// cipherSuite converts secure cipher suite name into it's id.
+func cipherSuite(name string) (uint16, error) {
+	for _, s := range tls.CipherSuites() {
+		if s.Name != name {
+			continue
+		}
+
+		for _, v := range s.SupportedVersions {
+			if v != tls.VersionTLS13 {
+				return s.ID, nil
+			}
+		}
+
+		return 0, fmt.Errorf("cipher suite '%s' is TLS 1.3 only and can not be configured", name)
+	}
+
+	for _, s := range tls.InsecureCipherSuites() {
+		if s.Name == name {
+			return 0, fmt.Errorf("cipher suite '%s' is insecure", name)
+		}
+	}
+
+	return 0, fmt.Errorf("invalid cipher suite '%s'", name)
+}
+
+// size suffixes
+var sizeUnits = []struct {
+	suffix string
+	size   int
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"B", 1},
+}
+
+// parseSize parses size in bytes with optional KB, MB or GB suffix, empty value is zero.
+func parseSize(v string) (int, error) {
+	value, unit := strings.ToUpper(strings.TrimSpace(v)), 1
+	if value == "" {
+		return 0, nil
+	}
+
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(value, u.suffix) {
+			value, unit = strings.TrimSpace(strings.TrimSuffix(value, u.suffix)), u.size
+			break
+		}
+	}
+
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", v)
+	}
+
+	return size * unit, nil
+}
+
+// supported TLS versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsVersion converts version string into tls package constant.
+func tlsVersion(v string, def uint16) (uint16, error) {
+	if v == "" {
+		return def, nil
+	}
+
+	if version, ok := tlsVersions[v]; ok {
+		return version, nil
+	}
+
+	return 0, fmt.Errorf("invalid TLS version '%s' (1.0, 1.1, 1.2, 1.3)", v)
+}
+
+// tlsVersionName converts tls package constant into version string.
+func tlsVersionName(version uint16) string {
+	for name, v := range tlsVersions {
+		if v == version {
+			return name
+		}
+	}
+
+	return "unknown"
 }
@@ -1,10 +1,17 @@
 package grpc
 
 import (
+	"bytes"
+	"crypto/tls"
 	"encoding/json"
+	"github.com/spf13/viper"
 	"github.com/spiral/roadrunner"
 	"github.com/spiral/roadrunner/service"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
@@ -141,6 +148,82 @@ func Test_Config_UnixListener(t *testing.T) {
 	ln.Close()
 }
 
+func Test_Config_Listener_ReusePort(t *testing.T) {
+	cfg := &Config{Listen: "tcp://127.0.0.1:9095", ReusePort: true, Backlog: 16}
+	if runtime.GOOS != "linux" {
+		_, err := cfg.Listener()
+		assert.Error(t, err)
+		return
+	}
+
+	ln, err := cfg.Listener()
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	// both processes (listeners) accept connections on the same port
+	ln2, err := cfg.Listener()
+	assert.NoError(t, err)
+	ln2.Close()
+
+	cfg.ReusePort = false
+	_, err = cfg.Listener()
+	assert.Error(t, err)
+}
+
+func Test_Config_Listener_Backlog(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	cfg := &Config{Listen: "tcp://127.0.0.1:9096", Backlog: 1}
+
+	ln, err := cfg.Listener()
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", "127.0.0.1:9096")
+	assert.NoError(t, err)
+	conn.Close()
+}
+
+func Test_Config_UnixListener_Permissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	cfg := &Config{
+		Listen:            "unix://rr.sock",
+		SocketPermissions: "0660",
+		Proto:             "tests/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+	}
+
+	assert.NoError(t, cfg.Valid())
+
+	ln, err := cfg.Listener()
+	assert.NoError(t, err)
+
+	stat, err := os.Stat("rr.sock")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), stat.Mode().Perm())
+
+	ln.Close()
+
+	_, err = os.Stat("rr.sock")
+	assert.True(t, os.IsNotExist(err))
+
+	cfg.SocketPermissions = "rw"
+	assert.Error(t, cfg.Valid())
+}
+
 func Test_Config_InvalidWorkerPool(t *testing.T) {
 	cfg := &Config{
 		Listen: "unix://rr.sock",
@@ -202,3 +285,781 @@ func Test_Config_TLS_No_Cert(t *testing.T) {
 
 	assert.Error(t, cfg.Valid())
 }
+
+func Test_Config_TLS_RootCA(t *testing.T) {
+	cfg := &Config{
+		Listen: "tcp://:8080",
+		TLS: TLS{
+			Key:    "tests/server.key",
+			Cert:   "tests/server.crt",
+			RootCA: "tests/server.crt",
+		},
+		Proto: "tests/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+	}
+
+	assert.NoError(t, cfg.Valid())
+
+	tlsCfg, err := cfg.TLSConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsCfg.ClientAuth)
+	assert.NotNil(t, tlsCfg.ClientCAs)
+}
+
+func Test_Config_TLS_No_RootCA(t *testing.T) {
+	cfg := &Config{
+		Listen: "tcp://:8080",
+		TLS: TLS{
+			Key:    "tests/server.key",
+			Cert:   "tests/server.crt",
+			RootCA: "tests/ca.crt",
+		},
+		Proto: "tests/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+	}
+
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_TLS_Invalid_ClientAuth(t *testing.T) {
+	cfg := &Config{
+		Listen: "tcp://:8080",
+		TLS: TLS{
+			Key:        "tests/server.key",
+			Cert:       "tests/server.crt",
+			ClientAuth: "always",
+		},
+		Proto: "tests/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+	}
+
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_TLS_Versions(t *testing.T) {
+	cfg := &Config{
+		Listen: "tcp://:8080",
+		TLS: TLS{
+			Key:  "tests/server.key",
+			Cert: "tests/server.crt",
+		},
+		Proto: "tests/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+	}
+
+	tlsCfg, err := cfg.TLSConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsCfg.MinVersion)
+	assert.Equal(t, uint16(0), tlsCfg.MaxVersion)
+
+	cfg.TLS.MinVersion = "1.3"
+	cfg.TLS.MaxVersion = "1.3"
+	tlsCfg, err = cfg.TLSConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsCfg.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsCfg.MaxVersion)
+
+	cfg.TLS.MaxVersion = "1.2"
+	assert.Error(t, cfg.Valid())
+
+	cfg.TLS.MinVersion = "1.4"
+	cfg.TLS.MaxVersion = ""
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_TLS_CipherSuites(t *testing.T) {
+	cfg := &TLS{}
+
+	suites, err := cfg.cipherSuites()
+	assert.NoError(t, err)
+	assert.Nil(t, suites)
+
+	cfg.CipherSuites = []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", " TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}
+	suites, err = cfg.cipherSuites()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	}, suites)
+
+	for _, name := range []string{"TLS_RSA_WITH_RC4_128_SHA", "TLS_AES_128_GCM_SHA256", "invalid"} {
+		cfg.CipherSuites = []string{name}
+		_, err = cfg.cipherSuites()
+		assert.Error(t, err, name)
+	}
+}
+
+func Test_Config_MsgSize(t *testing.T) {
+	cfg := &Config{
+		Listen:         "tcp://:8080",
+		Proto:          "tests/test.proto",
+		MaxRecvMsgSize: "-1",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+	}
+
+	assert.Error(t, cfg.Valid())
+
+	cfg.MaxRecvMsgSize = "16MB"
+	cfg.MaxSendMsgSize = "1024"
+	assert.NoError(t, cfg.Valid())
+
+	cfg.MaxSendMsgSize = "16XB"
+	assert.Error(t, cfg.Valid())
+
+	cfg.MaxSendMsgSize = ""
+	cfg.MaxRequestBytes = "1MB"
+	assert.NoError(t, cfg.Valid())
+
+	cfg.MaxRequestBytes = "-1"
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_BufferSize(t *testing.T) {
+	cfg := &Config{
+		Listen:          "tcp://:8080",
+		Proto:           "parser/test.proto",
+		ReadBufferSize:  "64KB",
+		WriteBufferSize: "64KB",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+	}
+
+	assert.NoError(t, cfg.Valid())
+
+	for _, size := range []string{"0", "-1", "64XB"} {
+		cfg.WriteBufferSize = size
+		assert.Error(t, cfg.Valid(), size)
+	}
+}
+
+func Test_Config_ParseSize(t *testing.T) {
+	sizes := map[string]int{
+		"":      0,
+		"1024":  1024,
+		"512B":  512,
+		"64KB":  64 << 10,
+		"16MB":  16 << 20,
+		"16 mb": 16 << 20,
+		"1GB":   1 << 30,
+	}
+
+	for v, expected := range sizes {
+		size, err := parseSize(v)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, size, v)
+	}
+
+	_, err := parseSize("MB")
+	assert.Error(t, err)
+}
+
+func Test_Config_Keepalive(t *testing.T) {
+	cfg := &Config{
+		Listen: "tcp://:8080",
+		Proto:  "tests/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+	}
+
+	assert.NoError(t, cfg.Valid())
+	assert.Nil(t, cfg.Keepalive.ServerParameters())
+	assert.Nil(t, cfg.Keepalive.EnforcementPolicy())
+
+	cfg.Keepalive = Keepalive{
+		MaxConnectionIdle: 5 * time.Minute,
+		Time:              30 * time.Second,
+		MinTime:           10 * time.Second,
+	}
+
+	assert.NoError(t, cfg.Valid())
+	assert.Equal(t, 5*time.Minute, cfg.Keepalive.ServerParameters().MaxConnectionIdle)
+	assert.Equal(t, 30*time.Second, cfg.Keepalive.ServerParameters().Time)
+	assert.Equal(t, 10*time.Second, cfg.Keepalive.EnforcementPolicy().MinTime)
+
+	cfg.Keepalive.Timeout = -time.Second
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_UnixListener_Stale(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	dir, err := ioutil.TempDir("", "grpc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "grpc.sock")
+	assert.NoError(t, ioutil.WriteFile(socket, []byte{}, 0644))
+
+	cfg := &Config{Listen: "unix://" + socket}
+
+	ln, err := cfg.Listener()
+	assert.NoError(t, err)
+
+	stat, err := os.Stat(socket)
+	assert.NoError(t, err)
+	assert.True(t, stat.Mode()&os.ModeSocket != 0)
+
+	ln.Close()
+
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_Config_Listeners(t *testing.T) {
+	cfg := &Config{Listen: "tcp://127.0.0.1:9097", Addresses: []string{"tcp://127.0.0.1:9098"}}
+
+	listeners, err := cfg.Listeners()
+	assert.NoError(t, err)
+	assert.Len(t, listeners, 2)
+	assert.Equal(t, "127.0.0.1:9097", listeners[0].Addr().String())
+	assert.Equal(t, "127.0.0.1:9098", listeners[1].Addr().String())
+
+	// created listeners are closed when any address fails
+	cfg.Addresses = append(cfg.Addresses, "tcp://127.0.0.1:9097")
+	_, err = cfg.Listeners()
+	assert.Error(t, err)
+
+	for _, l := range listeners {
+		l.Close()
+	}
+
+	cfg.Addresses = []string{"tcp://127.0.0.1:9098"}
+	listeners, err = cfg.Listeners()
+	assert.NoError(t, err)
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+func Test_Config_Addresses_Valid(t *testing.T) {
+	cfg := &Config{
+		Listen:    "tcp://:8080",
+		Addresses: []string{"tcp://:8081", "unix://grpc.sock"},
+		Proto:     "parser/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+	}
+	assert.NoError(t, cfg.Valid())
+
+	cfg.Addresses = []string{"tcp://:8080"}
+	assert.Error(t, cfg.Valid())
+
+	cfg.Addresses = []string{"tcp8081"}
+	assert.Error(t, cfg.Valid())
+
+	cfg.Addresses = []string{"unix://grpc.sock"}
+	cfg.ReusePort = true
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_ExposeServices(t *testing.T) {
+	cfg := &Config{}
+	assert.True(t, cfg.exposed("app.internal.Admin"))
+
+	cfg.ExposeServices = []string{"app.public.*", "app.Echo"}
+	assert.True(t, cfg.exposed("app.public.Users"))
+	assert.True(t, cfg.exposed("app.Echo"))
+	assert.False(t, cfg.exposed("app.internal.Admin"))
+
+	cfg.ExcludeServices = []string{"app.public.Debug"}
+	assert.True(t, cfg.exposed("app.public.Users"))
+	assert.False(t, cfg.exposed("app.public.Debug"))
+
+	cfg.ExposeServices = nil
+	assert.True(t, cfg.exposed("app.internal.Admin"))
+	assert.False(t, cfg.exposed("app.public.Debug"))
+
+	cfg = &Config{
+		Listen: "tcp://:8080",
+		Proto:  "parser/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+		ExposeServices:  []string{"app.namespace.*"},
+		ExcludeServices: []string{"app.namespace.PongService"},
+	}
+	assert.NoError(t, cfg.Valid())
+
+	cfg.ExposeServices = []string{"app.["}
+	assert.Error(t, cfg.Valid())
+
+	cfg.ExposeServices = nil
+	cfg.ExcludeServices = []string{""}
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_Keepalive_Durations(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(bytes.NewBufferString(`
+keepalive:
+  maxConnectionIdle: 5m
+  time: 30s
+  timeout: 10s
+  minTime: 15s
+  permitWithoutStream: true
+`)))
+
+	cfg := &Config{}
+	assert.NoError(t, v.Unmarshal(cfg))
+
+	assert.Equal(t, 5*time.Minute, cfg.Keepalive.MaxConnectionIdle)
+	assert.Equal(t, 30*time.Second, cfg.Keepalive.Time)
+	assert.Equal(t, 10*time.Second, cfg.Keepalive.Timeout)
+	assert.Equal(t, 15*time.Second, cfg.Keepalive.EnforcementPolicy().MinTime)
+	assert.True(t, cfg.Keepalive.EnforcementPolicy().PermitWithoutStream)
+}
+
+func Test_Config_ProtoFiles(t *testing.T) {
+	cfg := &Config{
+		Proto:  "parser/test_nested/*.proto",
+		Protos: []string{"parser/test.proto", "parser/test_nested/pong.proto"},
+	}
+
+	files, err := cfg.ProtoFiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"parser/test_nested/message.proto",
+		"parser/test_nested/pong.proto",
+		"parser/test_nested/test_import.proto",
+		"parser/test.proto",
+	}, files)
+
+	cfg.Protos = append(cfg.Protos, "parser/missing/*.proto")
+	_, err = cfg.ProtoFiles()
+	assert.Error(t, err)
+}
+
+func Test_Config_ImportPaths(t *testing.T) {
+	cfg := &Config{
+		Listen:      "tcp://:8080",
+		Proto:       "parser/test_include/api/service.proto",
+		ImportPaths: []string{"parser/test_include/shared"},
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+	}
+
+	assert.NoError(t, cfg.Valid())
+	assert.Equal(t,
+		[]string{"parser/test_include/api", "parser/test_include/shared"},
+		cfg.importPaths(cfg.Proto),
+	)
+
+	// proto directory is searched once
+	cfg.ImportPaths = []string{"parser/test_include/shared/", "parser/test_include/api", "parser/test_include/shared"}
+	assert.Equal(t,
+		[]string{"parser/test_include/api", "parser/test_include/shared"},
+		cfg.importPaths(cfg.Proto),
+	)
+
+	cfg.ImportPaths = append(cfg.ImportPaths, "parser/missing")
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_AccessLog(t *testing.T) {
+	cfg := &Config{
+		Listen: "tcp://:8080",
+		Proto:  "tests/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+		AccessLog: AccessLog{Enable: true, Level: "info", SlowThreshold: time.Second},
+	}
+
+	assert.NoError(t, cfg.Valid())
+
+	cfg.AccessLog.Level = "verbose"
+	assert.Error(t, cfg.Valid())
+
+	cfg.AccessLog.Level = "warn"
+	cfg.AccessLog.SlowThreshold = -time.Second
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_RateLimit(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(bytes.NewBufferString(`
+rateLimit:
+  methods:
+    - method: /app.Service/Method
+      limit: 1000/s
+    - method: "*"
+      limit: 60/m
+  client: 10/s
+  clientKey: x-api-key
+`)))
+
+	cfg := &Config{}
+	assert.NoError(t, v.Unmarshal(cfg))
+
+	assert.True(t, cfg.RateLimit.Enabled())
+	assert.Equal(t, []MethodLimit{
+		{Method: "/app.Service/Method", Limit: "1000/s"},
+		{Method: "*", Limit: "60/m"},
+	}, cfg.RateLimit.Methods)
+	assert.Equal(t, "10/s", cfg.RateLimit.Client)
+	assert.Equal(t, "x-api-key", cfg.RateLimit.ClientKey)
+	assert.NoError(t, cfg.RateLimit.Valid())
+
+	cfg.RateLimit.Client = "10/d"
+	assert.Error(t, cfg.RateLimit.Valid())
+
+	cfg.RateLimit.Client = ""
+	cfg.RateLimit.Methods = append(cfg.RateLimit.Methods, MethodLimit{Limit: "1/s"})
+	assert.Error(t, cfg.RateLimit.Valid())
+
+	assert.False(t, (&RateLimit{}).Enabled())
+}
+
+func Test_Config_KillOnCancel(t *testing.T) {
+	cfg := &Config{
+		Listen: "tcp://:8080",
+		Proto:  "tests/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+		KillOnCancel: []string{"/app.Service/Report"},
+	}
+
+	assert.NoError(t, cfg.Valid())
+
+	cfg.KillOnCancel = []string{"Report"}
+	assert.Error(t, cfg.Valid())
+
+	cfg.KillOnCancel = []string{"/app.Service.Report"}
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_MaxSessions(t *testing.T) {
+	cfg := &Config{
+		Listen:      "tcp://:8080",
+		Proto:       "parser/test.proto",
+		Workers:     echoWorkers(1),
+		MaxSessions: 10,
+	}
+	assert.NoError(t, cfg.Valid())
+
+	cfg.MaxSessions = -1
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_Retry(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(bytes.NewBufferString(`
+retry:
+  attempts: 3
+  backoff: 50ms
+  methods: ["/app.Service/Get"]
+`)))
+
+	cfg := &Config{}
+	assert.NoError(t, v.Unmarshal(cfg))
+	assert.Equal(t, Retry{Attempts: 3, Backoff: 50 * time.Millisecond, Methods: []string{"/app.Service/Get"}}, cfg.Retry)
+	assert.NoError(t, cfg.Retry.Valid())
+	assert.Equal(t, 50*time.Millisecond, cfg.Retry.backoff())
+
+	assert.Equal(t, 100*time.Millisecond, (&Retry{}).backoff())
+	assert.NoError(t, (&Retry{Attempts: 1, Methods: []string{"*"}}).Valid())
+	assert.Error(t, (&Retry{Attempts: -1}).Valid())
+	assert.Error(t, (&Retry{Backoff: -time.Second}).Valid())
+	assert.Error(t, (&Retry{Methods: []string{"Get"}}).Valid())
+}
+
+func Test_Config_Timeouts(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(bytes.NewBufferString(`
+timeouts:
+  - method: "/app.Service/Report"
+    timeout: 5m
+  - method: "*"
+    timeout: 30s
+`)))
+
+	cfg := &Config{}
+	assert.NoError(t, v.Unmarshal(cfg))
+	assert.Equal(t, []MethodTimeout{
+		{Method: "/app.Service/Report", Timeout: 5 * time.Minute},
+		{Method: "*", Timeout: 30 * time.Second},
+	}, cfg.Timeouts)
+
+	cfg.Listen = "tcp://:8080"
+	cfg.Proto = "tests/test.proto"
+	cfg.Workers = &roadrunner.ServerConfig{
+		Command: "php tests/worker.php",
+		Relay:   "pipes",
+		Pool: &roadrunner.Config{
+			NumWorkers:      1,
+			AllocateTimeout: time.Second,
+			DestroyTimeout:  time.Second,
+		},
+	}
+	assert.NoError(t, cfg.Valid())
+
+	cfg.Timeouts = []MethodTimeout{{Method: "Report", Timeout: time.Second}}
+	assert.Error(t, cfg.Valid())
+
+	cfg.Timeouts = []MethodTimeout{{Method: "*"}}
+	assert.Error(t, cfg.Valid())
+
+	cfg.Timeouts = nil
+	cfg.MaxExecutionTime = -time.Second
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_Valid_Problems(t *testing.T) {
+	cfg := &Config{Listen: "tcp://:9001", Proto: "parser/test.proto", Workers: echoWorkers(1)}
+	assert.NoError(t, cfg.Valid())
+
+	cfg = &Config{
+		Listen:  "unix://grpc.sock",
+		Protos:  []string{"parser/test.proto", "parser/pong.proto"},
+		Workers: echoWorkers(1),
+	}
+	assert.NoError(t, cfg.Valid())
+
+	cfg = &Config{
+		Listen:  "tcp://localhost",
+		Proto:   "missing.proto",
+		TLS:     TLS{Cert: "missing.crt"},
+		MaxJobs: -1,
+	}
+
+	err := cfg.Valid()
+	assert.Error(t, err)
+
+	// every problem is reported
+	assert.Contains(t, err.Error(), "proto file 'missing.proto' does not exists")
+	assert.Contains(t, err.Error(), "invalid listen address 'tcp://localhost'")
+	assert.Contains(t, err.Error(), "unable to read tls cert")
+	assert.Contains(t, err.Error(), "tls key is required")
+	assert.Contains(t, err.Error(), "workers are not configured")
+	assert.Contains(t, err.Error(), "max jobs must not be negative")
+
+	for _, listen := range []string{"", ":9001", "tcp://:port", "tcp://:70000", "udp://:9001", "unix://"} {
+		cfg = &Config{Listen: listen, Proto: "parser/test.proto", Workers: echoWorkers(1)}
+		assert.Error(t, cfg.Valid(), listen)
+	}
+
+	cfg = &Config{Listen: "tcp://:9001", Workers: echoWorkers(1)}
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_OverrideEnv(t *testing.T) {
+	os.Setenv("GRPC_LISTEN", "tcp://:9002")
+	os.Setenv("GRPC_TLS_CERT", "env.crt")
+	defer os.Unsetenv("GRPC_LISTEN")
+	defer os.Unsetenv("GRPC_TLS_CERT")
+
+	cfg := &Config{Listen: "tcp://:9001", Proto: "test.proto", TLS: TLS{Key: "server.key", Cert: "server.crt"}}
+	cfg.overrideEnv(map[string]string{"GRPC_LISTEN": "tcp://:9003", "GRPC_PROTO": "env.proto"})
+
+	// environment service takes precedence over the process environment
+	assert.Equal(t, "tcp://:9003", cfg.Listen)
+	assert.Equal(t, "env.proto", cfg.Proto)
+	assert.Equal(t, "env.crt", cfg.TLS.Cert)
+	assert.Equal(t, "server.key", cfg.TLS.Key)
+	assert.Equal(t, "", cfg.TLS.RootCA)
+
+	cfg.overrideEnv(nil)
+	assert.Equal(t, "tcp://:9002", cfg.Listen)
+}
+
+func Test_Config_Warmup(t *testing.T) {
+	w := &Warmup{}
+	assert.NoError(t, w.Valid())
+	assert.Equal(t, ":warmup", w.name())
+
+	w = &Warmup{Count: 2, Method: "/app.Service/Warmup"}
+	assert.NoError(t, w.Valid())
+	assert.Equal(t, "/app.Service/Warmup", w.name())
+
+	w.Method = "Warmup"
+	assert.Error(t, w.Valid())
+
+	w = &Warmup{Count: -1}
+	assert.Error(t, w.Valid())
+}
+
+func Test_Config_Compression(t *testing.T) {
+	cfg := &Config{
+		Listen: "tcp://:8080",
+		Proto:  "tests/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+		Compression: "gzip",
+	}
+
+	assert.NoError(t, cfg.Valid())
+
+	cfg.Compression = "snappy"
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_GracefulTimeout(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(bytes.NewBufferString(`gracefulTimeout: 30s`)))
+
+	cfg := &Config{}
+	assert.NoError(t, v.Unmarshal(cfg))
+	assert.Equal(t, 30*time.Second, cfg.GracefulTimeout)
+
+	cfg = &Config{
+		Listen: "tcp://:8080",
+		Proto:  "tests/test.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php tests/worker.php",
+			Relay:   "pipes",
+			Pool: &roadrunner.Config{
+				NumWorkers:      1,
+				AllocateTimeout: time.Second,
+				DestroyTimeout:  time.Second,
+			},
+		},
+		GracefulTimeout: -time.Second,
+	}
+	assert.Error(t, cfg.Valid())
+}
+
+func Test_Config_ConnectionTimeout(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(bytes.NewBufferString(`connectionTimeout: 5s`)))
+
+	cfg := &Config{}
+	assert.NoError(t, v.Unmarshal(cfg))
+	assert.Equal(t, 5*time.Second, cfg.ConnectionTimeout)
+
+	cfg = &Config{
+		Listen:            "tcp://:8080",
+		Proto:             "parser/test.proto",
+		Workers:           echoWorkers(1),
+		ConnectionTimeout: -time.Second,
+	}
+	assert.Error(t, cfg.Valid())
+
+	cfg.ConnectionTimeout = 0
+	assert.NoError(t, cfg.Valid())
+}
+
+func Test_Config_NumStreamWorkers(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(bytes.NewBufferString(`numStreamWorkers: 8`)))
+
+	cfg := &Config{}
+	assert.NoError(t, v.Unmarshal(cfg))
+	assert.Equal(t, 8, cfg.NumStreamWorkers)
+
+	cfg = &Config{
+		Listen:           "tcp://:8080",
+		Proto:            "parser/test.proto",
+		Workers:          echoWorkers(1),
+		NumStreamWorkers: -1,
+	}
+	assert.Error(t, cfg.Valid())
+
+	cfg.NumStreamWorkers = 0
+	assert.NoError(t, cfg.Valid())
+
+	cfg.NumStreamWorkers = 8
+	assert.NoError(t, cfg.Valid())
+}
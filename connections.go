@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/stats"
+	"net"
+)
+
+const (
+	// EventConnOpen thrown when client connection is established, event context is *ConnContext.
+	EventConnOpen = iota + 9600
+
+	// EventConnClose thrown when client connection is closed, event context is *ConnContext.
+	EventConnClose
+
+	// EventHandshakeError thrown when TLS handshake with the client failed, event context is *ConnContext.
+	EventHandshakeError
+)
+
+// ConnContext describes client connection.
+type ConnContext struct {
+	// RemoteAddr is client address.
+	RemoteAddr string
+
+	// LocalAddr is server address accepted the connection.
+	LocalAddr string
+
+	// Error contains handshake error, nil for other events.
+	Error error
+}
+
+// connKey is context key of the connection description.
+type connKey struct{}
+
+// newConnContext describes connection of the given addresses.
+func newConnContext(remote, local net.Addr) *ConnContext {
+	c := &ConnContext{}
+	if remote != nil {
+		c.RemoteAddr = remote.String()
+	}
+
+	if local != nil {
+		c.LocalAddr = local.String()
+	}
+
+	return c
+}
+
+// connEvents reports client connections to service listeners.
+type connEvents struct {
+	throw func(event int, ctx interface{})
+}
+
+// TagConn attaches connection description to the connection context.
+func (c *connEvents) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return context.WithValue(ctx, connKey{}, newConnContext(info.RemoteAddr, info.LocalAddr))
+}
+
+// HandleConn reports connection begin and end.
+func (c *connEvents) HandleConn(ctx context.Context, s stats.ConnStats) {
+	conn, ok := ctx.Value(connKey{}).(*ConnContext)
+	if !ok {
+		return
+	}
+
+	switch s.(type) {
+	case *stats.ConnBegin:
+		c.throw(EventConnOpen, conn)
+	case *stats.ConnEnd:
+		c.throw(EventConnClose, conn)
+	}
+}
+
+// TagRPC does nothing, calls are reported by interceptors.
+func (c *connEvents) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+// HandleRPC does nothing, calls are reported by interceptors.
+func (c *connEvents) HandleRPC(ctx context.Context, s stats.RPCStats) {}
+
+// handshakeEvents reports failed TLS handshakes to service listeners, grpc server closes such connections
+// before they are visible to the stats handler.
+type handshakeEvents struct {
+	credentials.TransportCredentials
+	throw func(event int, ctx interface{})
+}
+
+// ServerHandshake performs TLS handshake and reports it's failure.
+func (h *handshakeEvents) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	c, info, err := h.TransportCredentials.ServerHandshake(conn)
+	if err != nil {
+		e := newConnContext(conn.RemoteAddr(), conn.LocalAddr())
+		e.Error = err
+		h.throw(EventHandshakeError, e)
+	}
+
+	return c, info, err
+}
+
+// Clone makes a copy of the credentials reporting handshake failures.
+func (h *handshakeEvents) Clone() credentials.TransportCredentials {
+	return &handshakeEvents{TransportCredentials: h.TransportCredentials.Clone(), throw: h.throw}
+}
@@ -0,0 +1,108 @@
+package grpc
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/stats"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type failingCreds struct {
+	credentials.TransportCredentials
+}
+
+func (c *failingCreds) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("tls: bad certificate")
+}
+
+func (c *failingCreds) Clone() credentials.TransportCredentials {
+	return &failingCreds{}
+}
+
+func Test_ConnEvents(t *testing.T) {
+	events := make([]int, 0)
+	contexts := make([]*ConnContext, 0)
+	c := &connEvents{throw: func(event int, ctx interface{}) {
+		events = append(events, event)
+		contexts = append(contexts, ctx.(*ConnContext))
+	}}
+
+	ctx := c.TagConn(context.Background(), &stats.ConnTagInfo{
+		RemoteAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 50000},
+		LocalAddr:  &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9001},
+	})
+
+	c.HandleConn(ctx, &stats.ConnBegin{})
+	c.HandleConn(ctx, &stats.ConnEnd{})
+
+	// connections without description are ignored
+	c.HandleConn(context.Background(), &stats.ConnBegin{})
+
+	assert.Equal(t, []int{EventConnOpen, EventConnClose}, events)
+	assert.Equal(t, "127.0.0.1:50000", contexts[0].RemoteAddr)
+	assert.Equal(t, "127.0.0.1:9001", contexts[0].LocalAddr)
+	assert.NoError(t, contexts[0].Error)
+}
+
+func Test_ConnEvents_Server(t *testing.T) {
+	var mu sync.Mutex
+	events := make([]int, 0)
+
+	server := grpc.NewServer(grpc.StatsHandler(&connEvents{throw: func(event int, ctx interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}}))
+	defer server.Stop()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(l)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	assert.NoError(t, err)
+	conn.Close()
+
+	// connection end is reported asynchronously
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		n := len(events)
+		mu.Unlock()
+
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{EventConnOpen, EventConnClose}, events)
+}
+
+func Test_HandshakeEvents(t *testing.T) {
+	var e *ConnContext
+	creds := &handshakeEvents{TransportCredentials: &failingCreds{}, throw: func(event int, ctx interface{}) {
+		assert.Equal(t, EventHandshakeError, event)
+		e = ctx.(*ConnContext)
+	}}
+
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	_, _, err := creds.ServerHandshake(server)
+	assert.Error(t, err)
+	assert.NotNil(t, e)
+	assert.EqualError(t, e.Error, "tls: bad certificate")
+
+	clone, ok := creds.Clone().(*handshakeEvents)
+	assert.True(t, ok)
+	assert.NotNil(t, clone.throw)
+}
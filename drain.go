@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"sync/atomic"
+)
+
+// drainError is returned to calls arriving while the server is draining.
+var drainError = status.Error(codes.Unavailable, "server is draining")
+
+// drain flips the server into (or out of) drain mode. Draining server reports NOT_SERVING health status and
+// rejects new calls, calls in progress are allowed to complete. Resumed server reports SERVING once the worker
+// pool is running.
+func (svc *Service) drain(drain bool) {
+	if drain {
+		atomic.StoreInt32(&svc.draining, 1)
+		svc.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		return
+	}
+
+	atomic.StoreInt32(&svc.draining, 0)
+
+	// failed pool remains NOT_SERVING until it's recovered
+	if svc.rr == nil || svc.rr.Pool() == nil {
+		return
+	}
+
+	svc.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+}
+
+// isDraining returns true when server does not accept new calls.
+func (svc *Service) isDraining() bool {
+	return atomic.LoadInt32(&svc.draining) == 1
+}
+
+// unaryDrain rejects unary calls while server is draining.
+func (svc *Service) unaryDrain(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if svc.isDraining() {
+		return nil, drainError
+	}
+
+	return handler(ctx, req)
+}
+
+// streamDrain rejects streaming calls while server is draining.
+func (svc *Service) streamDrain(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if svc.isDraining() {
+		return drainError
+	}
+
+	return handler(srv, ss)
+}
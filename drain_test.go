@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"github.com/spiral/roadrunner"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func Test_Drain_Unary(t *testing.T) {
+	svc := &Service{}
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	out, err := svc.unaryDrain(context.Background(), nil, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", out)
+
+	svc.drain(true)
+	_, err = svc.unaryDrain(context.Background(), nil, info, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	svc.drain(false)
+	_, err = svc.unaryDrain(context.Background(), nil, info, handler)
+	assert.NoError(t, err)
+}
+
+func Test_Drain_Stream(t *testing.T) {
+	svc := &Service{}
+	info := &grpc.StreamServerInfo{FullMethod: "/service.Test/Stream"}
+
+	called := false
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+
+	svc.drain(true)
+	err := svc.streamDrain(nil, &mockStream{ctx: context.Background()}, info, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.False(t, called)
+
+	svc.drain(false)
+	assert.NoError(t, svc.streamDrain(nil, &mockStream{ctx: context.Background()}, info, handler))
+	assert.True(t, called)
+}
+
+func Test_Drain_Health(t *testing.T) {
+	rr := roadrunner.NewServer(echoWorkers(1))
+	assert.NoError(t, rr.Start())
+	defer rr.Stop()
+
+	svc := &Service{rr: rr, health: health.NewServer(), proxies: []*Proxy{NewProxy("service.Test", "", nil)}}
+	svc.throw(roadrunner.EventPoolConstruct, nil)
+
+	svc.drain(true)
+	out, err := svc.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "service.Test"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, out.Status)
+
+	// pool reset must not bring drained server back
+	svc.throw(roadrunner.EventPoolConstruct, nil)
+	out, err = svc.health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, out.Status)
+
+	svc.drain(false)
+	out, err = svc.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "service.Test"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, out.Status)
+
+	// server without pool must not be reported as serving
+	rr.Stop()
+	svc.throw(roadrunner.EventServerStop, nil)
+
+	svc.drain(true)
+	svc.drain(false)
+	out, err = svc.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "service.Test"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, out.Status)
+}
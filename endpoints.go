@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"net"
+)
+
+// endpoint serves the same services as the main addresses using it's own TLS settings.
+type endpoint struct {
+	cfg Endpoint
+
+	// credentials of the endpoint, nil for plaintext endpoints
+	creds credentials.TransportCredentials
+	certs *certHolder
+}
+
+// newEndpoint creates endpoint of the configuration, certificate of TLS endpoints can be reloaded.
+func newEndpoint(cfg Endpoint) (*endpoint, error) {
+	e := &endpoint{cfg: cfg}
+	if !cfg.TLS.Enabled() {
+		return e, nil
+	}
+
+	tlsCfg, err := cfg.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	e.certs = holdCertificate(tlsCfg, cfg.TLS.Cert, cfg.TLS.Key)
+	e.creds = credentials.NewTLS(tlsCfg)
+
+	return e, nil
+}
+
+// endpointListener marks accepted connections with the endpoint they were accepted by.
+type endpointListener struct {
+	net.Listener
+	e *endpoint
+}
+
+// Accept waits for the next connection of the endpoint.
+func (l *endpointListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &endpointConn{Conn: conn, e: l.e}, nil
+}
+
+// endpointConn is connection accepted by the endpoint.
+type endpointConn struct {
+	net.Conn
+	e *endpoint
+}
+
+// endpointCreds secures connections of endpoints by credentials of the endpoint, connections of the main
+// addresses are secured by server credentials (plaintext when nil).
+type endpointCreds struct {
+	server credentials.TransportCredentials
+}
+
+// ClientHandshake is not supported, credentials are used by the server only.
+func (c *endpointCreds) ClientHandshake(
+	ctx context.Context,
+	authority string,
+	conn net.Conn,
+) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("endpoint credentials can not be used by clients")
+}
+
+// ServerHandshake performs handshake using credentials of the endpoint which accepted the connection.
+func (c *endpointCreds) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	creds := c.server
+	if ec, ok := conn.(*endpointConn); ok {
+		creds = ec.e.creds
+	}
+
+	if creds == nil {
+		return conn, nil, nil
+	}
+
+	return creds.ServerHandshake(conn)
+}
+
+// Info returns protocol info of server credentials.
+func (c *endpointCreds) Info() credentials.ProtocolInfo {
+	if c.server == nil {
+		return credentials.ProtocolInfo{}
+	}
+
+	return c.server.Info()
+}
+
+// Clone makes a copy of the credentials.
+func (c *endpointCreds) Clone() credentials.TransportCredentials {
+	if c.server == nil {
+		return &endpointCreds{}
+	}
+
+	return &endpointCreds{server: c.server.Clone()}
+}
+
+// OverrideServerName is not supported, credentials are used by the server only.
+func (c *endpointCreds) OverrideServerName(string) error {
+	return nil
+}
+
+// endpointListeners creates listeners of configured endpoints, created listeners are closed on error.
+func (svc *Service) endpointListeners() ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(svc.endpoints))
+	for _, e := range svc.endpoints {
+		ln, err := svc.cfg.listener(e.cfg.Address)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+
+			return nil, err
+		}
+
+		listeners = append(listeners, &endpointListener{Listener: ln, e: e})
+	}
+
+	return listeners, nil
+}
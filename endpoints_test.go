@@ -0,0 +1,187 @@
+package grpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	ngrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// selfSigned writes self signed certificate pair into the directory.
+func selfSigned(t *testing.T, dir string) (cert, key string) {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &pk.PublicKey, pk)
+	assert.NoError(t, err)
+
+	kd, err := x509.MarshalECPrivateKey(pk)
+	assert.NoError(t, err)
+
+	cert, key = filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	assert.NoError(t, ioutil.WriteFile(cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, ioutil.WriteFile(key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kd}), 0600))
+
+	return cert, key
+}
+
+func Test_Config_Endpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "endpoints")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cert, key := selfSigned(t, dir)
+
+	assert.NoError(t, (&Endpoint{Address: "tcp://:9002"}).Valid())
+	assert.NoError(t, (&Endpoint{Address: "tcp://:9002", TLS: TLS{Cert: cert, Key: key}}).Valid())
+
+	assert.Error(t, (&Endpoint{Address: "localhost"}).Valid())
+	assert.Error(t, (&Endpoint{Address: "tcp://:9002", TLS: TLS{Cert: cert}}).Valid())
+	assert.Error(t, (&Endpoint{Address: "tcp://:9002", TLS: TLS{Cert: cert, Key: filepath.Join(dir, "missing.key")}}).Valid())
+
+	cfg := &Config{
+		Listen:    "tcp://:9001",
+		Proto:     "parser/test.proto",
+		Workers:   echoWorkers(1),
+		Endpoints: []Endpoint{{Address: "tcp://:9002", TLS: TLS{Key: key}}},
+	}
+	err = cfg.Valid()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "endpoint tcp://:9002: tls cert is required")
+}
+
+func Test_Service_Endpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "endpoints")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cert, key := selfSigned(t, dir)
+
+	svc := &Service{}
+	ok, err := svc.Init(&Config{
+		Listen:    "tcp://127.0.0.1:9094",
+		Proto:     "parser/test.proto",
+		Workers:   echoWorkers(1),
+		Endpoints: []Endpoint{{Address: "tcp://127.0.0.1:9093", TLS: TLS{Cert: cert, Key: key}}},
+	}, nil, nil)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		assert.NoError(t, svc.Serve())
+	}()
+
+	ping := func(address string, opt ngrpc.DialOption) error {
+		conn, err := ngrpc.Dial(address, opt, ngrpc.WithBlock(), ngrpc.WithTimeout(2*time.Second))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		out := &healthpb.HealthCheckRequest{}
+		return conn.Invoke(context.Background(), "/app.namespace.PingService/Ping", &healthpb.HealthCheckRequest{Service: "ping"}, out)
+	}
+
+	secure := ngrpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true}))
+
+	// main address is plaintext, endpoint is secured by it's own certificate
+	assert.NoError(t, ping("127.0.0.1:9094", ngrpc.WithInsecure()))
+	assert.NoError(t, ping("127.0.0.1:9093", secure))
+
+	assert.Error(t, ping("127.0.0.1:9094", secure))
+	assert.Error(t, ping("127.0.0.1:9093", ngrpc.WithInsecure()))
+
+	// certificate of the endpoint can be reloaded
+	assert.NoError(t, svc.reloadCertificate())
+
+	st, err := svc.status()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tcp://127.0.0.1:9094", "tcp://127.0.0.1:9093"}, st.Listen)
+
+	// every listener is closed once the service is stopped
+	svc.Stop()
+	<-served
+
+	for _, address := range []string{"127.0.0.1:9094", "127.0.0.1:9093"} {
+		ln, err := net.Listen("tcp", address)
+		if assert.NoError(t, err) {
+			ln.Close()
+		}
+	}
+}
+
+func Test_Service_Endpoints_Error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "endpoints")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cert, key := selfSigned(t, dir)
+
+	svc := &Service{}
+	ok, err := svc.Init(&Config{
+		Listen:    "tcp://127.0.0.1:9094",
+		Proto:     "parser/test.proto",
+		Workers:   echoWorkers(1),
+		Endpoints: []Endpoint{{Address: "tcp://127.0.0.1:9093", TLS: TLS{Cert: cert, Key: key}}},
+	}, nil, nil)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	stop := func() {
+		stopped := make(chan struct{})
+		go func() {
+			svc.Stop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("service must be stopped after failed serve")
+		}
+	}
+
+	// certificate of the endpoint is gone
+	assert.NoError(t, os.Remove(cert))
+	assert.Error(t, svc.Serve())
+	stop()
+
+	// address of the endpoint is taken, main listener must be released
+	selfSigned(t, dir)
+	ln, err := net.Listen("tcp", "127.0.0.1:9093")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	assert.Error(t, svc.Serve())
+	stop()
+
+	main, err := net.Listen("tcp", "127.0.0.1:9094")
+	if assert.NoError(t, err) {
+		main.Close()
+	}
+}
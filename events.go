@@ -0,0 +1,137 @@
+package grpc
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+const (
+	// EventUnaryCall thrown when unary call completed successfully, event context is *CallContext.
+	EventUnaryCall = iota + 9300
+
+	// EventUnaryError thrown when unary call failed, event context is *CallContext.
+	EventUnaryError
+
+	// EventStreamCall thrown when streaming call completed successfully, event context is *CallContext.
+	EventStreamCall
+
+	// EventStreamError thrown when streaming call failed, event context is *CallContext.
+	EventStreamError
+
+	// EventUnaryCancel thrown when unary call was cancelled by the client, event context is *CallContext.
+	EventUnaryCancel
+
+	// EventStreamCancel thrown when streaming call was cancelled by the client, event context is *CallContext.
+	EventStreamCancel
+
+	// EventForceStop thrown when server ("server") or worker pool ("pool") failed to stop within GracefulTimeout
+	// and was stopped forcibly, event context is the name of the component.
+	EventForceStop
+)
+
+// CallContext describes complete RPC call.
+type CallContext struct {
+	// Method is full name of RPC method.
+	Method string
+
+	// Peer is client address, empty if unknown.
+	Peer string
+
+	// Elapsed is duration of the call.
+	Elapsed time.Duration
+
+	// Code is call status code.
+	Code codes.Code
+
+	// ResponseSize is total size of sent messages in bytes.
+	ResponseSize int
+
+	// Error returned by the call, nil for successful calls.
+	Error error
+}
+
+// Event describes service event.
+type Event struct {
+	// Type of the event (EventUnaryCall, EventCertReload, roadrunner.EventWorkerError and etc).
+	Type int
+
+	// Call is set for call events (EventUnaryCall, EventUnaryError, EventUnaryCancel and stream alternatives).
+	Call *CallContext
+
+	// Worker is set for worker lifecycle events (EventWorkerStart and EventWorkerStop).
+	Worker *WorkerContext
+
+	// Context is original event context.
+	Context interface{}
+}
+
+// newCallContext creates context of the complete call.
+func newCallContext(ctx context.Context, method string, start time.Time, size int, err error) *CallContext {
+	c := &CallContext{
+		Method:       method,
+		Elapsed:      time.Since(start),
+		Code:         status.Code(err),
+		ResponseSize: size,
+		Error:        err,
+	}
+
+	if pr, ok := peer.FromContext(ctx); ok && pr.Addr != nil {
+		c.Peer = pr.Addr.String()
+	}
+
+	return c
+}
+
+// callEvent returns event type of the complete call, calls abandoned by the client are reported
+// as cancelled regardless of the returned error.
+func callEvent(ctx context.Context, err error, call, fail, cancel int) int {
+	switch {
+	case ctx.Err() == context.Canceled:
+		return cancel
+	case err != nil:
+		return fail
+	}
+
+	return call
+}
+
+// unaryEvents reports unary calls to service listeners.
+func (svc *Service) unaryEvents(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	svc.throw(
+		callEvent(ctx, err, EventUnaryCall, EventUnaryError, EventUnaryCancel),
+		newCallContext(ctx, info.FullMethod, start, messageSize(resp), err),
+	)
+
+	return resp, err
+}
+
+// streamEvents reports streaming calls to service listeners.
+func (svc *Service) streamEvents(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+	s := &accessStream{ServerStream: ss}
+	err := handler(srv, s)
+
+	svc.throw(
+		callEvent(ss.Context(), err, EventStreamCall, EventStreamError, EventStreamCancel),
+		newCallContext(ss.Context(), info.FullMethod, start, s.sent, err),
+	)
+
+	return err
+}
@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+)
+
+func Test_Events_Unary(t *testing.T) {
+	events := make([]Event, 0)
+	svc := &Service{}
+	svc.AddEventListener(func(e Event) { events = append(events, e) })
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9000}})
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+
+	_, err := svc.unaryEvents(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return rawMessage("ok"), nil
+	})
+	assert.NoError(t, err)
+
+	_, err = svc.unaryEvents(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.New("failure")
+	})
+	assert.Error(t, err)
+
+	assert.Len(t, events, 2)
+
+	assert.Equal(t, EventUnaryCall, events[0].Type)
+	assert.Equal(t, "/service.Test/Echo", events[0].Call.Method)
+	assert.Equal(t, "127.0.0.1:9000", events[0].Call.Peer)
+	assert.NoError(t, events[0].Call.Error)
+	assert.Equal(t, codes.OK, events[0].Call.Code)
+	assert.Equal(t, 2, events[0].Call.ResponseSize)
+	assert.Equal(t, events[0].Call, events[0].Context)
+
+	assert.Equal(t, EventUnaryError, events[1].Type)
+	assert.Equal(t, "", events[1].Call.Peer)
+	assert.EqualError(t, events[1].Call.Error, "failure")
+	assert.Equal(t, codes.Unknown, events[1].Call.Code)
+}
+
+func Test_Events_Stream(t *testing.T) {
+	events := make([]Event, 0)
+	svc := &Service{}
+	svc.AddEventListener(func(e Event) { events = append(events, e) })
+
+	info := &grpc.StreamServerInfo{FullMethod: "/service.Test/Stream"}
+	s := &mockStream{ctx: context.Background()}
+
+	assert.NoError(t, svc.streamEvents(nil, s, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return sendFrames(stream, packFrames([]byte("a"), []byte("bc")))
+	}))
+
+	assert.Error(t, svc.streamEvents(nil, s, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return errors.New("failure")
+	}))
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, EventStreamCall, events[0].Type)
+	assert.Equal(t, 3, events[0].Call.ResponseSize)
+	assert.Equal(t, EventStreamError, events[1].Type)
+	assert.Equal(t, "/service.Test/Stream", events[1].Call.Method)
+}
+
+func Test_Events_Other(t *testing.T) {
+	var event Event
+	svc := &Service{}
+	svc.AddEventListener(func(e Event) { event = e })
+
+	svc.throw(EventCertReload, "server.crt")
+	assert.Equal(t, EventCertReload, event.Type)
+	assert.Nil(t, event.Call)
+	assert.Equal(t, "server.crt", event.Context)
+}
+
+func Test_Events_Cancel(t *testing.T) {
+	events := make([]Event, 0)
+	svc := &Service{}
+	svc.AddEventListener(func(e Event) { events = append(events, e) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+
+	_, err := svc.unaryEvents(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		cancel()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	})
+	assert.Error(t, err)
+
+	s := &mockStream{ctx: ctx}
+	assert.Error(t, svc.streamEvents(nil, s, &grpc.StreamServerInfo{FullMethod: "/service.Test/Stream"}, func(srv interface{}, stream grpc.ServerStream) error {
+		return errors.New("failure")
+	}))
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, EventUnaryCancel, events[0].Type)
+	assert.Equal(t, codes.Canceled, events[0].Call.Code)
+	assert.Equal(t, EventStreamCancel, events[1].Type)
+}
@@ -1,18 +1,21 @@
 module github.com/spiral/php-grpc
 
+go 1.14
+
 require (
 	github.com/buger/goterm v0.0.0-20181115115552-c206103e1f37
 	github.com/c9s/inflect v0.0.0-20130402162822-006c50878f3f
 	github.com/emicklei/proto v1.6.10
-	github.com/golang/protobuf v1.3.1
-	github.com/inconshreveable/mousetrap v1.0.0 // indirect
-	github.com/shirou/w32 v0.0.0-20160930032740-bb4de0191aa4 // indirect
+	github.com/golang/protobuf v1.3.3
+	github.com/prometheus/client_golang v1.0.0
 	github.com/sirupsen/logrus v1.3.0
 	github.com/spf13/cobra v0.0.3
+	github.com/spf13/viper v1.3.1
+	github.com/spiral/goridge v2.1.3+incompatible
 	github.com/spiral/roadrunner v1.4.2
-	github.com/stretchr/testify v1.2.2
-	golang.org/x/net v0.0.0-20181017193950-04a2e542c03f
-	google.golang.org/genproto v0.0.0-20181016170114-94acd270e44e
-	google.golang.org/grpc v1.18.0
-	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
+	github.com/stretchr/testify v1.3.0
+	golang.org/x/net v0.0.0-20190311183353-d8887717615a
+	golang.org/x/sys v0.0.0-20190222072716-a9d3bda3a223
+	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55
+	google.golang.org/grpc v1.30.0
 )
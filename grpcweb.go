@@ -0,0 +1,306 @@
+package grpc
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/net/context"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// grpcWebContentType prefixes content type of gRPC-Web requests (application/grpc-web+proto).
+	grpcWebContentType = "application/grpc-web"
+
+	// grpcWebTextContentType prefixes content type of base64 encoded gRPC-Web requests.
+	grpcWebTextContentType = "application/grpc-web-text"
+
+	// grpcWebTrailer flags message frame carrying trailers.
+	grpcWebTrailer = 0x80
+)
+
+// grpcWeb translates gRPC-Web (HTTP/1.1) calls into native gRPC calls served by current grpc server.
+type grpcWeb struct {
+	cfg    GRPCWeb
+	server func() *grpc.Server
+	mu     sync.Mutex
+	calls  map[*grpc.Server]*sync.WaitGroup
+}
+
+// acquire returns current server and registers the call, release function must be called once the call is
+// complete.
+func (w *grpcWeb) acquire() (*grpc.Server, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	server := w.server()
+	if w.calls == nil {
+		w.calls = make(map[*grpc.Server]*sync.WaitGroup)
+	}
+
+	wg, ok := w.calls[server]
+	if !ok {
+		wg = &sync.WaitGroup{}
+		w.calls[server] = wg
+	}
+
+	wg.Add(1)
+	return server, wg.Done
+}
+
+// wait waits for gRPC-Web calls of the replaced server to complete, returns false when timeout (if any)
+// is reached.
+func (w *grpcWeb) wait(server *grpc.Server, timeout time.Duration) bool {
+	w.mu.Lock()
+	wg, ok := w.calls[server]
+	delete(w.calls, server)
+	w.mu.Unlock()
+
+	if !ok {
+		return true
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	if timeout == 0 {
+		<-done
+		return true
+	}
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// listen serves gRPC-Web calls on configured address, tlsCfg enables TLS when not nil.
+func (w *grpcWeb) listen(tlsCfg *tls.Config) (*http.Server, error) {
+	l, err := net.Listen("tcp", w.cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsCfg != nil {
+		tlsCfg = tlsCfg.Clone()
+		tlsCfg.NextProtos = []string{"http/1.1"}
+		l = tls.NewListener(l, tlsCfg)
+	}
+
+	server := &http.Server{Handler: w}
+	go server.Serve(l)
+
+	return server, nil
+}
+
+// ServeHTTP handles gRPC-Web calls and CORS preflight requests.
+func (w *grpcWeb) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin != "" {
+		if !w.allowOrigin(origin) {
+			http.Error(rw, "origin is not allowed", http.StatusForbidden)
+			return
+		}
+
+		rw.Header().Set("Access-Control-Allow-Origin", origin)
+		rw.Header().Add("Vary", "Origin")
+	}
+
+	if r.Method == http.MethodOptions && origin != "" {
+		rw.Header().Set("Access-Control-Allow-Methods", "POST")
+		rw.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+		rw.Header().Set("Access-Control-Max-Age", "600")
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if r.Method != http.MethodPost || !strings.HasPrefix(contentType, grpcWebContentType) {
+		http.Error(rw, "gRPC-Web request expected", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	text := strings.HasPrefix(contentType, grpcWebTextContentType)
+
+	// grpc server handles HTTP/2 requests only, the call itself is protocol agnostic
+	req := r.WithContext(context.WithValue(r.Context(), webKey{}, true))
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+	req.Header = r.Header.Clone()
+	req.Header.Set("Content-Type", "application/grpc"+strings.TrimPrefix(
+		strings.TrimPrefix(contentType, grpcWebTextContentType),
+		grpcWebContentType,
+	))
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+
+	if text {
+		req.Body = &readCloser{Reader: base64.NewDecoder(base64.StdEncoding, r.Body), Closer: r.Body}
+	}
+
+	server, release := w.acquire()
+	defer release()
+
+	ww := &grpcWebWriter{ResponseWriter: rw, contentType: contentType, text: text}
+	server.ServeHTTP(ww, req)
+	ww.finish()
+}
+
+type webKey struct{}
+
+// isWebCall returns true for calls made using gRPC-Web.
+func isWebCall(ctx context.Context) bool {
+	return ctx.Value(webKey{}) != nil
+}
+
+// setHeader sets response header of the call. Headers of gRPC-Web calls are sent immediately, grpc server
+// does not deliver headers set for calls served by http handler otherwise.
+func setHeader(ctx context.Context, md metadata.MD) error {
+	if isWebCall(ctx) {
+		return grpc.SendHeader(ctx, md)
+	}
+
+	return grpc.SetHeader(ctx, md)
+}
+
+// setStreamHeader sets response header of the streaming call, see setHeader.
+func setStreamHeader(stream grpc.ServerStream, md metadata.MD) error {
+	if isWebCall(stream.Context()) {
+		return stream.SendHeader(md)
+	}
+
+	return stream.SetHeader(md)
+}
+
+// allowOrigin checks that browser origin is allowed to call the services.
+func (w *grpcWeb) allowOrigin(origin string) bool {
+	for _, o := range w.cfg.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readCloser combines decoded reader with original body closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// grpcWebWriter converts native gRPC response into gRPC-Web response, trailers are sent as the last message
+// frame of the body.
+type grpcWebWriter struct {
+	http.ResponseWriter
+	contentType string
+	text        bool
+	wroteHeader bool
+	sent        http.Header
+}
+
+// WriteHeader sends response headers, trailers declared by grpc are excluded.
+func (w *grpcWebWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.ResponseWriter.Header()
+
+	// trailers are delivered in the body
+	h.Del("Trailer")
+	h.Set("Content-Type", w.contentType)
+
+	exposed := make([]string, 0, len(h))
+	for k := range h {
+		exposed = append(exposed, k)
+	}
+	sort.Strings(exposed)
+
+	h.Set("Access-Control-Expose-Headers", strings.Join(append(exposed, "Grpc-Status", "Grpc-Message"), ", "))
+
+	w.sent = h.Clone()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes message frames of the response.
+func (w *grpcWebWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if !w.text {
+		return w.ResponseWriter.Write(data)
+	}
+
+	if _, err := w.ResponseWriter.Write([]byte(base64.StdEncoding.EncodeToString(data))); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+// Flush flushes written data to the client.
+func (w *grpcWebWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify notifies when client connection is gone.
+func (w *grpcWebWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+
+	return make(chan bool)
+}
+
+// finish sends trailers set by grpc server as the trailer frame.
+func (w *grpcWebWriter) finish() {
+	w.WriteHeader(http.StatusOK)
+
+	h := w.ResponseWriter.Header()
+	trailers := make(http.Header)
+
+	for k, v := range h {
+		if strings.HasPrefix(k, http2.TrailerPrefix) {
+			trailers[strings.TrimPrefix(k, http2.TrailerPrefix)] = v
+			delete(h, k)
+			continue
+		}
+
+		if _, ok := w.sent[k]; !ok {
+			trailers[k] = v
+			delete(h, k)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	for k, v := range trailers {
+		for _, value := range v {
+			fmt.Fprintf(buf, "%s: %s\r\n", strings.ToLower(k), value)
+		}
+	}
+
+	frame := make([]byte, 5, 5+buf.Len())
+	frame[0] = grpcWebTrailer
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+
+	w.Write(append(frame, buf.Bytes()...))
+	w.Flush()
+}
@@ -0,0 +1,196 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func webServer(t *testing.T, cfg GRPCWeb) *httptest.Server {
+	server := grpc.NewServer(grpc.CustomCodec(&codec{encoding.GetCodec("proto")}))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "app.Echo",
+		HandlerType: (*proxyService)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := rawMessage{}
+				if err := dec(&in); err != nil {
+					return nil, err
+				}
+
+				if string(in) == "fail" {
+					return nil, status.Error(codes.NotFound, "not found")
+				}
+
+				setHeader(ctx, metadata.Pairs("x-header", "value"))
+				return in, nil
+			},
+		}},
+	}, &Proxy{})
+
+	return httptest.NewServer(&grpcWeb{cfg: cfg, server: func() *grpc.Server { return server }})
+}
+
+// webFrames splits gRPC-Web response body into data and trailer frames.
+func webFrames(t *testing.T, body []byte) (data []string, trailer string) {
+	for len(body) >= 5 {
+		size := binary.BigEndian.Uint32(body[1:5])
+		frame := string(body[5 : 5+size])
+
+		if body[0]&grpcWebTrailer != 0 {
+			trailer = frame
+		} else {
+			data = append(data, frame)
+		}
+
+		body = body[5+size:]
+	}
+
+	assert.Len(t, body, 0)
+	return data, trailer
+}
+
+func webFrame(data string) []byte {
+	frame := make([]byte, 5)
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+
+	return append(frame, data...)
+}
+
+func Test_GRPCWeb_Unary(t *testing.T) {
+	s := webServer(t, GRPCWeb{})
+	defer s.Close()
+
+	r, err := http.Post(s.URL+"/app.Echo/Echo", "application/grpc-web+proto", bytes.NewReader(webFrame("hello")))
+	assert.NoError(t, err)
+	defer r.Body.Close()
+
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, "application/grpc-web+proto", r.Header.Get("Content-Type"))
+	assert.Equal(t, "value", r.Header.Get("X-Header"))
+	assert.Empty(t, r.Header.Get("Grpc-Status"))
+
+	body, err := ioutil.ReadAll(r.Body)
+	assert.NoError(t, err)
+
+	data, trailer := webFrames(t, body)
+	assert.Equal(t, []string{"hello"}, data)
+	assert.Contains(t, trailer, "grpc-status: 0\r\n")
+}
+
+func Test_GRPCWeb_Error(t *testing.T) {
+	s := webServer(t, GRPCWeb{})
+	defer s.Close()
+
+	r, err := http.Post(s.URL+"/app.Echo/Echo", "application/grpc-web", bytes.NewReader(webFrame("fail")))
+	assert.NoError(t, err)
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	assert.NoError(t, err)
+
+	data, trailer := webFrames(t, body)
+	assert.Len(t, data, 0)
+	assert.Contains(t, trailer, "grpc-status: 5\r\n")
+	assert.Contains(t, trailer, "grpc-message: not found\r\n")
+}
+
+func Test_GRPCWeb_Text(t *testing.T) {
+	s := webServer(t, GRPCWeb{})
+	defer s.Close()
+
+	in := base64.StdEncoding.EncodeToString(webFrame("hello"))
+	r, err := http.Post(s.URL+"/app.Echo/Echo", "application/grpc-web-text", strings.NewReader(in))
+	assert.NoError(t, err)
+	defer r.Body.Close()
+
+	assert.Equal(t, "application/grpc-web-text", r.Header.Get("Content-Type"))
+
+	body, err := ioutil.ReadAll(r.Body)
+	assert.NoError(t, err)
+
+	// every write is encoded separately
+	decoded := make([]byte, 0)
+	for len(body) != 0 {
+		end := bytes.IndexByte(body, '=')
+		for end != -1 && end+1 < len(body) && body[end+1] == '=' {
+			end++
+		}
+
+		chunk := body
+		if end != -1 {
+			chunk, body = body[:end+1], body[end+1:]
+		} else {
+			body = nil
+		}
+
+		part, err := base64.StdEncoding.DecodeString(string(chunk))
+		assert.NoError(t, err)
+		decoded = append(decoded, part...)
+	}
+
+	data, trailer := webFrames(t, decoded)
+	assert.Equal(t, []string{"hello"}, data)
+	assert.Contains(t, trailer, "grpc-status: 0\r\n")
+}
+
+func Test_GRPCWeb_CORS(t *testing.T) {
+	s := webServer(t, GRPCWeb{AllowedOrigins: []string{"https://app.example.com"}})
+	defer s.Close()
+
+	req, _ := http.NewRequest(http.MethodOptions, s.URL+"/app.Echo/Echo", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+
+	r, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	r.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, r.StatusCode)
+	assert.Equal(t, "https://app.example.com", r.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "content-type,x-grpc-web", r.Header.Get("Access-Control-Allow-Headers"))
+
+	req, _ = http.NewRequest(http.MethodPost, s.URL+"/app.Echo/Echo", bytes.NewReader(webFrame("hello")))
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+
+	r, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	r.Body.Close()
+
+	assert.Equal(t, "https://app.example.com", r.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, r.Header.Get("Access-Control-Expose-Headers"), "X-Header")
+
+	req, _ = http.NewRequest(http.MethodOptions, s.URL+"/app.Echo/Echo", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+
+	r, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	r.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, r.StatusCode)
+}
+
+func Test_GRPCWeb_InvalidRequest(t *testing.T) {
+	s := webServer(t, GRPCWeb{})
+	defer s.Close()
+
+	r, err := http.Post(s.URL+"/app.Echo/Echo", "application/json", strings.NewReader("{}"))
+	assert.NoError(t, err)
+	r.Body.Close()
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, r.StatusCode)
+}
@@ -0,0 +1,319 @@
+package grpc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksTimeout limits time spent fetching the key set.
+	jwksTimeout = 10 * time.Second
+
+	// jwksRetry defines how often the key set is refetched to locate unknown keys.
+	jwksRetry = time.Minute
+)
+
+// claims describes verified JWT claims.
+type claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	Expires   int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	Scope     string   `json:"scope"`
+}
+
+// scopes returns space delimited scopes as list.
+func (c *claims) scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// audience claim is either single string or list of strings.
+type audience []string
+
+// UnmarshalJSON accepts both forms of the claim.
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	*a = list
+	return nil
+}
+
+// contains returns true if audience includes the value.
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// jwtVerifier verifies JWT tokens signed by keys of the key set (RS256, ES256).
+type jwtVerifier struct {
+	keys     *keySet
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// verify validates token signature and claims.
+func (v *jwtVerifier) verify(token string) (*claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+
+	key, err := v.keys.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	c := &claims{}
+	if err := decodeSegment(parts[1], c); err != nil {
+		return nil, err
+	}
+
+	now := v.now().Unix()
+	if c.Expires != 0 && now >= c.Expires {
+		return nil, errors.New("token is expired")
+	}
+
+	if c.NotBefore != 0 && now < c.NotBefore {
+		return nil, errors.New("token is not valid yet")
+	}
+
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return nil, errors.New("invalid token issuer")
+	}
+
+	if v.audience != "" && !c.Audience.contains(v.audience) {
+		return nil, errors.New("invalid token audience")
+	}
+
+	return c, nil
+}
+
+// decodeSegment decodes base64url encoded JSON segment of the token.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.New("malformed token")
+	}
+
+	return nil
+}
+
+// verifySignature checks signature of the signed content using algorithm declared by the token.
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	hash := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "RS256":
+		if k, ok := key.(*rsa.PublicKey); ok && rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig) == nil {
+			return nil
+		}
+	case "ES256":
+		if k, ok := key.(*ecdsa.PublicKey); ok && len(sig) == 64 {
+			r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+			if ecdsa.Verify(k, hash[:], r, s) {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported token algorithm '%s'", alg)
+	}
+
+	return errors.New("invalid token signature")
+}
+
+// keySet fetches and caches keys of JSON Web Key Set, the set is refetched once refresh interval passes or
+// when token refers to unknown key (at most once per jwksRetry).
+type keySet struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// newKeySet creates key set fetched from the given url.
+func newKeySet(url string, refresh time.Duration) *keySet {
+	return &keySet{url: url, refresh: refresh, client: &http.Client{Timeout: jwksTimeout}}
+}
+
+// key returns public key with the given id, id can be omitted when set has single key.
+func (s *keySet) key(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, found := s.lookup(kid)
+	stale := time.Since(s.fetched) >= s.refresh
+	if (found && stale) || (!found && time.Since(s.fetched) >= jwksRetry) {
+		if err := s.fetch(); err != nil && !found {
+			return nil, err
+		}
+
+		// stale keys remain in use if the set can not be fetched
+		if nk, ok := s.lookup(kid); ok {
+			k, found = nk, true
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("unknown token key '%s'", kid)
+	}
+
+	return k, nil
+}
+
+// lookup locates the key in cached set.
+func (s *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+// fetch loads the key set, keys of unsupported types are ignored.
+func (s *keySet) fetch() error {
+	s.fetched = time.Now()
+
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return fmt.Errorf("unable to fetch jwks: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to fetch jwks: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("invalid jwks: %s", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if k, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = k
+		}
+	}
+
+	s.keys = keys
+	return nil
+}
+
+// jsonWebKey describes public key of the key set.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes RSA or P-256 EC public key.
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	if k.Use != "" && k.Use != "sig" {
+		return nil, fmt.Errorf("key use '%s' is not supported", k.Use)
+	}
+
+	switch {
+	case k.Kty == "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid rsa exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("invalid ec key")
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("key type '%s' is not supported", k.Kty)
+}
+
+// decodeInt decodes base64url encoded big-endian integer.
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,215 @@
+package grpc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testKeys serves JSON Web Key Set with single RSA and EC keys.
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ec      *ecdsa.PrivateKey
+	fetches int32
+	server  *httptest.Server
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	k := &testKeys{}
+
+	var err error
+	k.rsa, err = rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	k.ec, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	enc := base64.RawURLEncoding
+	set := map[string]interface{}{"keys": []map[string]string{
+		{
+			"kty": "RSA", "kid": "rsa", "use": "sig",
+			"n": enc.EncodeToString(k.rsa.N.Bytes()),
+			"e": enc.EncodeToString(big.NewInt(int64(k.rsa.E)).Bytes()),
+		},
+		{
+			"kty": "EC", "kid": "ec", "crv": "P-256",
+			"x": enc.EncodeToString(k.ec.X.Bytes()),
+			"y": enc.EncodeToString(k.ec.Y.Bytes()),
+		},
+		{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+	}}
+
+	k.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&k.fetches, 1)
+		json.NewEncoder(w).Encode(set)
+	}))
+
+	return k
+}
+
+// sign creates token signed by the key with the given id.
+func (k *testKeys) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	enc := base64.RawURLEncoding
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(body)
+	hash := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch alg {
+	case "RS256":
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, hash[:])
+		assert.NoError(t, err)
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, k.ec, hash[:])
+		assert.NoError(t, err)
+
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	default:
+		sig = []byte("signature")
+	}
+
+	return signed + "." + enc.EncodeToString(sig)
+}
+
+func Test_Auth_Valid(t *testing.T) {
+	assert.True(t, (&Auth{JWKS: "https://auth.example.com/jwks.json"}).Enabled())
+	assert.NoError(t, (&Auth{JWKS: "https://auth.example.com/jwks.json"}).Valid())
+	assert.Error(t, (&Auth{JWKS: "auth.example.com/jwks.json"}).Valid())
+	assert.Error(t, (&Auth{JWKS: "https://auth.example.com", JWKSRefresh: -1}).Valid())
+	assert.Equal(t, time.Hour, (&Auth{}).jwksRefresh())
+}
+
+func Test_Auth_JWT(t *testing.T) {
+	keys := newTestKeys(t)
+	defer keys.server.Close()
+
+	a, err := newAuthenticator(Auth{
+		Tokens:   []string{"static"},
+		JWKS:     keys.server.URL,
+		Issuer:   "https://auth.example.com",
+		Audience: "api",
+		Exclude:  []string{"/grpc.health.v1.Health/Check"},
+	})
+	assert.NoError(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := map[string]interface{}{
+		"sub":   "user-1",
+		"iss":   "https://auth.example.com",
+		"aud":   []string{"api", "web"},
+		"exp":   exp,
+		"scope": "read write",
+	}
+
+	for _, alg := range []string{"RS256", "ES256"} {
+		kid := map[string]string{"RS256": "rsa", "ES256": "ec"}[alg]
+
+		ctx, err := a.authenticate(authContext("authorization", "Bearer "+keys.sign(t, alg, kid, valid)), "/app.Test/Echo")
+		assert.NoError(t, err)
+
+		c := claimsFromContext(ctx)
+		if assert.NotNil(t, c) {
+			assert.Equal(t, "user-1", c.Subject)
+			assert.Equal(t, []string{"read", "write"}, c.scopes())
+		}
+	}
+
+	// static tokens are accepted as well
+	ctx, err := a.authenticate(authContext("authorization", "Bearer static"), "/app.Test/Echo")
+	assert.NoError(t, err)
+	assert.Nil(t, claimsFromContext(ctx))
+
+	_, err = a.authenticate(context.Background(), "/grpc.health.v1.Health/Check")
+	assert.NoError(t, err)
+
+	invalid := map[string]map[string]interface{}{
+		"expired":     {"sub": "user-1", "iss": "https://auth.example.com", "aud": "api", "exp": time.Now().Add(-time.Minute).Unix()},
+		"not before":  {"sub": "user-1", "iss": "https://auth.example.com", "aud": "api", "nbf": time.Now().Add(time.Minute).Unix()},
+		"issuer":      {"sub": "user-1", "iss": "https://other.example.com", "aud": "api", "exp": exp},
+		"audience":    {"sub": "user-1", "iss": "https://auth.example.com", "aud": "web", "exp": exp},
+		"no audience": {"sub": "user-1", "iss": "https://auth.example.com", "exp": exp},
+	}
+
+	for name, claims := range invalid {
+		_, err = a.authenticate(authContext("authorization", "Bearer "+keys.sign(t, "RS256", "rsa", claims)), "/app.Test/Echo")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), name)
+	}
+
+	for name, token := range map[string]string{
+		"wrong key":   keys.sign(t, "RS256", "ec", valid),
+		"unknown key": keys.sign(t, "RS256", "missing", valid),
+		"hmac":        keys.sign(t, "HS256", "hmac", valid),
+		"none":        keys.sign(t, "none", "rsa", valid),
+		"tampered":    keys.sign(t, "RS256", "rsa", valid)[:20] + "x" + keys.sign(t, "RS256", "rsa", valid)[21:],
+		"malformed":   "a.b.c",
+	} {
+		_, err = a.authenticate(authContext("authorization", "Bearer "+token), "/app.Test/Echo")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), name)
+	}
+
+	// unknown keys do not refetch the set more than once per jwksRetry
+	assert.Equal(t, int32(1), atomic.LoadInt32(&keys.fetches))
+}
+
+func Test_KeySet_Refresh(t *testing.T) {
+	keys := newTestKeys(t)
+
+	s := newKeySet(keys.server.URL, time.Millisecond)
+	_, err := s.key("rsa")
+	assert.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = s.key("rsa")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&keys.fetches))
+
+	// stale keys remain in use while the set is not available
+	keys.server.Close()
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = s.key("rsa")
+	assert.NoError(t, err)
+
+	_, err = s.key("missing")
+	assert.Error(t, err)
+}
+
+func Test_Proxy_Payload_Claims(t *testing.T) {
+	p := NewProxy("app.Service", "", nil)
+
+	ctx := context.WithValue(context.Background(), claimsKey{}, &claims{Subject: "user-1", Scope: "read write"})
+	payload, err := p.makePayload(ctx, "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc := rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+	assert.Equal(t, []string{"user-1"}, rc.Context[":auth.subject"])
+	assert.Equal(t, []string{"read", "write"}, rc.Context[":auth.scopes"])
+
+	payload, err = p.makePayload(context.Background(), "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc = rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+	assert.NotContains(t, rc.Context, ":auth.subject")
+}
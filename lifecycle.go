@@ -0,0 +1,176 @@
+package grpc
+
+import (
+	"github.com/spiral/roadrunner"
+	"sync"
+	"time"
+)
+
+const (
+	// EventWorkerStart thrown when pool worker is started, event context is *WorkerContext.
+	EventWorkerStart = iota + 9800
+
+	// EventWorkerStop thrown once pool worker process is stopped for any reason, event context is *WorkerContext
+	// with the reason of the stop.
+	EventWorkerStop
+)
+
+// Reasons of worker stop reported by EventWorkerStop.
+const (
+	// StopReasonStopped - worker was stopped by the pool (stop, reset or reload) or exited by itself.
+	StopReasonStopped = "stopped"
+
+	// StopReasonCrashed - worker process failed, WorkerContext.Error describes the failure.
+	StopReasonCrashed = "crashed"
+
+	// StopReasonMaxJobs - worker was replaced after executing MaxJobs calls.
+	StopReasonMaxJobs = "max jobs"
+
+	// StopReasonMaxMemory - worker was replaced after exceeding MaxMemory.
+	StopReasonMaxMemory = "max memory"
+
+	// StopReasonMaxExecutionTime - worker was killed executing the call exceeding it's execution time.
+	StopReasonMaxExecutionTime = "max execution time"
+)
+
+// WorkerContext describes started or stopped pool worker.
+type WorkerContext struct {
+	// Pid of the worker process.
+	Pid int
+
+	// Pool is name of the worker pool ("default" for Workers pool).
+	Pool string
+
+	// Reason of the stop, empty for started workers.
+	Reason string
+
+	// Error of the crashed worker, nil otherwise.
+	Error error
+
+	// Executions is number of calls executed by the worker.
+	Executions int64
+
+	// Uptime is time since the worker was created.
+	Uptime time.Duration
+
+	// Worker is the pool worker.
+	Worker *roadrunner.Worker
+}
+
+// workerLifecycle converts RoadRunner pool events into worker lifecycle events. Every started worker is reported
+// as stopped exactly once, reasons of workers removed by the service are recorded before the removal.
+type workerLifecycle struct {
+	mu      sync.Mutex
+	reasons map[*roadrunner.Worker]string
+
+	// dead workers waiting for their error
+	crashed map[*roadrunner.Worker]*WorkerContext
+}
+
+// removed records the reason of the worker removal.
+func (l *workerLifecycle) removed(w *roadrunner.Worker, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.reasons == nil {
+		l.reasons = make(map[*roadrunner.Worker]string)
+	}
+	l.reasons[w] = reason
+}
+
+// handle converts event of the named pool into lifecycle event, returns nil context when event has no lifecycle
+// alternative. Pool reports crashed workers as dead followed by the worker error, crashed workers are reported
+// once their error is known.
+func (l *workerLifecycle) handle(pool string, maxJobs int64, event int, ctx interface{}) (int, *WorkerContext) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch event {
+	case roadrunner.EventWorkerConstruct:
+		if w, ok := ctx.(*roadrunner.Worker); ok {
+			return EventWorkerStart, newWorkerContext(w, pool)
+		}
+
+	case roadrunner.EventWorkerDead:
+		w, ok := ctx.(*roadrunner.Worker)
+		if !ok {
+			return 0, nil
+		}
+
+		wc := newWorkerContext(w, pool)
+		wc.Reason, ok = l.reasons[w]
+		delete(l.reasons, w)
+
+		switch {
+		case ok:
+		case maxJobs != 0 && wc.Executions >= maxJobs:
+			wc.Reason = StopReasonMaxJobs
+		case w.State().Value() == roadrunner.StateErrored:
+			wc.Reason = StopReasonCrashed
+			if l.crashed == nil {
+				l.crashed = make(map[*roadrunner.Worker]*WorkerContext)
+			}
+			l.crashed[w] = wc
+			return 0, nil
+		default:
+			wc.Reason = StopReasonStopped
+		}
+
+		return EventWorkerStop, wc
+
+	case roadrunner.EventWorkerError:
+		we, ok := ctx.(roadrunner.WorkerError)
+		if !ok {
+			return 0, nil
+		}
+
+		if wc, ok := l.crashed[we.Worker]; ok {
+			delete(l.crashed, we.Worker)
+			wc.Error = we.Caused
+			return EventWorkerStop, wc
+		}
+	}
+
+	return 0, nil
+}
+
+// newWorkerContext describes the worker of the named pool.
+func newWorkerContext(w *roadrunner.Worker, pool string) *WorkerContext {
+	wc := &WorkerContext{
+		Pool:       pool,
+		Executions: w.State().NumExecs(),
+		Uptime:     time.Since(w.Created),
+		Worker:     w,
+	}
+
+	if w.Pid != nil {
+		wc.Pid = *w.Pid
+	}
+
+	return wc
+}
+
+// workersStarted reports workers created along with the pool, RoadRunner reports only workers created once the
+// pool is constructed (replacements of stopped workers).
+func (svc *Service) workersStarted(pool string, workers []*roadrunner.Worker) {
+	for _, w := range workers {
+		svc.throw(EventWorkerStart, newWorkerContext(w, pool))
+	}
+}
+
+// poolListener forwards events of the named pool to service listeners followed by worker lifecycle events.
+func (svc *Service) poolListener(pool string, maxJobs int64) func(event int, ctx interface{}) {
+	return func(event int, ctx interface{}) {
+		svc.throw(event, ctx)
+
+		// workers of pools created on reset
+		if p, ok := ctx.(roadrunner.Pool); ok && event == roadrunner.EventPoolConstruct {
+			svc.workersStarted(pool, p.Workers())
+			return
+		}
+
+		if event, wc := svc.lifecycle.handle(pool, maxJobs, event, ctx); wc != nil {
+			svc.throw(event, wc)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"github.com/spiral/roadrunner"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+// lifecycleEvents collects worker lifecycle events of the service.
+func lifecycleEvents(svc *Service) chan Event {
+	events := make(chan Event, 100)
+	svc.AddEventListener(func(e Event) {
+		if e.Worker != nil {
+			events <- e
+		}
+	})
+
+	return events
+}
+
+func nextEvent(t *testing.T, events chan Event) Event {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker event is missing")
+	}
+
+	return Event{}
+}
+
+func Test_Lifecycle_MaxJobs(t *testing.T) {
+	svc := &Service{}
+	events := lifecycleEvents(svc)
+
+	cfg := echoWorkers(1)
+	cfg.Pool.MaxJobs = 1
+
+	rr := roadrunner.NewServer(cfg)
+	rr.Listen(svc.poolListener("reports", 1))
+	assert.NoError(t, svc.startServer(rr, "reports"))
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventWorkerStart, e.Type)
+	assert.Equal(t, "reports", e.Worker.Pool)
+	assert.Equal(t, *rr.Workers()[0].Pid, e.Worker.Pid)
+	first := e.Worker.Pid
+
+	_, err := rr.Exec(&roadrunner.Payload{Context: []byte("{}"), Body: []byte("hello")})
+	assert.NoError(t, err)
+
+	// worker is replaced after the call
+	stopped := map[int]*WorkerContext{}
+	started := 0
+	for started == 0 || len(stopped) == 0 {
+		e := nextEvent(t, events)
+		switch e.Type {
+		case EventWorkerStart:
+			started++
+		case EventWorkerStop:
+			stopped[e.Worker.Pid] = e.Worker
+		}
+	}
+
+	if assert.NotNil(t, stopped[first]) {
+		assert.Equal(t, StopReasonMaxJobs, stopped[first].Reason)
+		assert.Equal(t, int64(1), stopped[first].Executions)
+		assert.Nil(t, stopped[first].Error)
+	}
+
+	rr.Stop()
+
+	e = nextEvent(t, events)
+	assert.Equal(t, EventWorkerStop, e.Type)
+	assert.Equal(t, StopReasonStopped, e.Worker.Reason)
+}
+
+func Test_Lifecycle_Crashed(t *testing.T) {
+	svc := &Service{}
+	events := lifecycleEvents(svc)
+
+	rr := roadrunner.NewServer(echoWorkers(1))
+	rr.Listen(svc.poolListener(defaultPool, 0))
+	assert.NoError(t, svc.startServer(rr, defaultPool))
+	defer rr.Stop()
+
+	assert.Equal(t, EventWorkerStart, nextEvent(t, events).Type)
+
+	pid := *rr.Workers()[0].Pid
+	p, err := os.FindProcess(pid)
+	assert.NoError(t, err)
+	assert.NoError(t, p.Kill())
+
+	for {
+		e := nextEvent(t, events)
+		if e.Type != EventWorkerStop {
+			continue
+		}
+
+		assert.Equal(t, pid, e.Worker.Pid)
+		assert.Equal(t, StopReasonCrashed, e.Worker.Reason)
+		assert.Error(t, e.Worker.Error)
+		return
+	}
+}
+
+func Test_Lifecycle_Removed(t *testing.T) {
+	rr := roadrunner.NewServer(echoWorkers(1))
+	assert.NoError(t, rr.Start())
+	defer rr.Stop()
+
+	w := rr.Workers()[0]
+	l := &workerLifecycle{}
+	l.removed(w, StopReasonMaxMemory)
+
+	event, wc := l.handle(defaultPool, 0, roadrunner.EventWorkerDead, w)
+	assert.Equal(t, EventWorkerStop, event)
+	assert.Equal(t, StopReasonMaxMemory, wc.Reason)
+	assert.Empty(t, l.reasons)
+
+	// events without lifecycle alternative
+	_, wc = l.handle(defaultPool, 0, roadrunner.EventWorkerError, roadrunner.WorkerError{Worker: w})
+	assert.Nil(t, wc)
+	_, wc = l.handle(defaultPool, 0, roadrunner.EventPoolConstruct, nil)
+	assert.Nil(t, wc)
+}
@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"net"
+	"syscall"
+)
+
+const (
+	// reusePortSupported indicates that SO_REUSEPORT can be enabled.
+	reusePortSupported = true
+
+	// backlogSupported indicates that listen backlog can be changed.
+	backlogSupported = true
+)
+
+// reusePort enables SO_REUSEPORT on the socket before it's bound.
+func reusePort(network, address string, c syscall.RawConn) error {
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	}); cerr != nil {
+		return cerr
+	}
+
+	return err
+}
+
+// setBacklog changes backlog of the listening socket, listen can be called again to update the backlog.
+func setBacklog(ln net.Listener, backlog int) error {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return errors.New("unable to set backlog of the listener")
+	}
+
+	c, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	if cerr := c.Control(func(fd uintptr) {
+		err = unix.Listen(int(fd), backlog)
+	}); cerr != nil {
+		return cerr
+	}
+
+	return err
+}
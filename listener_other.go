@@ -0,0 +1,28 @@
+//go:build !linux
+// +build !linux
+
+package grpc
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+const (
+	// reusePortSupported indicates that SO_REUSEPORT can be enabled.
+	reusePortSupported = false
+
+	// backlogSupported indicates that listen backlog can be changed.
+	backlogSupported = false
+)
+
+// reusePort is not supported on the platform.
+func reusePort(network, address string, c syscall.RawConn) error {
+	return errors.New("reuse port is not supported")
+}
+
+// setBacklog is not supported on the platform.
+func setBacklog(ln net.Listener, backlog int) error {
+	return errors.New("listen backlog is not supported")
+}
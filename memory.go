@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"fmt"
+	"github.com/spiral/roadrunner"
+	"github.com/spiral/roadrunner/util"
+)
+
+// EventMaxMemory thrown when pool worker exceeds MaxMemory and is replaced, event context is roadrunner.WorkerError.
+const EventMaxMemory = iota + 9700
+
+// memoryLimit replaces pool workers exceeding the memory limit. Workers are inspected once they complete calls,
+// removed workers are destroyed and replaced by the pool on the next allocation.
+type memoryLimit struct {
+	limit   uint64
+	servers []*roadrunner.Server
+	throw   func(event int, ctx interface{})
+	memory  func(w *roadrunner.Worker) (uint64, error)
+
+	// executions of workers at the last inspection
+	execs  map[*roadrunner.Worker]int64
+	notify chan struct{}
+	stop   chan struct{}
+}
+
+// newMemoryLimit creates memory limit of workers of the server pools in bytes.
+func newMemoryLimit(limit uint64, servers []*roadrunner.Server, throw func(event int, ctx interface{})) *memoryLimit {
+	return &memoryLimit{
+		limit:   limit,
+		servers: servers,
+		throw:   throw,
+		memory:  workerMemory,
+		execs:   make(map[*roadrunner.Worker]int64),
+		notify:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// workerMemory returns memory usage (RSS) of the worker in bytes.
+func workerMemory(w *roadrunner.Worker) (uint64, error) {
+	s, err := util.WorkerState(w)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.MemoryUsage, nil
+}
+
+// executed notifies that pool worker completed the call, does not block the caller.
+func (m *memoryLimit) executed() {
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+// watch inspects pool workers on every notification until the limit is closed.
+func (m *memoryLimit) watch() {
+	for {
+		select {
+		case <-m.notify:
+			m.inspect()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// close stops watching pool workers.
+func (m *memoryLimit) close() {
+	close(m.stop)
+}
+
+// inspect removes idle workers which completed calls since the last inspection and exceed the limit.
+func (m *memoryLimit) inspect() {
+	execs := make(map[*roadrunner.Worker]int64)
+	for _, rr := range m.servers {
+		if pool := rr.Pool(); pool != nil {
+			m.inspectPool(pool, execs)
+		}
+	}
+
+	m.execs = execs
+}
+
+// inspectPool inspects workers of the pool, executions of inspected workers are recorded in execs.
+func (m *memoryLimit) inspectPool(pool roadrunner.Pool, execs map[*roadrunner.Worker]int64) {
+	for _, w := range pool.Workers() {
+		last, ok := m.execs[w]
+		if ok {
+			execs[w] = last
+		}
+
+		n := w.State().NumExecs()
+		if w.State().Value() != roadrunner.StateReady || (ok && last == n) {
+			continue
+		}
+
+		usage, err := m.memory(w)
+		if err != nil {
+			continue
+		}
+		execs[w] = n
+
+		if usage >= m.limit {
+			err := fmt.Errorf("max allowed memory reached (%d bytes)", m.limit)
+			if pool.Remove(w, err) {
+				m.throw(EventMaxMemory, roadrunner.WorkerError{Worker: w, Caused: err})
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spiral/goridge"
+	"github.com/spiral/roadrunner"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoWorkers configures pool of echo workers running Test_EchoWorker.
+func echoWorkers(numWorkers int64) *roadrunner.ServerConfig {
+	cfg := &roadrunner.ServerConfig{
+		Command:      os.Args[0] + " -test.run=^Test_EchoWorker$",
+		Relay:        "pipes",
+		RelayTimeout: time.Minute,
+		Pool:         &roadrunner.Config{NumWorkers: numWorkers, AllocateTimeout: time.Minute, DestroyTimeout: time.Minute},
+	}
+	cfg.SetEnv("GRPC_TEST_WORKER", "1")
+
+	return cfg
+}
+
+// Test_EchoWorker is echo worker process started by the tests, skipped otherwise.
+func Test_EchoWorker(t *testing.T) {
+	if os.Getenv("GRPC_TEST_WORKER") == "" {
+		t.Skip("worker process")
+	}
+
+	rl := goridge.NewPipeRelay(os.Stdin, os.Stdout)
+	for {
+		data, p, err := rl.Receive()
+		if err != nil {
+			os.Exit(0)
+		}
+
+		if !p.HasFlag(goridge.PayloadRaw) {
+			var cmd struct {
+				Pid  int  `json:"pid"`
+				Stop bool `json:"stop"`
+			}
+			json.Unmarshal(data, &cmd)
+
+			if cmd.Stop {
+				os.Exit(0)
+			}
+
+			rl.Send([]byte(fmt.Sprintf(`{"pid":%d}`, os.Getpid())), goridge.PayloadControl)
+			continue
+		}
+
+		body, _, err := rl.Receive()
+		if err != nil {
+			os.Exit(0)
+		}
+
+		// "sleep:1s" bodies delay the response
+		if strings.HasPrefix(string(body), "sleep:") {
+			d, _ := time.ParseDuration(strings.TrimPrefix(string(body), "sleep:"))
+			time.Sleep(d)
+		}
+
+		rl.Send([]byte("{}"), goridge.PayloadControl|goridge.PayloadRaw)
+		rl.Send(body, goridge.PayloadRaw)
+	}
+}
+
+func Test_MemoryLimit(t *testing.T) {
+	rr := roadrunner.NewServer(echoWorkers(1))
+	assert.NoError(t, rr.Start())
+	defer rr.Stop()
+
+	events := make(chan roadrunner.WorkerError, 10)
+	m := newMemoryLimit(1024, []*roadrunner.Server{rr}, func(event int, ctx interface{}) {
+		if event == EventMaxMemory {
+			events <- ctx.(roadrunner.WorkerError)
+		}
+	})
+
+	// the first worker reports memory usage above the limit
+	first := *rr.Workers()[0].Pid
+	m.memory = func(w *roadrunner.Worker) (uint64, error) {
+		if *w.Pid == first {
+			return 2048, nil
+		}
+
+		return 512, nil
+	}
+
+	go m.watch()
+	defer m.close()
+
+	resp, err := rr.Exec(&roadrunner.Payload{Context: []byte("{}"), Body: []byte("hello")})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(resp.Body))
+	m.executed()
+
+	select {
+	case e := <-events:
+		assert.Equal(t, first, *e.Worker.Pid)
+		assert.Contains(t, e.Caused.Error(), "max allowed memory reached")
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker exceeding the limit was not removed")
+	}
+
+	// removed worker is replaced on the next call
+	resp, err = rr.Exec(&roadrunner.Payload{Context: []byte("{}"), Body: []byte("again")})
+	assert.NoError(t, err)
+	assert.Equal(t, "again", string(resp.Body))
+	assert.NotEqual(t, first, *rr.Workers()[0].Pid)
+
+	m.executed()
+	select {
+	case <-events:
+		t.Fatal("worker within the limit was removed")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func Test_Config_WorkerLimits(t *testing.T) {
+	c := &Config{}
+	assert.NoError(t, c.Hydrate(&mockCfg{`{"listen": "tcp://:9080", "proto": "parser/test.proto", "workers": {"command": "php worker.php"}, "maxJobs": 100, "maxMemory": "128MB"}`}))
+	assert.Equal(t, int64(100), c.Workers.Pool.MaxJobs)
+
+	assert.NoError(t, c.Valid())
+
+	c = &Config{}
+	assert.NoError(t, c.Hydrate(&mockCfg{`{"listen": "tcp://:9080", "proto": "parser/test.proto", "workers": {"command": "php worker.php"}, "maxJobs": -1}`}))
+	assert.Error(t, c.Valid())
+
+	c = &Config{}
+	assert.NoError(t, c.Hydrate(&mockCfg{`{"listen": "tcp://:9080", "proto": "parser/test.proto", "workers": {"command": "php worker.php"}, "maxMemory": "lots"}`}))
+	assert.Error(t, c.Valid())
+}
@@ -0,0 +1,339 @@
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"io/ioutil"
+	"unicode/utf8"
+)
+
+const (
+	// maxMessageDepth limits nesting of validated messages.
+	maxMessageDepth = 100
+
+	// maxFieldNumber is the largest valid field number.
+	maxFieldNumber = 1<<29 - 1
+)
+
+// protobuf wire types
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireStartGroup = 3
+	wireEndGroup   = 4
+	wireFixed32    = 5
+)
+
+// methodMessages describes request and response message types of the method.
+type methodMessages struct {
+	in, out *messageType
+}
+
+// messageType describes fields of the message used to validate encoded messages without decoding them.
+type messageType struct {
+	name     string
+	proto3   bool
+	fields   map[int32]*messageField
+	required []int32
+}
+
+// messageField describes field of the message.
+type messageField struct {
+	kind     dpb.FieldDescriptorProto_Type
+	repeated bool
+	message  *messageType
+}
+
+// describeMethods builds message types of methods declared by file descriptors, methods are keyed by full method
+// name ("/app.Service/Method"). Dependencies missing in files are loaded from golang/protobuf registry (well-known
+// types), messages of unknown types are not validated.
+func describeMethods(files []*dpb.FileDescriptorProto) map[string]*methodMessages {
+	files = withDependencies(files)
+
+	types := make(map[string]*messageType)
+	descriptors := make(map[string]*dpb.DescriptorProto)
+
+	var collect func(scope string, proto3 bool, messages []*dpb.DescriptorProto)
+	collect = func(scope string, proto3 bool, messages []*dpb.DescriptorProto) {
+		for _, m := range messages {
+			name := scope + "." + m.GetName()
+			types[name] = &messageType{name: name[1:], proto3: proto3, fields: make(map[int32]*messageField)}
+			descriptors[name] = m
+
+			collect(name, proto3, m.NestedType)
+		}
+	}
+
+	for _, fd := range files {
+		scope := ""
+		if fd.GetPackage() != "" {
+			scope = "." + fd.GetPackage()
+		}
+
+		collect(scope, fd.GetSyntax() == "proto3", fd.MessageType)
+	}
+
+	for name, m := range descriptors {
+		t := types[name]
+		for _, f := range m.Field {
+			t.fields[f.GetNumber()] = &messageField{
+				kind:     f.GetType(),
+				repeated: f.GetLabel() == dpb.FieldDescriptorProto_LABEL_REPEATED,
+				message:  types[f.GetTypeName()],
+			}
+
+			if f.GetLabel() == dpb.FieldDescriptorProto_LABEL_REQUIRED {
+				t.required = append(t.required, f.GetNumber())
+			}
+		}
+	}
+
+	methods := make(map[string]*methodMessages)
+	for _, fd := range files {
+		pkg := fd.GetPackage()
+		for _, s := range fd.Service {
+			service := s.GetName()
+			if pkg != "" {
+				service = pkg + "." + service
+			}
+
+			for _, m := range s.Method {
+				methods[fmt.Sprintf("/%s/%s", service, m.GetName())] = &methodMessages{
+					in:  types[m.GetInputType()],
+					out: types[m.GetOutputType()],
+				}
+			}
+		}
+	}
+
+	return methods
+}
+
+// withDependencies appends descriptors of dependencies registered in golang/protobuf registry.
+func withDependencies(files []*dpb.FileDescriptorProto) []*dpb.FileDescriptorProto {
+	known := make(map[string]bool)
+	for _, fd := range files {
+		known[fd.GetName()] = true
+	}
+
+	for i := 0; i < len(files); i++ {
+		for _, dep := range files[i].Dependency {
+			if known[dep] {
+				continue
+			}
+			known[dep] = true
+
+			if fd, err := registeredFile(dep); err == nil {
+				files = append(files, fd)
+			}
+		}
+	}
+
+	return files
+}
+
+// registeredFile decodes file descriptor registered in golang/protobuf registry.
+func registeredFile(name string) (*dpb.FileDescriptorProto, error) {
+	enc := proto.FileDescriptor(name)
+	if enc == nil {
+		return nil, fmt.Errorf("file descriptor '%s' is not registered", name)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	fd := &dpb.FileDescriptorProto{}
+	return fd, proto.Unmarshal(data, fd)
+}
+
+// validate checks that data is well formed encoded message of the type: every field can be read, known fields
+// are encoded with wire types matching their declaration, nested messages are valid, proto3 strings are valid
+// UTF-8 and proto2 required fields are present. Unknown fields are allowed.
+func (t *messageType) validate(data []byte) error {
+	if t == nil {
+		return nil
+	}
+
+	return t.check(data, 0)
+}
+
+// check validates message at the given nesting depth.
+func (t *messageType) check(data []byte, depth int) error {
+	if depth > maxMessageDepth {
+		return errors.New("message nesting is too deep")
+	}
+
+	var seen map[int32]bool
+	if len(t.required) != 0 {
+		seen = make(map[int32]bool)
+	}
+
+	for len(data) != 0 {
+		num, wire, value, rest, err := readField(data)
+		if err != nil {
+			return fmt.Errorf("%s: %s", t.name, err)
+		}
+		data = rest
+
+		f, ok := t.fields[num]
+		if !ok {
+			continue
+		}
+
+		if seen != nil {
+			seen[num] = true
+		}
+
+		if err := f.check(wire, value, t.proto3, depth); err != nil {
+			return fmt.Errorf("%s: field %d %s", t.name, num, err)
+		}
+	}
+
+	for _, num := range t.required {
+		if !seen[num] {
+			return fmt.Errorf("%s: required field %d is missing", t.name, num)
+		}
+	}
+
+	return nil
+}
+
+// check validates value of the field encoded using the wire type.
+func (f *messageField) check(wire int, value []byte, proto3 bool, depth int) error {
+	expected := wireType(f.kind)
+	if wire != expected {
+		// repeated scalars can be packed
+		if f.repeated && wire == wireBytes && expected != wireBytes && expected != wireStartGroup {
+			return checkPacked(expected, value)
+		}
+
+		return fmt.Errorf("has wire type %d, expected %d", wire, expected)
+	}
+
+	switch f.kind {
+	case dpb.FieldDescriptorProto_TYPE_MESSAGE, dpb.FieldDescriptorProto_TYPE_GROUP:
+		if f.message != nil {
+			return f.message.check(value, depth+1)
+		}
+	case dpb.FieldDescriptorProto_TYPE_STRING:
+		if proto3 && !utf8.Valid(value) {
+			return errors.New("contains invalid UTF-8")
+		}
+	}
+
+	return nil
+}
+
+// checkPacked validates packed repeated scalars.
+func checkPacked(wire int, data []byte) error {
+	for len(data) != 0 {
+		_, rest, err := readValue(wire, 0, data)
+		if err != nil {
+			return errors.New("contains malformed packed values")
+		}
+		data = rest
+	}
+
+	return nil
+}
+
+// readField reads field key and value, value of length delimited fields and groups excludes the length and
+// end group key.
+func readField(data []byte) (num int32, wire int, value, rest []byte, err error) {
+	key, n := proto.DecodeVarint(data)
+	if n == 0 {
+		return 0, 0, nil, nil, errors.New("malformed field key")
+	}
+
+	if key>>3 == 0 || key>>3 > maxFieldNumber {
+		return 0, 0, nil, nil, fmt.Errorf("invalid field number %d", key>>3)
+	}
+
+	num, wire = int32(key>>3), int(key&7)
+	value, rest, err = readValue(wire, num, data[n:])
+	if err != nil {
+		return 0, 0, nil, nil, fmt.Errorf("field %d %s", num, err)
+	}
+
+	return num, wire, value, rest, nil
+}
+
+// readValue reads value of the wire type.
+func readValue(wire int, num int32, data []byte) (value, rest []byte, err error) {
+	switch wire {
+	case wireVarint:
+		if _, n := proto.DecodeVarint(data); n != 0 {
+			return data[:n], data[n:], nil
+		}
+	case wireFixed64:
+		if len(data) >= 8 {
+			return data[:8], data[8:], nil
+		}
+	case wireFixed32:
+		if len(data) >= 4 {
+			return data[:4], data[4:], nil
+		}
+	case wireBytes:
+		size, n := proto.DecodeVarint(data)
+		if n != 0 && size <= uint64(len(data)-n) {
+			return data[n : n+int(size)], data[n+int(size):], nil
+		}
+	case wireStartGroup:
+		for rest = data; len(rest) != 0; {
+			key, n := proto.DecodeVarint(rest)
+			if n == 0 {
+				break
+			}
+
+			if int(key&7) == wireEndGroup {
+				if int32(key>>3) != num {
+					return nil, nil, errors.New("has mismatched end group")
+				}
+
+				return data[:len(data)-len(rest)], rest[n:], nil
+			}
+
+			if _, _, _, rest, err = readField(rest); err != nil {
+				return nil, nil, err
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("has invalid wire type %d", wire)
+	}
+
+	return nil, nil, errors.New("is truncated")
+}
+
+// wireType returns wire type used to encode the field type.
+func wireType(kind dpb.FieldDescriptorProto_Type) int {
+	switch kind {
+	case dpb.FieldDescriptorProto_TYPE_DOUBLE,
+		dpb.FieldDescriptorProto_TYPE_FIXED64,
+		dpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return wireFixed64
+	case dpb.FieldDescriptorProto_TYPE_FLOAT,
+		dpb.FieldDescriptorProto_TYPE_FIXED32,
+		dpb.FieldDescriptorProto_TYPE_SFIXED32:
+		return wireFixed32
+	case dpb.FieldDescriptorProto_TYPE_STRING,
+		dpb.FieldDescriptorProto_TYPE_BYTES,
+		dpb.FieldDescriptorProto_TYPE_MESSAGE:
+		return wireBytes
+	case dpb.FieldDescriptorProto_TYPE_GROUP:
+		return wireStartGroup
+	}
+
+	return wireVarint
+}
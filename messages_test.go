@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/spiral/php-grpc/parser"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func loadMethods(t *testing.T, file string) map[string]*methodMessages {
+	files, err := parser.Descriptor(file)
+	assert.NoError(t, err)
+
+	return describeMethods(files)
+}
+
+// encodeFields encodes fields given as pairs of key and value, values of bytes keys are length prefixed.
+func encodeFields(fields ...interface{}) []byte {
+	b := proto.NewBuffer(nil)
+	for i := 0; i < len(fields); i += 2 {
+		key := fields[i].(uint64)
+		b.EncodeVarint(key)
+
+		switch v := fields[i+1].(type) {
+		case uint64:
+			b.EncodeVarint(v)
+		case []byte:
+			b.EncodeRawBytes(v)
+		case string:
+			b.EncodeStringBytes(v)
+		}
+	}
+
+	return b.Bytes()
+}
+
+func Test_Messages_Validate(t *testing.T) {
+	msg := loadMethods(t, "parser/test.proto")["/app.namespace.PingService/Ping"]
+	if !assert.NotNil(t, msg) || !assert.NotNil(t, msg.in) {
+		return
+	}
+
+	assert.Equal(t, "app.namespace.Message", msg.in.name)
+	assert.NoError(t, msg.in.validate(nil))
+	assert.NoError(t, msg.in.validate(encodeFields(uint64(1<<3|2), "hello", uint64(2<<3), uint64(5))))
+
+	// unknown fields are allowed
+	assert.NoError(t, msg.out.validate(append(encodeFields(uint64(2<<3), uint64(1)), 0x7d, 1, 2, 3, 4)))
+
+	for name, data := range map[string][]byte{
+		"garbage":        []byte("not a message"),
+		"wrong type":     encodeFields(uint64(1<<3), uint64(1)),
+		"truncated":      encodeFields(uint64(1<<3|2), "hello")[:4],
+		"invalid utf8":   encodeFields(uint64(1<<3|2), []byte{0xff, 0xfe}),
+		"field zero":     encodeFields(uint64(0), uint64(1)),
+		"end group":      {0x0c},
+		"invalid wire":   {0x0e},
+		"truncated key":  {0x80},
+		"fixed64 cutoff": {0x11, 1, 2, 3},
+	} {
+		assert.Error(t, msg.in.validate(data), name)
+	}
+}
+
+func Test_Messages_Nested(t *testing.T) {
+	msg := loadMethods(t, "parser/test_types.proto")["/app.types.Types/Get"]
+	if !assert.NotNil(t, msg) {
+		return
+	}
+
+	// google.protobuf.Empty is loaded from the bundled descriptors
+	assert.NotNil(t, msg.in)
+	assert.Equal(t, "google.protobuf.Empty", msg.in.name)
+
+	inner := encodeFields(uint64(1<<3), uint64(1))
+	entry := encodeFields(uint64(1<<3|2), "name", uint64(2<<3|2), inner)
+	assert.NoError(t, msg.out.validate(encodeFields(uint64(1<<3|2), entry, uint64(4<<3|2), inner)))
+
+	// kind of the map value is length delimited
+	invalid := encodeFields(uint64(1<<3|2), "name", uint64(2<<3|2), encodeFields(uint64(1<<3|2), "B"))
+	err := msg.out.validate(encodeFields(uint64(1<<3|2), invalid))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "app.types.Outer.Inner: field 1")
+
+	// nested message is malformed
+	assert.Error(t, msg.out.validate(encodeFields(uint64(3<<3|2), []byte{0x0a, 0x05})))
+}
+
+func Test_Messages_Proto2(t *testing.T) {
+	msg := loadMethods(t, "parser/test_proto2.proto")["/app.legacy.Legacy/Call"]
+	if !assert.NotNil(t, msg) {
+		return
+	}
+
+	name := encodeFields(uint64(1<<3|2), "name")
+	packed := encodeFields(uint64(2<<3|2), []byte{1, 2, 0x80, 0x01})
+	unpacked := encodeFields(uint64(2<<3), uint64(1), uint64(2<<3), uint64(2))
+
+	assert.NoError(t, msg.in.validate(name))
+	assert.NoError(t, msg.in.validate(append(append([]byte{}, name...), packed...)))
+	assert.NoError(t, msg.in.validate(append(append([]byte{}, name...), unpacked...)))
+
+	// proto2 strings are not checked for UTF-8
+	assert.NoError(t, msg.in.validate(encodeFields(uint64(1<<3|2), []byte{0xff})))
+
+	err := msg.in.validate(packed)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "required field 1 is missing")
+
+	// required fields of nested messages are checked as well
+	assert.Error(t, msg.in.validate(append(append([]byte{}, name...), encodeFields(uint64(3<<3|2), packed)...)))
+
+	// packed values are truncated
+	assert.Error(t, msg.in.validate(append(append([]byte{}, name...), encodeFields(uint64(2<<3|2), []byte{1, 0x80})...)))
+}
+
+func Test_Proxy_ValidateMessages(t *testing.T) {
+	p := NewProxy("app.namespace.PingService", "", nil)
+	p.RegisterMethod("Ping")
+	p.messages["Ping"] = loadMethods(t, "parser/test.proto")["/app.namespace.PingService/Ping"]
+
+	_, err := p.exec(context.Background(), "Ping", rawMessage("not a message"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "invalid request message")
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("content-type", "application/grpc+json"))
+	assert.Equal(t, "json", messageEncoding(ctx))
+	assert.Equal(t, "proto", messageEncoding(context.Background()))
+}
@@ -0,0 +1,134 @@
+package grpc
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spiral/roadrunner"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+	"time"
+)
+
+// metrics collects RPC and worker pool statistics.
+type metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+// newMetrics creates and registers service collectors, workers function provides current list of pool workers.
+func newMetrics(namespace string, workers func() []*roadrunner.Worker) (*metrics, error) {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Total number of handled RPC calls.",
+		}, []string{"method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "RPC call duration.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "requests_in_flight",
+			Help:      "Number of RPC calls being processed.",
+		}, []string{"method"}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.requests,
+		m.duration,
+		m.inFlight,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "workers_total",
+			Help:      "Number of workers in the pool.",
+		}, func() float64 {
+			return float64(len(workers()))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "workers_busy",
+			Help:      "Number of workers processing the request.",
+		}, func() float64 {
+			busy := 0
+			for _, w := range workers() {
+				if w.State().Value() == roadrunner.StateWorking {
+					busy++
+				}
+			}
+
+			return float64(busy)
+		}),
+	}
+
+	for _, c := range collectors {
+		if err := m.registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// listen serves /metrics endpoint on given address.
+func (m *metrics) listen(address string) (*http.Server, error) {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(l)
+
+	return server, nil
+}
+
+// observe records single RPC call.
+func (m *metrics) observe(method string, start time.Time, err error) {
+	m.inFlight.WithLabelValues(method).Dec()
+	m.requests.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// unaryInterceptor records metrics of unary calls.
+func (m *metrics) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	m.inFlight.WithLabelValues(info.FullMethod).Inc()
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	m.observe(info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// streamInterceptor records metrics of streaming calls.
+func (m *metrics) streamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	m.inFlight.WithLabelValues(info.FullMethod).Inc()
+
+	start := time.Now()
+	err := handler(srv, ss)
+	m.observe(info.FullMethod, start, err)
+
+	return err
+}
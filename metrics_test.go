@@ -0,0 +1,95 @@
+package grpc
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spiral/roadrunner"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func Test_Metrics_Unary(t *testing.T) {
+	m, err := newMetrics("test", func() []*roadrunner.Worker { return nil })
+	assert.NoError(t, err)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+
+	_, err = m.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	assert.NoError(t, err)
+
+	_, err = m.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	assert.Error(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("/service.Test/Echo", "OK")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("/service.Test/Echo", "NotFound")))
+
+	families, err := m.registry.Gather()
+	assert.NoError(t, err)
+
+	names := make([]string, 0)
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+
+	assert.Contains(t, names, "test_requests_total")
+	assert.Contains(t, names, "test_request_duration_seconds")
+	assert.Contains(t, names, "test_workers_total")
+	assert.Contains(t, names, "test_workers_busy")
+}
+
+func Test_Metrics_Stream(t *testing.T) {
+	m, err := newMetrics("test", func() []*roadrunner.Worker { return nil })
+	assert.NoError(t, err)
+
+	info := &grpc.StreamServerInfo{FullMethod: "/service.Test/Stream"}
+
+	err = m.streamInterceptor(nil, &mockStream{}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+	assert.Error(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("/service.Test/Stream", "Unavailable")))
+}
+
+func Test_Metrics_InFlight(t *testing.T) {
+	m, err := newMetrics("test", func() []*roadrunner.Worker { return nil })
+	assert.NoError(t, err)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+
+	_, err = m.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.inFlight.WithLabelValues("/service.Test/Echo")))
+		return "ok", nil
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.inFlight.WithLabelValues("/service.Test/Echo")))
+}
+
+func Test_Metrics_Listen(t *testing.T) {
+	m, err := newMetrics("test", func() []*roadrunner.Worker { return nil })
+	assert.NoError(t, err)
+
+	server, err := m.listen("localhost:9113")
+	assert.NoError(t, err)
+	defer server.Close()
+
+	r, err := http.Get("http://localhost:9113/metrics")
+	assert.NoError(t, err)
+	defer r.Body.Close()
+
+	b, err := ioutil.ReadAll(r.Body)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 200, r.StatusCode)
+	assert.Contains(t, string(b), "test_workers_total")
+}
@@ -0,0 +1,466 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	pp "github.com/emicklei/proto"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// scalar types supported by proto
+var scalars = map[string]dpb.FieldDescriptorProto_Type{
+	"double":   dpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"float":    dpb.FieldDescriptorProto_TYPE_FLOAT,
+	"int64":    dpb.FieldDescriptorProto_TYPE_INT64,
+	"uint64":   dpb.FieldDescriptorProto_TYPE_UINT64,
+	"int32":    dpb.FieldDescriptorProto_TYPE_INT32,
+	"fixed64":  dpb.FieldDescriptorProto_TYPE_FIXED64,
+	"fixed32":  dpb.FieldDescriptorProto_TYPE_FIXED32,
+	"bool":     dpb.FieldDescriptorProto_TYPE_BOOL,
+	"string":   dpb.FieldDescriptorProto_TYPE_STRING,
+	"bytes":    dpb.FieldDescriptorProto_TYPE_BYTES,
+	"uint32":   dpb.FieldDescriptorProto_TYPE_UINT32,
+	"sfixed32": dpb.FieldDescriptorProto_TYPE_SFIXED32,
+	"sfixed64": dpb.FieldDescriptorProto_TYPE_SFIXED64,
+	"sint32":   dpb.FieldDescriptorProto_TYPE_SINT32,
+	"sint64":   dpb.FieldDescriptorProto_TYPE_SINT64,
+}
+
+// Descriptor builds file descriptors of the given proto file and all of it's imports. Descriptors
+// are ordered by dependency, the last descriptor belongs to the given file. Imports are resolved against
+// every import path in order, imports which can not be found are resolved using golang/protobuf
+// registry (well-known types).
+func Descriptor(file string, importPaths ...string) ([]*dpb.FileDescriptorProto, error) {
+	name := filepath.Base(file)
+	for _, dir := range importPaths {
+		if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+			break
+		}
+	}
+
+	b := &builder{
+		importPaths: importPaths,
+		symbols:     make(map[string]dpb.FieldDescriptorProto_Type),
+		loaded:      make(map[string]bool),
+	}
+
+	if err := b.load(filepath.ToSlash(name), file); err != nil {
+		return nil, err
+	}
+
+	return b.files, nil
+}
+
+// Compress encodes file descriptor in a form used by golang/protobuf registry.
+func Compress(fd *dpb.FileDescriptorProto) ([]byte, error) {
+	data, err := proto.Marshal(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// builder converts parsed proto definitions into file descriptors.
+type builder struct {
+	importPaths []string
+	symbols     map[string]dpb.FieldDescriptorProto_Type
+	loaded      map[string]bool
+	files       []*dpb.FileDescriptorProto
+}
+
+// load parses proto file and all of it's imports.
+func (b *builder) load(name, file string) error {
+	if b.loaded[name] {
+		return nil
+	}
+	b.loaded[name] = true
+
+	reader, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	pf, err := pp.NewParser(reader).Parse()
+	if err != nil {
+		return err
+	}
+
+	fd := &dpb.FileDescriptorProto{Name: proto.String(name)}
+	if pkg := parsePackage(pf); pkg != "" {
+		fd.Package = proto.String(pkg)
+	}
+
+	for _, e := range pf.Elements {
+		switch v := e.(type) {
+		case *pp.Syntax:
+			if v.Value == "proto3" {
+				fd.Syntax = proto.String(v.Value)
+			}
+		case *pp.Import:
+			fd.Dependency = append(fd.Dependency, v.Filename)
+			if err := b.loadImport(v.Filename); err != nil {
+				return err
+			}
+		}
+	}
+
+	b.collect(fd.GetPackage(), pf.Elements)
+
+	for _, e := range pf.Elements {
+		switch v := e.(type) {
+		case *pp.Message:
+			if v.IsExtend {
+				continue
+			}
+
+			m, err := b.message(fd.GetPackage(), v)
+			if err != nil {
+				return err
+			}
+			fd.MessageType = append(fd.MessageType, m)
+		case *pp.Enum:
+			fd.EnumType = append(fd.EnumType, enum(v))
+		case *pp.Service:
+			s, err := b.service(fd.GetPackage(), v)
+			if err != nil {
+				return err
+			}
+			fd.Service = append(fd.Service, s)
+		}
+	}
+
+	b.files = append(b.files, fd)
+	return nil
+}
+
+// loadImport loads imported file from import paths or from golang/protobuf registry.
+func (b *builder) loadImport(name string) error {
+	file, err := resolveImport(name, b.importPaths)
+	if err == nil {
+		return b.load(name, file)
+	}
+
+	if b.loaded[name] {
+		return nil
+	}
+	b.loaded[name] = true
+
+	enc := proto.FileDescriptor(name)
+	if enc == nil {
+		return err
+	}
+
+	fd, err := decompress(enc)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range fd.MessageType {
+		b.collectDescriptor(fd.GetPackage(), m)
+	}
+
+	for _, e := range fd.EnumType {
+		b.symbols[join(fd.GetPackage(), e.GetName())] = dpb.FieldDescriptorProto_TYPE_ENUM
+	}
+
+	return nil
+}
+
+// collect registers all message and enum names declared in the given scope.
+func (b *builder) collect(scope string, elements []pp.Visitee) {
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *pp.Message:
+			if v.IsExtend {
+				continue
+			}
+
+			name := join(scope, v.Name)
+			b.symbols[name] = dpb.FieldDescriptorProto_TYPE_MESSAGE
+			b.collect(name, v.Elements)
+		case *pp.MapField:
+			b.symbols[join(scope, mapEntry(v.Name))] = dpb.FieldDescriptorProto_TYPE_MESSAGE
+		case *pp.Enum:
+			b.symbols[join(scope, v.Name)] = dpb.FieldDescriptorProto_TYPE_ENUM
+		}
+	}
+}
+
+// collectDescriptor registers message declared by existed descriptor.
+func (b *builder) collectDescriptor(scope string, m *dpb.DescriptorProto) {
+	name := join(scope, m.GetName())
+	b.symbols[name] = dpb.FieldDescriptorProto_TYPE_MESSAGE
+
+	for _, n := range m.NestedType {
+		b.collectDescriptor(name, n)
+	}
+
+	for _, e := range m.EnumType {
+		b.symbols[join(name, e.GetName())] = dpb.FieldDescriptorProto_TYPE_ENUM
+	}
+}
+
+// resolve finds fully qualified name of the type referenced within the given scope.
+func (b *builder) resolve(scope, name string) (string, dpb.FieldDescriptorProto_Type, error) {
+	if strings.HasPrefix(name, ".") {
+		if t, ok := b.symbols[name[1:]]; ok {
+			return name, t, nil
+		}
+
+		return "", 0, fmt.Errorf("unable to resolve type '%s'", name)
+	}
+
+	for s := scope; ; s = parent(s) {
+		if t, ok := b.symbols[join(s, name)]; ok {
+			return "." + join(s, name), t, nil
+		}
+
+		if s == "" {
+			return "", 0, fmt.Errorf("unable to resolve type '%s' in '%s'", name, scope)
+		}
+	}
+}
+
+// message builds message descriptor.
+func (b *builder) message(scope string, m *pp.Message) (*dpb.DescriptorProto, error) {
+	name := join(scope, m.Name)
+	d := &dpb.DescriptorProto{Name: proto.String(m.Name)}
+
+	for _, e := range m.Elements {
+		switch v := e.(type) {
+		case *pp.NormalField:
+			label := dpb.FieldDescriptorProto_LABEL_OPTIONAL
+			if v.Repeated {
+				label = dpb.FieldDescriptorProto_LABEL_REPEATED
+			} else if v.Required {
+				label = dpb.FieldDescriptorProto_LABEL_REQUIRED
+			}
+
+			f, err := b.field(name, v.Field, label)
+			if err != nil {
+				return nil, err
+			}
+			d.Field = append(d.Field, f)
+		case *pp.MapField:
+			entry, err := b.mapEntry(name, v)
+			if err != nil {
+				return nil, err
+			}
+			d.NestedType = append(d.NestedType, entry)
+
+			f := newField(v.Field, dpb.FieldDescriptorProto_LABEL_REPEATED)
+			f.Type = dpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			f.TypeName = proto.String("." + join(name, entry.GetName()))
+			d.Field = append(d.Field, f)
+		case *pp.Oneof:
+			index := int32(len(d.OneofDecl))
+			d.OneofDecl = append(d.OneofDecl, &dpb.OneofDescriptorProto{Name: proto.String(v.Name)})
+
+			for _, oe := range v.Elements {
+				if of, ok := oe.(*pp.OneOfField); ok {
+					f, err := b.field(name, of.Field, dpb.FieldDescriptorProto_LABEL_OPTIONAL)
+					if err != nil {
+						return nil, err
+					}
+
+					f.OneofIndex = proto.Int32(index)
+					d.Field = append(d.Field, f)
+				}
+			}
+		case *pp.Message:
+			if v.IsExtend {
+				continue
+			}
+
+			nested, err := b.message(name, v)
+			if err != nil {
+				return nil, err
+			}
+			d.NestedType = append(d.NestedType, nested)
+		case *pp.Enum:
+			d.EnumType = append(d.EnumType, enum(v))
+		}
+	}
+
+	return d, nil
+}
+
+// mapEntry builds synthetic map entry message.
+func (b *builder) mapEntry(scope string, m *pp.MapField) (*dpb.DescriptorProto, error) {
+	key, err := b.field(scope, &pp.Field{Name: "key", Type: m.KeyType, Sequence: 1}, dpb.FieldDescriptorProto_LABEL_OPTIONAL)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := b.field(scope, &pp.Field{Name: "value", Type: m.Type, Sequence: 2}, dpb.FieldDescriptorProto_LABEL_OPTIONAL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dpb.DescriptorProto{
+		Name:    proto.String(mapEntry(m.Name)),
+		Field:   []*dpb.FieldDescriptorProto{key, value},
+		Options: &dpb.MessageOptions{MapEntry: proto.Bool(true)},
+	}, nil
+}
+
+// field builds field descriptor and resolves it's type.
+func (b *builder) field(scope string, f *pp.Field, label dpb.FieldDescriptorProto_Label) (*dpb.FieldDescriptorProto, error) {
+	d := newField(f, label)
+
+	if t, ok := scalars[f.Type]; ok {
+		d.Type = t.Enum()
+		return d, nil
+	}
+
+	name, t, err := b.resolve(scope, f.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Type = t.Enum()
+	d.TypeName = proto.String(name)
+
+	return d, nil
+}
+
+// service builds service descriptor.
+func (b *builder) service(scope string, s *pp.Service) (*dpb.ServiceDescriptorProto, error) {
+	d := &dpb.ServiceDescriptorProto{Name: proto.String(s.Name)}
+
+	for _, m := range parseMethods(s) {
+		in, _, err := b.resolve(scope, m.RequestType)
+		if err != nil {
+			return nil, err
+		}
+
+		out, _, err := b.resolve(scope, m.ReturnsType)
+		if err != nil {
+			return nil, err
+		}
+
+		d.Method = append(d.Method, &dpb.MethodDescriptorProto{
+			Name:            proto.String(m.Name),
+			InputType:       proto.String(in),
+			OutputType:      proto.String(out),
+			ClientStreaming: proto.Bool(m.StreamsRequest),
+			ServerStreaming: proto.Bool(m.StreamsReturns),
+		})
+	}
+
+	return d, nil
+}
+
+// enum builds enum descriptor.
+func enum(e *pp.Enum) *dpb.EnumDescriptorProto {
+	d := &dpb.EnumDescriptorProto{Name: proto.String(e.Name)}
+	for _, v := range e.Elements {
+		if f, ok := v.(*pp.EnumField); ok {
+			d.Value = append(d.Value, &dpb.EnumValueDescriptorProto{
+				Name:   proto.String(f.Name),
+				Number: proto.Int32(int32(f.Integer)),
+			})
+		}
+	}
+
+	return d
+}
+
+// newField creates field descriptor without type information.
+func newField(f *pp.Field, label dpb.FieldDescriptorProto_Label) *dpb.FieldDescriptorProto {
+	return &dpb.FieldDescriptorProto{
+		Name:     proto.String(f.Name),
+		JsonName: proto.String(jsonName(f.Name)),
+		Number:   proto.Int32(int32(f.Sequence)),
+		Label:    label.Enum(),
+	}
+}
+
+// decompress decodes file descriptor from golang/protobuf registry.
+func decompress(enc []byte) (*dpb.FileDescriptorProto, error) {
+	r, err := gzip.NewReader(bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	fd := &dpb.FileDescriptorProto{}
+	return fd, proto.Unmarshal(data, fd)
+}
+
+// mapEntry returns name of synthetic map entry message (same as protoc).
+func mapEntry(field string) string {
+	name := camelCase(field)
+	return strings.ToUpper(name[:1]) + name[1:] + "Entry"
+}
+
+// jsonName returns lowerCamelCase field name (same as protoc).
+func jsonName(field string) string {
+	return camelCase(field)
+}
+
+// camelCase removes underscores and capitalizes following letters.
+func camelCase(s string) string {
+	var (
+		out   []byte
+		upper bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' {
+			upper = true
+			continue
+		}
+
+		if upper && c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+
+		upper = false
+		out = append(out, c)
+	}
+
+	return string(out)
+}
+
+// join joins scope and name.
+func join(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+
+	return scope + "." + name
+}
+
+// parent returns parent scope.
+func parent(scope string) string {
+	if i := strings.LastIndex(scope, "."); i != -1 {
+		return scope[:i]
+	}
+
+	return ""
+}
@@ -0,0 +1,148 @@
+package parser
+
+import (
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	_ "github.com/golang/protobuf/ptypes/empty"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDescriptor(t *testing.T) {
+	files, err := Descriptor("test.proto", ".")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	fd := files[0]
+	assert.Equal(t, "test.proto", fd.GetName())
+	assert.Equal(t, "app.namespace", fd.GetPackage())
+	assert.Equal(t, "proto3", fd.GetSyntax())
+	assert.Len(t, fd.Service, 2)
+	assert.Len(t, fd.MessageType, 1)
+
+	ping := fd.Service[0].Method[0]
+	assert.Equal(t, "Ping", ping.GetName())
+	assert.Equal(t, ".app.namespace.Message", ping.GetInputType())
+	assert.Equal(t, ".app.namespace.Message", ping.GetOutputType())
+
+	pong := fd.Service[1].Method[0]
+	assert.True(t, pong.GetClientStreaming())
+	assert.True(t, pong.GetServerStreaming())
+}
+
+func TestDescriptorWithImports(t *testing.T) {
+	files, err := Descriptor("test_nested/test_import.proto", "test_nested")
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	assert.Equal(t, "message.proto", files[0].GetName())
+	assert.Equal(t, "pong.proto", files[1].GetName())
+	assert.Equal(t, "test_import.proto", files[2].GetName())
+	assert.Equal(t, []string{"message.proto", "pong.proto"}, files[2].Dependency)
+
+	data, err := Compress(files[2])
+	assert.NoError(t, err)
+
+	fd, err := decompress(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_import.proto", fd.GetName())
+}
+
+func TestDescriptorTypes(t *testing.T) {
+	files, err := Descriptor("test_types.proto", ".")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	fd := files[0]
+	assert.Equal(t, []string{"google/protobuf/empty.proto"}, fd.Dependency)
+	assert.Equal(t, ".google.protobuf.Empty", fd.Service[0].Method[0].GetInputType())
+	assert.Equal(t, ".app.types.Outer", fd.Service[0].Method[0].GetOutputType())
+
+	outer := fd.MessageType[0]
+	assert.Len(t, outer.NestedType, 2)
+	assert.Len(t, outer.EnumType, 1)
+	assert.Len(t, outer.OneofDecl, 1)
+
+	entry := outer.NestedType[1]
+	assert.Equal(t, "ItemsByNameEntry", entry.GetName())
+	assert.True(t, entry.GetOptions().GetMapEntry())
+	assert.Equal(t, ".app.types.Outer.Inner", entry.Field[1].GetTypeName())
+
+	assert.Equal(t, "itemsByName", outer.Field[0].GetJsonName())
+	assert.Equal(t, ".app.types.Outer.ItemsByNameEntry", outer.Field[0].GetTypeName())
+	assert.Equal(t, int32(0), outer.Field[1].GetOneofIndex())
+	assert.Equal(t, ".app.types.Outer.Inner", outer.Field[2].GetTypeName())
+	assert.Equal(t, ".app.types.Outer.Inner", outer.Field[3].GetTypeName())
+
+	assert.Equal(t, ".app.types.Outer.Kind", outer.NestedType[0].Field[0].GetTypeName())
+}
+
+func TestDescriptorOptionalOneof(t *testing.T) {
+	files, err := Descriptor("test_features.proto")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	fd := files[0]
+	assert.Equal(t, ".app.features.Request", fd.Service[0].Method[0].GetInputType())
+	assert.Equal(t, ".app.features.Profile", fd.Service[0].Method[0].GetOutputType())
+
+	// proto3 optional fields are regular optional fields on the wire
+	request := fd.MessageType[0]
+	assert.Len(t, request.Field, 2)
+	assert.Len(t, request.OneofDecl, 0)
+	assert.Equal(t, dpb.FieldDescriptorProto_LABEL_OPTIONAL, request.Field[0].GetLabel())
+
+	profile := fd.MessageType[1]
+	assert.Len(t, profile.Field, 5)
+	assert.Len(t, profile.OneofDecl, 1)
+	assert.Equal(t, "contact", profile.OneofDecl[0].GetName())
+	assert.Nil(t, profile.Field[1].OneofIndex)
+	for _, f := range profile.Field[2:] {
+		assert.Equal(t, int32(0), f.GetOneofIndex())
+	}
+	assert.Equal(t, ".app.features.Profile.Address", profile.Field[4].GetTypeName())
+
+	address := profile.NestedType[0]
+	assert.Len(t, address.OneofDecl, 1)
+	assert.Equal(t, "kind", address.OneofDecl[0].GetName())
+	assert.Len(t, address.Field, 3)
+}
+
+func TestDescriptorWellKnownTypes(t *testing.T) {
+	files, err := Descriptor("test_wellknown.proto", ".")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	fd := files[0]
+	assert.Len(t, fd.Dependency, 7)
+	assert.Equal(t, ".google.protobuf.Empty", fd.Service[0].Method[0].GetOutputType())
+
+	event := fd.MessageType[0]
+	assert.Equal(t, dpb.FieldDescriptorProto_LABEL_OPTIONAL, event.Field[0].GetLabel())
+	assert.Equal(t, ".google.protobuf.Timestamp", event.Field[1].GetTypeName())
+	assert.Equal(t, ".google.protobuf.Duration", event.Field[2].GetTypeName())
+	assert.Equal(t, ".google.protobuf.StringValue", event.Field[3].GetTypeName())
+	assert.Equal(t, ".google.protobuf.Struct", event.Field[4].GetTypeName())
+	assert.Equal(t, ".google.protobuf.Any", event.Field[5].GetTypeName())
+
+	assert.Equal(t, ".google.protobuf.FieldMask", fd.MessageType[1].Field[1].GetTypeName())
+}
+
+func TestDescriptorNotFound(t *testing.T) {
+	_, err := Descriptor("test2.proto", ".")
+	assert.Error(t, err)
+}
+
+func TestDescriptorMissingImport(t *testing.T) {
+	_, err := Descriptor("test_nested/test_import.proto", "test_types")
+	assert.Error(t, err)
+}
+
+func TestDescriptorImportPaths(t *testing.T) {
+	files, err := Descriptor("test_include/api/service.proto", "test_include/api", "test_include/shared")
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	assert.Equal(t, "common/types.proto", files[0].GetName())
+	assert.Equal(t, "service.proto", files[1].GetName())
+	assert.Equal(t, ".app.common.Request", files[1].Service[0].Method[0].GetInputType())
+}
@@ -2,11 +2,20 @@ package parser
 
 import (
 	"bytes"
+	"fmt"
 	pp "github.com/emicklei/proto"
+	gproto "github.com/golang/protobuf/proto"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
+// position matches line and column prefix of syntax errors.
+var position = regexp.MustCompile(`^(\d+):(\d+): (.*)$`)
+
 // Service contains information about singular GRPC service.
 type Service struct {
 	// Package defines service namespace.
@@ -19,6 +28,16 @@ type Service struct {
 	Methods []Method
 }
 
+// FullName returns fully qualified service name used in gRPC paths ("app.namespace.Service"), services of
+// files without package are named by the service name only.
+func (s Service) FullName() string {
+	if s.Package == "" {
+		return s.Name
+	}
+
+	return s.Package + "." + s.Name
+}
+
 // Method describes singular RPC method.
 type Method struct {
 	// Name is method name.
@@ -37,32 +56,120 @@ type Method struct {
 	ReturnsType string
 }
 
-// File parses given proto file or returns error.
-func File(file string, importPath string) ([]Service, error) {
-	reader, _ := os.Open(file)
-	defer reader.Close()
+// Error describes proto file which can not be parsed.
+type Error struct {
+	// File is name of the failed file, empty for parsed bytes.
+	File string
+
+	// Line and Column locate the problem, zero when unknown.
+	Line   int
+	Column int
+
+	// ImportedBy lists files importing the failed file, the first one imports it directly.
+	ImportedBy []string
+
+	// Message describes the problem.
+	Message string
+}
+
+// Error returns location of the problem and it's description (file.proto:12:3: found "}" but expected [;]).
+func (e *Error) Error() string {
+	location := e.File
+	if e.Line != 0 {
+		location = strings.TrimPrefix(fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column), ":")
+	}
+
+	msg := e.Message
+	if location != "" {
+		msg = location + ": " + msg
+	}
 
-	return parse(reader, importPath)
+	if len(e.ImportedBy) != 0 {
+		msg = fmt.Sprintf("%s (imported by %s)", msg, strings.Join(e.ImportedBy, " <- "))
+	}
+
+	return msg
+}
+
+// syntaxError converts parser error into Error, parser reports position as file:line:column prefix
+// (<input> is used for bytes).
+func syntaxError(file string, err error) *Error {
+	prefix := file
+	if prefix == "" {
+		prefix = "<input>"
+	}
+
+	msg := strings.TrimPrefix(err.Error(), prefix+":")
+
+	e := &Error{File: file, Message: msg}
+	if m := position.FindStringSubmatch(msg); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+		e.Column, _ = strconv.Atoi(m[2])
+		e.Message = m[3]
+	}
+
+	return e
+}
+
+// File parses given proto file or returns error. Imports are resolved against every import path in order
+// (same as protoc -I), imports of well-known types are skipped. Syntax errors of the file and it's imports and
+// imports which can not be resolved are reported as *Error.
+func File(file string, importPaths ...string) ([]Service, error) {
+	return parseFile(file, importPaths, nil)
+}
+
+// FileSkipping parses given proto file same as File, imports which can not be resolved are passed to the skip
+// function (as *Error pointing to the import statement) and skipped instead of failing the parse.
+func FileSkipping(file string, skip func(e *Error), importPaths ...string) ([]Service, error) {
+	return parseFile(file, importPaths, skip)
 }
 
 // Bytes parses string into proto definition.
 func Bytes(data []byte) ([]Service, error) {
-	return parse(bytes.NewBuffer(data), "")
+	return parse(bytes.NewBuffer(data), "", nil, nil)
 }
 
-func parse(reader io.Reader, importPath string) ([]Service, error) {
-	proto, err := pp.NewParser(reader).Parse()
+// parseFile parses proto file, unresolved imports are passed to the skip function or fail the parse when it's nil.
+func parseFile(file string, importPaths []string, skip func(e *Error)) ([]Service, error) {
+	reader, err := os.Open(file)
 	if err != nil {
 		return nil, err
 	}
+	defer reader.Close()
+
+	return parse(reader, file, importPaths, skip)
+}
+
+func parse(reader io.Reader, file string, importPaths []string, skip func(e *Error)) ([]Service, error) {
+	p := pp.NewParser(reader)
+	p.Filename(file)
+
+	proto, err := p.Parse()
+	if err != nil {
+		return nil, syntaxError(file, err)
+	}
 
 	return parseServices(
 		proto,
+		file,
 		parsePackage(proto),
-		importPath,
+		importPaths,
+		skip,
 	)
 }
 
+// resolveImport locates imported file in one of import paths.
+func resolveImport(name string, importPaths []string) (string, error) {
+	for _, dir := range importPaths {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+
+	return "", fmt.Errorf("unable to resolve import '%s' (searched in: %s)", name, strings.Join(importPaths, ", "))
+}
+
 func parsePackage(proto *pp.Proto) string {
 	for _, e := range proto.Elements {
 		if p, ok := e.(*pp.Package); ok {
@@ -73,7 +180,12 @@ func parsePackage(proto *pp.Proto) string {
 	return ""
 }
 
-func parseServices(proto *pp.Proto, pkg string, importPath string) ([]Service, error) {
+func parseServices(
+	proto *pp.Proto,
+	name, pkg string,
+	importPaths []string,
+	skip func(e *Error),
+) ([]Service, error) {
 	services := make([]Service, 0)
 
 	pp.Walk(proto, pp.WithService(func(service *pp.Service) {
@@ -84,13 +196,52 @@ func parseServices(proto *pp.Proto, pkg string, importPath string) ([]Service, e
 		})
 	}))
 
-	pp.Walk(proto, func(v pp.Visitee) {
-		if i, ok := v.(*pp.Import); ok {
-			if im, err := File(importPath+"/"+i.Filename, importPath); err == nil {
-				services = append(services, im...)
+	if len(importPaths) == 0 {
+		return services, nil
+	}
+
+	// skipped imports of imported files are reported along with the importing file
+	imported := skip
+	if skip != nil {
+		imported = func(e *Error) {
+			e.ImportedBy = append(e.ImportedBy, name)
+			skip(e)
+		}
+	}
+
+	for _, e := range proto.Elements {
+		i, ok := e.(*pp.Import)
+		if !ok {
+			continue
+		}
+
+		file, err := resolveImport(i.Filename, importPaths)
+		if err != nil {
+			// well-known types do not declare any services
+			if gproto.FileDescriptor(i.Filename) != nil {
+				continue
+			}
+
+			pe := &Error{File: name, Line: i.Position.Line, Column: i.Position.Column, Message: err.Error()}
+			if skip == nil {
+				return nil, pe
 			}
+
+			skip(pe)
+			continue
 		}
-	})
+
+		im, err := parseFile(file, importPaths, imported)
+		if err != nil {
+			if pe, ok := err.(*Error); ok {
+				pe.ImportedBy = append(pe.ImportedBy, name)
+			}
+
+			return nil, err
+		}
+
+		services = append(services, im...)
+	}
 
 	return services, nil
 }
@@ -2,6 +2,9 @@ package parser
 
 import (
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -68,3 +71,211 @@ message Message {
 
 	assert.Equal(t, "app.namespace", services[0].Package)
 }
+
+func TestParseImportPaths(t *testing.T) {
+	services, err := File("test_include/api/service.proto", "test_include/api", "test_include/shared")
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+
+	assert.Equal(t, "app.api", services[0].Package)
+	assert.Equal(t, "Api", services[0].Name)
+}
+
+func TestParseImportNotFound(t *testing.T) {
+	_, err := File("test_include/api/service.proto", "test_include/api")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "common/types.proto")
+	assert.Contains(t, err.Error(), "test_include/api")
+}
+
+func TestParseImportNotFound_Location(t *testing.T) {
+	_, err := File("test_include/api/service.proto", "test_include/api")
+
+	pe, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, "test_include/api/service.proto", pe.File)
+	assert.NotZero(t, pe.Line)
+}
+
+func TestFileSkipping(t *testing.T) {
+	skipped := make([]*Error, 0)
+	skip := func(e *Error) { skipped = append(skipped, e) }
+
+	services, err := FileSkipping("test_include/api/service.proto", skip, "test_include/api")
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	assert.Equal(t, "Api", services[0].Name)
+
+	if assert.Len(t, skipped, 1) {
+		assert.Equal(t, "test_include/api/service.proto", skipped[0].File)
+		assert.Equal(t, 4, skipped[0].Line)
+		assert.Contains(t, skipped[0].Error(), "common/types.proto")
+		assert.Contains(t, skipped[0].Error(), "test_include/api")
+	}
+
+	skipped = skipped[:0]
+	_, err = FileSkipping("test_include/api/service.proto", skip, "test_include/api", "test_include/shared")
+	assert.NoError(t, err)
+	assert.Len(t, skipped, 0)
+
+	// well-known types are resolved by the registry
+	_, err = FileSkipping("test_wellknown.proto", skip, ".")
+	assert.NoError(t, err)
+	assert.Len(t, skipped, 0)
+}
+
+func TestFileSkipping_ImportedBy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "unresolved")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "service.proto"), []byte(`syntax = "proto3";
+import "nested.proto";`), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "nested.proto"), []byte(`syntax = "proto3";
+import "missing.proto";`), 0644))
+
+	_, err = File(filepath.Join(dir, "service.proto"), dir)
+	if assert.IsType(t, &Error{}, err) {
+		assert.Equal(t, filepath.Join(dir, "nested.proto"), err.(*Error).File)
+		assert.Equal(t, []string{filepath.Join(dir, "service.proto")}, err.(*Error).ImportedBy)
+	}
+
+	skipped := make([]*Error, 0)
+	_, err = FileSkipping(filepath.Join(dir, "service.proto"), func(e *Error) { skipped = append(skipped, e) }, dir)
+	assert.NoError(t, err)
+	if assert.Len(t, skipped, 1) {
+		assert.Equal(t, filepath.Join(dir, "nested.proto"), skipped[0].File)
+		assert.Equal(t, []string{filepath.Join(dir, "service.proto")}, skipped[0].ImportedBy)
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	_, err := Bytes([]byte("syntax = \"proto3\";\nmessage Message { string msg = ; }"))
+
+	pe, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, "", pe.File)
+	assert.Equal(t, 2, pe.Line)
+	assert.Equal(t, 32, pe.Column)
+	assert.Equal(t, `2:32: found "=" but expected [field sequence number]`, pe.Error())
+}
+
+func TestParseFileSyntaxError(t *testing.T) {
+	_, err := File("test_invalid/broken.proto")
+
+	pe, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, "test_invalid/broken.proto", pe.File)
+	assert.Equal(t, 7, pe.Line)
+	assert.Equal(t, 1, pe.Column)
+	assert.Empty(t, pe.ImportedBy)
+	assert.Equal(t, `test_invalid/broken.proto:7:1: found "}" but expected [rpc type closing )]`, pe.Error())
+}
+
+func TestParseImportSyntaxError(t *testing.T) {
+	_, err := File("test_invalid/service.proto", "test_invalid")
+
+	pe, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, "test_invalid/broken.proto", pe.File)
+	assert.Equal(t, 7, pe.Line)
+	assert.Equal(t, []string{"test_invalid/service.proto"}, pe.ImportedBy)
+	assert.Contains(t, pe.Error(), "(imported by test_invalid/service.proto)")
+}
+
+func TestParseMalformedImport(t *testing.T) {
+	_, err := File("test_invalid/import.proto", "test_invalid")
+
+	pe, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, "test_invalid/import.proto", pe.File)
+	assert.Equal(t, 5, pe.Line)
+	assert.Equal(t, 14, pe.Column)
+}
+
+func TestParseMultipleServices(t *testing.T) {
+	services, err := File("test_multi.proto")
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+
+	assert.Equal(t, "app.multi", services[0].Package)
+	assert.Equal(t, "Users", services[0].Name)
+	assert.Len(t, services[0].Methods, 3)
+	assert.True(t, services[0].Methods[1].StreamsReturns)
+
+	assert.Equal(t, "app.multi", services[1].Package)
+	assert.Equal(t, "Orders", services[1].Name)
+	assert.Len(t, services[1].Methods, 2)
+	assert.True(t, services[1].Methods[1].StreamsRequest)
+}
+
+func TestParsePackage(t *testing.T) {
+	services, err := File("test_deep_package.proto")
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	assert.Equal(t, "com.acme.api.v1", services[0].Package)
+	assert.Equal(t, "com.acme.api.v1.Greeter", services[0].FullName())
+
+	services, err = File("test_no_package.proto")
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	assert.Equal(t, "", services[0].Package)
+	assert.Equal(t, "Greeter", services[0].FullName())
+}
+
+func TestParseOptionalOneof(t *testing.T) {
+	services, err := File("test_features.proto")
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+
+	assert.Equal(t, "app.features", services[0].Package)
+	assert.Equal(t, "Profiles", services[0].Name)
+	assert.Equal(t, []Method{
+		{Name: "Get", RequestType: "Request", ReturnsType: "Profile"},
+		{Name: "Update", RequestType: "Profile", ReturnsType: "Profile"},
+	}, services[0].Methods)
+}
+
+func TestParseWellKnownTypes(t *testing.T) {
+	services, err := File("test_wellknown.proto", ".")
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+
+	assert.Equal(t, "app.wellknown.Events", services[0].FullName())
+	assert.Equal(t, "google.protobuf.Empty", services[0].Methods[0].ReturnsType)
+}
+
+func TestParseStreams(t *testing.T) {
+	services, err := Bytes([]byte(`
+syntax = "proto3";
+package app.namespace;
+
+service StreamService {
+   rpc Unary (Message) returns (Message) {}
+   rpc Server (Message) returns (stream Message) {}
+   rpc Client (stream Message) returns (Message) {}
+   rpc Bidi (stream Message) returns (stream Message) {}
+}
+
+message Message {
+   string msg = 1;
+}
+`))
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+
+	methods := services[0].Methods
+	assert.Len(t, methods, 4)
+
+	assert.False(t, methods[0].StreamsRequest)
+	assert.False(t, methods[0].StreamsReturns)
+
+	assert.False(t, methods[1].StreamsRequest)
+	assert.True(t, methods[1].StreamsReturns)
+
+	assert.True(t, methods[2].StreamsRequest)
+	assert.False(t, methods[2].StreamsReturns)
+
+	assert.True(t, methods[3].StreamsRequest)
+	assert.True(t, methods[3].StreamsReturns)
+}
@@ -0,0 +1,95 @@
+package grpc
+
+import (
+	"github.com/spiral/roadrunner"
+)
+
+// defaultPool names the pool of Workers configuration in statistics once dedicated pools are configured.
+const defaultPool = "default"
+
+// workerPool is dedicated worker pool serving methods listed in it's configuration.
+type workerPool struct {
+	cfg MethodPool
+	rr  *roadrunner.Server
+}
+
+// servers returns servers of the default and dedicated worker pools, must be called under lock.
+func (svc *Service) servers() []*roadrunner.Server {
+	servers := []*roadrunner.Server{svc.rr}
+	for _, p := range svc.pools {
+		servers = append(servers, p.rr)
+	}
+
+	return servers
+}
+
+// startFailed starts servers which pools were destroyed by failure, must be called under lock.
+func (svc *Service) startFailed() error {
+	if svc.rr.Pool() == nil {
+		if err := svc.startServer(svc.rr, defaultPool); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range svc.pools {
+		if p.rr.Pool() == nil {
+			if err := svc.startServer(p.rr, p.cfg.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// startServer starts server of the named pool and reports started workers.
+func (svc *Service) startServer(rr *roadrunner.Server, pool string) error {
+	if err := rr.Start(); err != nil {
+		return err
+	}
+
+	svc.workersStarted(pool, rr.Workers())
+	return nil
+}
+
+// workerStates returns states of workers of every pool, workers are labeled by the pool name when dedicated
+// pools are configured.
+func (svc *Service) workerStates() ([]*WorkerState, []string, error) {
+	svc.mu.Lock()
+	names := []string{""}
+	servers := []*roadrunner.Server{svc.rr}
+	if len(svc.pools) != 0 {
+		names[0] = defaultPool
+		for _, p := range svc.pools {
+			names = append(names, p.cfg.Name)
+			servers = append(servers, p.rr)
+		}
+	}
+	svc.mu.Unlock()
+
+	var (
+		workers []*roadrunner.Worker
+		labels  []string
+	)
+	for i, rr := range servers {
+		for _, w := range rr.Workers() {
+			workers = append(workers, w)
+			labels = append(labels, names[i])
+		}
+	}
+
+	states, err := svc.errs.states(workers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, s := range states {
+		s.Pool = labels[i]
+	}
+
+	if len(names) == 1 {
+		return states, nil, nil
+	}
+
+	return states, names, nil
+}
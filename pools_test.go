@@ -0,0 +1,162 @@
+package grpc
+
+import (
+	"bytes"
+	"github.com/spf13/viper"
+	"github.com/spiral/roadrunner"
+	"github.com/spiral/roadrunner/util"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	ngrpc "google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"testing"
+	"time"
+)
+
+func Test_Config_Pools(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(bytes.NewBufferString(`
+pools:
+  - name: reports
+    methods: ["/app.Reports/*", "/app.Service/Export"]
+    command: "php reports.php"
+    pool:
+      numWorkers: 2
+      destroyTimeout: 5
+`)))
+
+	cfg := &Config{Workers: &roadrunner.ServerConfig{}}
+	cfg.Workers.InitDefaults()
+	assert.NoError(t, v.Unmarshal(cfg))
+	assert.Len(t, cfg.Pools, 1)
+
+	p := &cfg.Pools[0]
+	p.initDefaults(cfg.Workers)
+	assert.Equal(t, time.Minute, p.Pool.AllocateTimeout)
+	assert.Equal(t, 5*time.Second, p.Pool.DestroyTimeout)
+	assert.NoError(t, cfg.validPools())
+
+	assert.True(t, p.serves("/app.Reports/Monthly"))
+	assert.True(t, p.serves("/app.Service/Export"))
+	assert.False(t, p.serves("/app.Service/Get"))
+	assert.False(t, p.serves("/app.ReportsV2/Monthly"))
+
+	cfg.Workers.Command = "php worker.php"
+	cfg.Workers.SetEnv("key", "value")
+
+	sc := p.serverConfig(cfg.Workers)
+	assert.Equal(t, "php reports.php", sc.Command)
+	assert.Equal(t, int64(2), sc.Pool.NumWorkers)
+	assert.Equal(t, "php worker.php", cfg.Workers.Command)
+	assert.Equal(t, "php worker.php", (&MethodPool{Pool: p.Pool}).serverConfig(cfg.Workers).Command)
+
+	valid := &roadrunner.Config{NumWorkers: 1, AllocateTimeout: time.Second, DestroyTimeout: time.Second}
+	for name, pools := range map[string][]MethodPool{
+		"name":      {{Methods: []string{"/app.Service/Get"}, Pool: valid}},
+		"reserved":  {{Name: "default", Methods: []string{"/app.Service/Get"}, Pool: valid}},
+		"duplicate": {{Name: "a", Methods: []string{"/app.Service/Get"}, Pool: valid}, {Name: "a", Methods: []string{"/app.Service/Put"}, Pool: valid}},
+		"methods":   {{Name: "a", Pool: valid}},
+		"method":    {{Name: "a", Methods: []string{"Get"}, Pool: valid}},
+		"overlap":   {{Name: "a", Methods: []string{"/app.Service/Get"}, Pool: valid}, {Name: "b", Methods: []string{"/app.Service/Get"}, Pool: valid}},
+		"pool":      {{Name: "a", Methods: []string{"/app.Service/Get"}}},
+		"workers":   {{Name: "a", Methods: []string{"/app.Service/Get"}, Pool: &roadrunner.Config{}}},
+	} {
+		assert.Error(t, (&Config{Pools: pools}).validPools(), name)
+	}
+}
+
+func stateOf(pid int, execs int64) util.State {
+	return util.State{Pid: pid, Status: "ready", NumJobs: execs, Created: time.Now().UnixNano()}
+}
+
+func Test_PoolsStats(t *testing.T) {
+	now := time.Now()
+	states := []*WorkerState{
+		{State: stateOf(1, 10), Pool: "default"},
+		{State: stateOf(2, 5), Pool: "reports"},
+		{State: stateOf(3, 1), Pool: "reports"},
+	}
+
+	stats := poolsStats(states, []string{"default", "reports"}, now)
+	assert.Equal(t, 3, stats.Workers)
+	assert.Equal(t, int64(16), stats.Executions)
+	assert.Len(t, stats.Pools, 2)
+
+	assert.Equal(t, "default", stats.Pools[0].Name)
+	assert.Equal(t, 1, stats.Pools[0].Workers)
+	assert.Equal(t, "reports", stats.Pools[1].Name)
+	assert.Equal(t, int64(6), stats.Pools[1].Executions)
+
+	assert.Nil(t, poolsStats(states, nil, now).Pools)
+}
+
+func Test_Proxy_Pools(t *testing.T) {
+	main, dedicated := &roadrunner.Server{}, &roadrunner.Server{}
+
+	p := NewProxy("app.Service", "", main)
+	p.pools["Export"] = dedicated
+
+	assert.Equal(t, dedicated, p.server("Export"))
+	assert.Equal(t, main, p.server("Get"))
+}
+
+func Test_Service_Pools(t *testing.T) {
+	cfg := &Config{
+		Listen:  "tcp://127.0.0.1:9099",
+		Proto:   "parser/test.proto",
+		Workers: echoWorkers(1),
+		Pools: []MethodPool{{
+			Name:    "ping",
+			Methods: []string{"/app.namespace.PingService/*"},
+			Pool:    &roadrunner.Config{NumWorkers: 2, AllocateTimeout: time.Minute, DestroyTimeout: time.Minute},
+		}},
+	}
+
+	svc := &Service{}
+	ok, err := svc.Init(cfg, nil, nil)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	go func() { assert.NoError(t, svc.Serve()) }()
+	defer svc.Stop()
+
+	conn, err := ngrpc.Dial("127.0.0.1:9099", ngrpc.WithInsecure(), ngrpc.WithBlock(), ngrpc.WithTimeout(5*time.Second))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// echo worker responds with the request message
+	out := &healthpb.HealthCheckRequest{}
+	err = conn.Invoke(context.Background(), "/app.namespace.PingService/Ping", &healthpb.HealthCheckRequest{Service: "ping"}, out)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", out.Service)
+
+	rpc := &rpcServer{svc}
+	stats := &PoolStats{}
+	assert.NoError(t, rpc.Stats(true, stats))
+	assert.Equal(t, 3, stats.Workers)
+	assert.Equal(t, int64(1), stats.Executions)
+
+	if assert.Len(t, stats.Pools, 2) {
+		assert.Equal(t, "default", stats.Pools[0].Name)
+		assert.Equal(t, 1, stats.Pools[0].Workers)
+		assert.Equal(t, int64(0), stats.Pools[0].Executions)
+
+		assert.Equal(t, "ping", stats.Pools[1].Name)
+		assert.Equal(t, 2, stats.Pools[1].Workers)
+		assert.Equal(t, int64(1), stats.Pools[1].Executions)
+	}
+
+	list := &WorkerList{}
+	assert.NoError(t, rpc.Workers(true, list))
+	assert.Len(t, list.Workers, 3)
+
+	// every pool is reset
+	var r string
+	assert.NoError(t, rpc.Reset(true, &r))
+	assert.NoError(t, rpc.Stats(true, stats))
+	assert.Equal(t, 3, stats.Workers)
+	assert.Equal(t, int64(0), stats.Executions)
+}
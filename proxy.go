@@ -1,6 +1,7 @@
 package grpc
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/golang/protobuf/proto"
@@ -9,11 +10,14 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"net"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // base interface for Proxy class
@@ -21,15 +25,44 @@ type proxyService interface {
 	// RegisterMethod registers new RPC method.
 	RegisterMethod(method string)
 
+	// RegisterStream registers new streaming RPC method.
+	RegisterStream(method string, serverStreams, clientStreams bool)
+
 	// ServiceDesc returns service description for the proxy.
 	ServiceDesc() *grpc.ServiceDesc
 }
 
-// carry details about service, method and RPC context to PHP process
+// binarySuffix marks metadata keys carrying binary values.
+const binarySuffix = "-bin"
+
+// carry details about service, method and RPC context to PHP process. Context contains incoming metadata and proxy
+// provided values prefixed with colon (see README), values contain results of functions registered with AddValues.
 type rpcContext struct {
 	Service string              `json:"service"`
 	Method  string              `json:"method"`
 	Context map[string][]string `json:"context"`
+	Values  map[string]string   `json:"values,omitempty"`
+}
+
+// carry response headers and trailers set by PHP worker, values of binary metadata
+// (keys ending with -bin) must be base64 encoded, worker reports it's PID for traced calls
+type responseContext struct {
+	Headers  map[string][]string `json:"headers"`
+	Trailers map[string][]string `json:"trailers"`
+	PID      int                 `json:"pid"`
+}
+
+// carry worker response or error
+type execResult struct {
+	resp *roadrunner.Payload
+	err  error
+}
+
+// carry details about streaming RPC method
+type streamMethod struct {
+	name          string
+	serverStreams bool
+	clientStreams bool
 }
 
 // Proxy manages GRPC/RoadRunner bridge.
@@ -38,6 +71,50 @@ type Proxy struct {
 	name     string
 	metadata string
 	methods  []string
+	streams  []streamMethod
+	sf       *sessionFactory
+
+	// serve client streaming calls by session workers
+	streamSessions bool
+
+	// serve server streaming calls by pool workers
+	bufferedStreams bool
+
+	// methods served by session workers killed on cancellation
+	killOnCancel map[string]bool
+
+	// metadata key trusted to carry the client IP
+	forwardedHeader string
+
+	// recovers panics of interceptors set by server options
+	recovery *recovery
+
+	// functions computing call values
+	values []ValuesFunc
+
+	// maximal size of the request passed to the worker, zero means no limit
+	maxRequest int
+
+	// maximal execution time of methods, timeout applies to methods not listed
+	timeouts map[string]time.Duration
+	timeout  time.Duration
+
+	// idempotent methods retried on worker errors
+	retries       map[string]bool
+	retryAttempts int
+	retryBackoff  time.Duration
+
+	// notified once pool worker completes the call
+	executed func()
+
+	// notified about pool workers removed by the proxy and the reason of the removal
+	removed func(w *roadrunner.Worker, reason string)
+
+	// message types of validated methods
+	messages map[string]*methodMessages
+
+	// dedicated worker pools of methods, other methods are served by rr
+	pools map[string]*roadrunner.Server
 }
 
 // NewProxy creates new service proxy object.
@@ -47,6 +124,13 @@ func NewProxy(name string, metadata string, rr *roadrunner.Server) *Proxy {
 		name:     name,
 		metadata: metadata,
 		methods:  make([]string, 0),
+		streams:  make([]streamMethod, 0),
+
+		killOnCancel: make(map[string]bool),
+		timeouts:     make(map[string]time.Duration),
+		retries:      make(map[string]bool),
+		messages:     make(map[string]*methodMessages),
+		pools:        make(map[string]*roadrunner.Server),
 	}
 }
 
@@ -55,6 +139,15 @@ func (p *Proxy) RegisterMethod(method string) {
 	p.methods = append(p.methods, method)
 }
 
+// RegisterStream registers new streaming RPC method.
+func (p *Proxy) RegisterStream(method string, serverStreams, clientStreams bool) {
+	p.streams = append(p.streams, streamMethod{
+		name:          method,
+		serverStreams: serverStreams,
+		clientStreams: clientStreams,
+	})
+}
+
 // ServiceDesc returns service description for the proxy.
 func (p *Proxy) ServiceDesc() *grpc.ServiceDesc {
 	desc := &grpc.ServiceDesc{
@@ -73,6 +166,21 @@ func (p *Proxy) ServiceDesc() *grpc.ServiceDesc {
 		})
 	}
 
+	// Registering streams
+	for _, m := range p.streams {
+		handler := p.sessionHandler(m)
+		if (!m.serverStreams && !p.streamSessions) || (!m.clientStreams && p.bufferedStreams) {
+			handler = p.streamHandler(m)
+		}
+
+		desc.Streams = append(desc.Streams, grpc.StreamDesc{
+			StreamName:    m.name,
+			Handler:       handler,
+			ServerStreams: m.serverStreams,
+			ClientStreams: m.clientStreams,
+		})
+	}
+
 	return desc
 }
 
@@ -106,43 +214,240 @@ func (p *Proxy) methodHandler(method string) func(
 			return p.invoke(ctx, method, req.(rawMessage))
 		}
 
-		return interceptor(ctx, in, info, handler)
+		if p.recovery == nil {
+			return interceptor(ctx, in, info, handler)
+		}
+
+		// interceptor of grpc.UnaryInterceptor option is invoked before the recovery interceptor of the service
+		return p.recovery.unaryInterceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, handler)
+		})
 	}
 }
 
 func (p *Proxy) invoke(ctx context.Context, method string, in rawMessage) (interface{}, error) {
-	payload, err := p.makePayload(ctx, method, in)
+	resp, err := p.exec(ctx, method, in)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := p.rr.Exec(payload)
+	if msg := p.messages[method]; msg != nil && messageEncoding(ctx) == "proto" {
+		if err := msg.out.validate(resp.Body); err != nil {
+			return nil, status.Errorf(codes.Internal, "invalid response message: %s", err)
+		}
+	}
+
+	return respond(ctx, resp)
+}
 
+// respond sends response metadata and returns the response message. Worker responds with the message already
+// encoded by PHP (proto or json depending on :encoding), body is passed to the transport as is without
+// re-encoding (see codec).
+func respond(ctx context.Context, resp *roadrunner.Payload) (interface{}, error) {
+	header, trailer, err := responseMetadata(resp)
 	if err != nil {
-		return nil, wrapError(err)
+		return nil, err
+	}
+
+	if header.Len() != 0 {
+		if err := setHeader(ctx, header); err != nil {
+			return nil, err
+		}
+	}
+
+	if trailer.Len() != 0 {
+		if err := grpc.SetTrailer(ctx, trailer); err != nil {
+			return nil, err
+		}
 	}
 
 	return rawMessage(resp.Body), nil
 }
 
+// server returns server of the worker pool serving the method.
+func (p *Proxy) server(method string) *roadrunner.Server {
+	if rr, ok := p.pools[method]; ok {
+		return rr
+	}
+
+	return p.rr
+}
+
+// exec sends the message to the PHP worker and returns raw worker response.
+func (p *Proxy) exec(ctx context.Context, method string, in rawMessage) (*roadrunner.Payload, error) {
+	// do not waste worker capacity on calls abandoned by the client
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return nil, status.Error(codes.DeadlineExceeded, context.DeadlineExceeded.Error())
+	}
+
+	if p.maxRequest != 0 && len(in) > p.maxRequest {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"request size %d bytes exceeds the limit of %d bytes",
+			len(in),
+			p.maxRequest,
+		)
+	}
+
+	if msg := p.messages[method]; msg != nil && messageEncoding(ctx) == "proto" {
+		if err := msg.in.validate(in); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid request message: %s", err)
+		}
+	}
+
+	// server side limit of the execution time, workers of calls exceeding the limit are killed
+	parent, timeout := ctx, p.execTimeout(method)
+	if timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, timeout)
+		defer cancel()
+	}
+
+	payload, err := p.makePayload(ctx, method, in)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.killOnCancel[method] {
+		return p.sessionExec(ctx, payload)
+	}
+
+	rr := p.server(method)
+
+	var jobs map[*roadrunner.Worker]workerJob
+	if timeout != 0 {
+		jobs = workerJobs(rr)
+	}
+
+	// RoadRunner workers can not be interrupted, the proxy stops waiting for the response once the call is
+	// cancelled, worker completes the execution and returns to the pool (use KillOnCancel for long running calls).
+	// Workers of calls exceeding the method timeout are killed.
+	result := make(chan execResult, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := p.retry(ctx, method, func() (*roadrunner.Payload, error) {
+			return rr.Exec(payload)
+		})
+		result <- execResult{resp: resp, err: err}
+
+		if p.executed != nil {
+			p.executed()
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		if timeout != 0 && parent.Err() == nil {
+			go killRunaway(rr, jobs, timeout, done, p.removed)
+		}
+
+		return nil, status.FromContextError(ctx.Err()).Err()
+	case r := <-result:
+		if r.err != nil {
+			return nil, execError(r.err)
+		}
+
+		if s := spanFromContext(ctx); s != nil {
+			s.PID = workerPID(r.resp)
+		}
+
+		return r.resp, nil
+	}
+}
+
+// execError converts pool error into grpc status, worker crashes and pool failures are reported as Unavailable
+// (the pool replaces dead workers) while errors returned by the worker are mapped using wrapError.
+func execError(err error) error {
+	if _, ok := err.(roadrunner.JobError); ok {
+		return wrapError(err)
+	}
+
+	return status.Error(codes.Unavailable, err.Error())
+}
+
 // makePayload generates RoadRunner compatible payload based on GRPC message. todo: return error
 func (p *Proxy) makePayload(ctx context.Context, method string, body rawMessage) (*roadrunner.Payload, error) {
-	ctxMD := make(map[string][]string)
+	ctxMD := map[string][]string{
+		":service": {p.name},
+		":method":  {method},
+	}
 
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		for k, v := range md {
-			ctxMD[k] = v
+			ctxMD[k] = encodeMetadata(k, v)
+		}
+
+		// message encoding, messages are forwarded to the worker as is
+		ctxMD[":encoding"] = []string{contentSubtype(md.Get("content-type"))}
+	}
+
+	// call deadline as unix timestamp in milliseconds
+	if deadline, ok := ctx.Deadline(); ok {
+		ctxMD[":deadline"] = []string{strconv.FormatInt(deadline.UnixNano()/int64(time.Millisecond), 10)}
+	}
+
+	// trace context for the worker to continue the trace
+	if s := spanFromContext(ctx); s != nil {
+		ctxMD[":trace.id"] = []string{s.TraceID}
+		ctxMD[":span.id"] = []string{s.SpanID}
+		ctxMD[":traceparent"] = []string{s.Traceparent()}
+
+		if s.State != "" {
+			ctxMD[":tracestate"] = []string{s.State}
 		}
 	}
 
+	// identity of the caller authenticated by JWT
+	if c := claimsFromContext(ctx); c != nil {
+		ctxMD[":auth.subject"] = []string{c.Subject}
+		ctxMD[":auth.scopes"] = c.scopes()
+	}
+
 	if pr, ok := peer.FromContext(ctx); ok {
-		ctxMD[":peer.address"] = []string{pr.Addr.String()}
+		// unix socket peers might not have an address
+		if pr.Addr != nil {
+			ctxMD[":peer.address"] = []string{pr.Addr.String()}
+
+			if host, _, err := net.SplitHostPort(pr.Addr.String()); err == nil {
+				ctxMD[":peer.ip"] = []string{host}
+				ctxMD[":client.ip"] = []string{host}
+			}
+		}
+
 		if pr.AuthInfo != nil {
 			ctxMD[":peer.auth-type"] = []string{pr.AuthInfo.AuthType()}
 		}
+
+		ctxMD[":peer.tls"] = []string{"false"}
+		if info, ok := pr.AuthInfo.(credentials.TLSInfo); ok {
+			ctxMD[":peer.tls"] = []string{"true"}
+			ctxMD[":peer.tls-version"] = []string{tlsVersionName(info.State.Version)}
+			ctxMD[":peer.protocol"] = []string{info.State.NegotiatedProtocol}
+
+			// verified client certificate (mutual TLS)
+			if len(info.State.VerifiedChains) != 0 {
+				ctxMD[":peer.subject"] = []string{info.State.VerifiedChains[0][0].Subject.String()}
+				ctxMD[":peer.cn"] = []string{info.State.VerifiedChains[0][0].Subject.CommonName}
+			}
+		}
+	}
+
+	// trusted proxy header overrides the peer IP
+	if ip := forwardedIP(ctx, p.forwardedHeader); ip != "" {
+		ctxMD[":client.ip"] = []string{ip}
+	}
+
+	values, err := callValues(ctx, p.values)
+	if err != nil {
+		return nil, err
 	}
 
-	ctxData, err := json.Marshal(rpcContext{Service: p.name, Method: method, Context: ctxMD})
+	ctxData, err := json.Marshal(rpcContext{Service: p.name, Method: method, Context: ctxMD, Values: values})
 
 	if err != nil {
 		return nil, err
@@ -151,7 +456,121 @@ func (p *Proxy) makePayload(ctx context.Context, method string, body rawMessage)
 	return &roadrunner.Payload{Context: ctxData, Body: body}, nil
 }
 
-// mounts proper error code for the error
+// forwardedIP returns the first address of the given metadata key ("x-forwarded-for: client, proxy1, proxy2"),
+// empty when key is not configured or not present.
+func forwardedIP(ctx context.Context, key string) string {
+	if key == "" {
+		return ""
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, v := range md.Get(key) {
+		if ip := strings.TrimSpace(strings.Split(v, ",")[0]); ip != "" {
+			return ip
+		}
+	}
+
+	return ""
+}
+
+// messageEncoding returns encoding of the call messages (proto, json).
+func messageEncoding(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return contentSubtype(md.Get("content-type"))
+}
+
+// contentSubtype returns message encoding based on request content type (application/grpc+json),
+// defaults to proto.
+func contentSubtype(contentType []string) string {
+	if len(contentType) == 0 {
+		return "proto"
+	}
+
+	ct := strings.ToLower(strings.TrimPrefix(contentType[0], "application/grpc"))
+	if i := strings.Index(ct, ";"); i != -1 {
+		ct = ct[:i]
+	}
+
+	if !strings.HasPrefix(ct, "+") || len(ct) == 1 {
+		return "proto"
+	}
+
+	return ct[1:]
+}
+
+// encodeMetadata encodes binary metadata values (keys with -bin suffix) using base64 so they can
+// safely pass JSON encoding.
+func encodeMetadata(key string, values []string) []string {
+	if !strings.HasSuffix(key, binarySuffix) {
+		return values
+	}
+
+	encoded := make([]string, 0, len(values))
+	for _, v := range values {
+		encoded = append(encoded, base64.StdEncoding.EncodeToString([]byte(v)))
+	}
+
+	return encoded
+}
+
+// responseMetadata reads response headers and trailers from worker response context.
+func responseMetadata(resp *roadrunner.Payload) (header, trailer metadata.MD, err error) {
+	if len(resp.Context) == 0 {
+		return nil, nil, nil
+	}
+
+	rc := responseContext{}
+	if err := json.Unmarshal(resp.Context, &rc); err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "invalid response context: %s", err)
+	}
+
+	if header, err = decodeMetadata(rc.Headers); err != nil {
+		return nil, nil, err
+	}
+
+	if trailer, err = decodeMetadata(rc.Trailers); err != nil {
+		return nil, nil, err
+	}
+
+	return header, trailer, nil
+}
+
+// decodeMetadata creates metadata from worker provided values, binary values (keys with -bin suffix)
+// are decoded from base64 (padded or unpadded).
+func decodeMetadata(values map[string][]string) (metadata.MD, error) {
+	md := metadata.MD{}
+	for k, v := range values {
+		k = strings.ToLower(k)
+		if !strings.HasSuffix(k, binarySuffix) {
+			md.Append(k, v...)
+			continue
+		}
+
+		for _, value := range v {
+			enc := base64.StdEncoding
+			if len(value)%4 != 0 {
+				enc = base64.RawStdEncoding
+			}
+
+			data, err := enc.DecodeString(value)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "invalid binary metadata `%s`: %s", k, err)
+			}
+
+			md.Append(k, string(data))
+		}
+	}
+
+	return md, nil
+}
+
+// mounts proper error code for the error, worker reports structured errors as
+// "code|:|message|:|details|:|details..." where every details chunk is serialized google.protobuf.Any.
+// Unstructured errors and errors with invalid code fallback to codes.Internal.
 func wrapError(err error) error {
 	// internal agreement
 	if strings.Index(err.Error(), "|:|") != -1 {
@@ -159,7 +578,7 @@ func wrapError(err error) error {
 		code := codes.Internal
 
 		if phpCode, err := strconv.Atoi(chunks[0]); err == nil {
-			code = codes.Code(phpCode)
+			code = errorCode(phpCode)
 		}
 
 		st := status.New(code, chunks[1]).Proto()
@@ -180,3 +599,12 @@ func wrapError(err error) error {
 
 	return status.Error(codes.Internal, err.Error())
 }
+
+// errorCode converts worker error code into grpc code, unknown codes (and OK) fallback to codes.Internal.
+func errorCode(code int) codes.Code {
+	if code <= int(codes.OK) || code > int(codes.Unauthenticated) {
+		return codes.Internal
+	}
+
+	return codes.Code(code)
+}
@@ -1,17 +1,33 @@
 package grpc
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiral/php-grpc/tests"
+	"github.com/spiral/roadrunner"
 	"github.com/spiral/roadrunner/service"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"net"
+	"strconv"
 	"testing"
 	"time"
+	"unicode/utf8"
 )
 
 func Test_Proxy_Error(t *testing.T) {
@@ -126,3 +142,440 @@ func Test_Proxy_Metadata(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, `["proxy-value"]`, out.Msg)
 }
+
+func Test_Proxy_Payload_Metadata(t *testing.T) {
+	p := NewProxy("app.Service", "", nil)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"key", "value",
+		"Authorization", "Bearer token",
+		"x-trace-id", "1", "x-trace-id", "2",
+		"trace-bin", string([]byte{0, 255, 1}),
+	))
+
+	payload, err := p.makePayload(ctx, "Method", rawMessage("body"))
+	assert.NoError(t, err)
+	assert.Equal(t, "body", string(payload.Body))
+
+	rc := rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+
+	assert.Equal(t, "app.Service", rc.Service)
+	assert.Equal(t, "Method", rc.Method)
+	assert.Equal(t, []string{"app.Service"}, rc.Context[":service"])
+	assert.Equal(t, []string{"Method"}, rc.Context[":method"])
+	assert.Equal(t, []string{"value"}, rc.Context["key"])
+	assert.Equal(t, []string{"Bearer token"}, rc.Context["authorization"])
+	assert.Equal(t, []string{"1", "2"}, rc.Context["x-trace-id"])
+	assert.Equal(t, []string{"AP8B"}, rc.Context["trace-bin"])
+	assert.Equal(t, []string{"proto"}, rc.Context[":encoding"])
+}
+
+func Test_Proxy_ResponseMetadata(t *testing.T) {
+	header, trailer, err := responseMetadata(&roadrunner.Payload{
+		Context: []byte(`{"headers":{"X-Rate-Limit":["10"]},"trailers":{"next-page":["2"],"token-bin":["AP8B"]}}`),
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"10"}, header.Get("x-rate-limit"))
+	assert.Equal(t, []string{"2"}, trailer.Get("next-page"))
+	assert.Equal(t, []string{string([]byte{0, 255, 1})}, trailer.Get("token-bin"))
+}
+
+func Test_Proxy_Metadata_RoundTrip(t *testing.T) {
+	values := map[string][]string{
+		"token-bin": {string([]byte{0, 0, 1}), string([]byte{0xff, 0xfe, 0x00, 'a'}), ""},
+		"trace-bin": {"\x00"},
+		"x-name":    {"привет", "plain"},
+	}
+
+	// metadata received from the client is sent back by the worker as is
+	encoded := make(map[string][]string)
+	for k, v := range values {
+		encoded[k] = encodeMetadata(k, v)
+	}
+
+	assert.Equal(t, []string{"привет", "plain"}, encoded["x-name"])
+	for _, v := range encoded["token-bin"] {
+		assert.True(t, utf8.ValidString(v))
+	}
+
+	data, err := json.Marshal(responseContext{Headers: encoded, Trailers: encoded})
+	assert.NoError(t, err)
+
+	header, trailer, err := responseMetadata(&roadrunner.Payload{Context: data})
+	assert.NoError(t, err)
+
+	for k, v := range values {
+		assert.Equal(t, v, header.Get(k), k)
+		assert.Equal(t, v, trailer.Get(k), k)
+	}
+}
+
+func Test_Proxy_ResponseMetadata_Unpadded(t *testing.T) {
+	_, trailer, err := responseMetadata(&roadrunner.Payload{
+		Context: []byte(`{"trailers":{"token-bin":["AP8", "AP8B", "AA=="]}}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		string([]byte{0, 255}),
+		string([]byte{0, 255, 1}),
+		string([]byte{0}),
+	}, trailer.Get("token-bin"))
+}
+
+func Test_Proxy_ResponseMetadata_Empty(t *testing.T) {
+	header, trailer, err := responseMetadata(&roadrunner.Payload{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, header.Len())
+	assert.Equal(t, 0, trailer.Len())
+}
+
+func Test_Proxy_ResponseMetadata_Invalid(t *testing.T) {
+	_, _, err := responseMetadata(&roadrunner.Payload{Context: []byte(`{"trailers":{"token-bin":["!!"]}}`)})
+	assert.Error(t, err)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, _, err = responseMetadata(&roadrunner.Payload{Context: []byte(`{`)})
+	assert.Error(t, err)
+}
+
+func Test_Proxy_Payload_Peer(t *testing.T) {
+	p := NewProxy("app.Service", "", nil)
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client", Organization: []string{"Spiral"}}}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9001},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			Version:            tls.VersionTLS13,
+			NegotiatedProtocol: "h2",
+			VerifiedChains:     [][]*x509.Certificate{{cert}},
+		}},
+	})
+
+	payload, err := p.makePayload(ctx, "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc := rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+
+	assert.Equal(t, []string{"127.0.0.1:9001"}, rc.Context[":peer.address"])
+	assert.Equal(t, []string{"127.0.0.1"}, rc.Context[":peer.ip"])
+	assert.Equal(t, []string{"127.0.0.1"}, rc.Context[":client.ip"])
+	assert.Equal(t, []string{"tls"}, rc.Context[":peer.auth-type"])
+	assert.Equal(t, []string{"CN=client,O=Spiral"}, rc.Context[":peer.subject"])
+	assert.Equal(t, []string{"client"}, rc.Context[":peer.cn"])
+	assert.Equal(t, []string{"true"}, rc.Context[":peer.tls"])
+	assert.Equal(t, []string{"1.3"}, rc.Context[":peer.tls-version"])
+	assert.Equal(t, []string{"h2"}, rc.Context[":peer.protocol"])
+}
+
+func Test_Proxy_Payload_ForwardedIP(t *testing.T) {
+	p := NewProxy("app.Service", "", nil)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9001}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", "203.0.113.7, 10.0.0.1"))
+
+	payload, err := p.makePayload(ctx, "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc := rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+
+	// header is not trusted unless configured
+	assert.Equal(t, []string{"10.0.0.1"}, rc.Context[":client.ip"])
+
+	p.forwardedHeader = "X-Forwarded-For"
+	payload, err = p.makePayload(ctx, "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc = rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+
+	assert.Equal(t, []string{"203.0.113.7"}, rc.Context[":client.ip"])
+	assert.Equal(t, []string{"10.0.0.1"}, rc.Context[":peer.ip"])
+}
+
+func Test_Proxy_Payload_UnixPeer(t *testing.T) {
+	p := NewProxy("app.Service", "", nil)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.UnixAddr{Name: "@", Net: "unix"}})
+
+	payload, err := p.makePayload(ctx, "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc := rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+
+	assert.Equal(t, []string{"@"}, rc.Context[":peer.address"])
+	assert.Equal(t, []string{"false"}, rc.Context[":peer.tls"])
+
+	// no address at all
+	_, err = p.makePayload(peer.NewContext(context.Background(), &peer.Peer{}), "Method", rawMessage("body"))
+	assert.NoError(t, err)
+}
+
+func Test_Proxy_Payload_Deadline(t *testing.T) {
+	p := NewProxy("app.Service", "", nil)
+
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	payload, err := p.makePayload(ctx, "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc := rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+
+	assert.Equal(t, []string{strconv.FormatInt(deadline.UnixNano()/int64(time.Millisecond), 10)}, rc.Context[":deadline"])
+}
+
+func Test_Proxy_Exec_Expired(t *testing.T) {
+	// no worker server, call must be rejected before dispatching
+	p := NewProxy("app.Service", "", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+
+	_, err := p.exec(ctx, "Method", rawMessage("body"))
+	assert.Error(t, err)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	_, err = p.exec(ctx, "Method", rawMessage("body"))
+	assert.Error(t, err)
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
+
+func Test_Proxy_MaxRequest(t *testing.T) {
+	// no worker server, call must be rejected before dispatching
+	p := NewProxy("app.Service", "", nil)
+	p.maxRequest = 4
+
+	_, err := p.exec(context.Background(), "Method", rawMessage("large body"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "request size 10 bytes exceeds the limit of 4 bytes")
+}
+
+func Test_Proxy_ExecTimeout(t *testing.T) {
+	p := NewProxy("app.Service", "", nil)
+	assert.Equal(t, time.Duration(0), p.execTimeout("Method"))
+
+	p.timeout = time.Second
+	p.timeouts["Report"] = time.Minute
+
+	assert.Equal(t, time.Second, p.execTimeout("Method"))
+	assert.Equal(t, time.Minute, p.execTimeout("Report"))
+}
+
+func Test_Proxy_Retry(t *testing.T) {
+	p := NewProxy("app.Service", "", nil)
+	p.retries["Get"] = true
+	p.retryAttempts, p.retryBackoff = 2, time.Millisecond
+
+	calls := 0
+	exec := func(errs ...error) func() (*roadrunner.Payload, error) {
+		calls = 0
+		return func() (*roadrunner.Payload, error) {
+			calls++
+			if calls <= len(errs) {
+				return nil, errs[calls-1]
+			}
+
+			return &roadrunner.Payload{Body: []byte("ok")}, nil
+		}
+	}
+
+	// worker errors are retried
+	resp, err := p.retry(context.Background(), "Get", exec(errors.New("worker crashed")))
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(resp.Body))
+	assert.Equal(t, 2, calls)
+
+	// number of retries is limited
+	_, err = p.retry(context.Background(), "Get", exec(errors.New("a"), errors.New("b"), errors.New("c")))
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls)
+
+	// application errors are not retried
+	_, err = p.retry(context.Background(), "Get", exec(roadrunner.JobError("5|:|not found")))
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+
+	// methods which are not idempotent are not retried
+	_, err = p.retry(context.Background(), "Create", exec(errors.New("worker crashed")))
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+
+	// retry must not exceed the deadline
+	p.retryBackoff = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	_, err = p.retry(ctx, "Get", exec(errors.New("worker crashed")))
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func Test_Proxy_WrapError(t *testing.T) {
+	details, err := ptypes.MarshalAny(&any.Any{TypeUrl: "type.googleapis.com/test", Value: []byte("value")})
+	assert.NoError(t, err)
+
+	data, err := proto.Marshal(details)
+	assert.NoError(t, err)
+
+	st := status.Convert(wrapError(errors.New("3|:|invalid argument|:|" + string(data))))
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "invalid argument", st.Message())
+	assert.Len(t, st.Proto().Details, 1)
+
+	assert.Equal(t, codes.Internal, status.Code(wrapError(errors.New("99|:|out of range"))))
+	assert.Equal(t, codes.Internal, status.Code(wrapError(errors.New("0|:|ok is not an error"))))
+	assert.Equal(t, codes.Internal, status.Code(wrapError(errors.New("broken|:|not a code"))))
+	assert.Equal(t, codes.Internal, status.Code(wrapError(errors.New("worker error"))))
+}
+
+func Test_Proxy_ExecError(t *testing.T) {
+	assert.Equal(t, codes.NotFound, status.Code(execError(roadrunner.JobError("5|:|not found"))))
+	assert.Equal(t, codes.Internal, status.Code(execError(roadrunner.JobError("uncaught exception"))))
+	assert.Equal(t, codes.Unavailable, status.Code(execError(errors.New("worker timeout"))))
+	assert.Equal(t, codes.Unavailable, status.Code(execError(roadrunner.WorkerError{Caused: errors.New("broken pipe")})))
+}
+
+func Test_Proxy_ContentSubtype(t *testing.T) {
+	assert.Equal(t, "proto", contentSubtype(nil))
+	assert.Equal(t, "proto", contentSubtype([]string{"application/grpc"}))
+	assert.Equal(t, "proto", contentSubtype([]string{"application/grpc+"}))
+	assert.Equal(t, "proto", contentSubtype([]string{"application/grpc+proto"}))
+	assert.Equal(t, "json", contentSubtype([]string{"application/grpc+json"}))
+	assert.Equal(t, "json", contentSubtype([]string{"application/grpc+JSON; charset=utf-8"}))
+}
+
+func Test_Proxy_RawMessages(t *testing.T) {
+	p := NewProxy("app.Service", "", nil)
+	p.RegisterMethod("Method")
+
+	// fields out of order and unknown field 15, any re-encoding would change the bytes
+	request := []byte{0x10, 0x01, 0x0a, 0x02, 'h', 'i', 0x7a, 0x01, 0x00}
+	response := []byte{0x10, 0x02, 0x7a, 0x00, 0x0a, 0x03, 0xff, 0x00, 0xfe}
+
+	// worker is replaced with interceptor responding with pre-encoded message
+	received := make(chan []byte, 1)
+	server := grpc.NewServer(
+		grpc.CustomCodec(&codec{encoding.GetCodec("proto")}),
+		grpc.UnaryInterceptor(func(
+			ctx context.Context,
+			req interface{},
+			info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			received <- req.(rawMessage)
+			return respond(ctx, &roadrunner.Payload{Body: response})
+		}),
+	)
+	server.RegisterService(p.ServiceDesc(), p)
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+
+	go server.Serve(ln)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	out := rawMessage{}
+	err = conn.Invoke(
+		context.Background(),
+		"/app.Service/Method",
+		rawMessage(request),
+		&out,
+		grpc.CallCustomCodec(&codec{encoding.GetCodec("proto")}),
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, request, []byte(<-received))
+	assert.Equal(t, response, []byte(out))
+}
+
+func Test_Proxy_JSON(t *testing.T) {
+	encoding.RegisterCodec(jsonCodec{})
+
+	p := NewProxy("app.Service", "", nil)
+	p.RegisterMethod("Method")
+
+	// worker is replaced with interceptor responding with the payload context
+	server := grpc.NewServer(
+		grpc.CustomCodec(&codec{encoding.GetCodec("proto")}),
+		grpc.UnaryInterceptor(func(
+			ctx context.Context,
+			req interface{},
+			info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			payload, err := p.makePayload(ctx, "Method", req.(rawMessage))
+			if err != nil {
+				return nil, err
+			}
+
+			return rawMessage(payload.Context), nil
+		}),
+	)
+	server.RegisterService(p.ServiceDesc(), p)
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+
+	go server.Serve(ln)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	rc := rpcContext{}
+	err = conn.Invoke(
+		context.Background(),
+		"/app.Service/Method",
+		map[string]string{"msg": "ping"},
+		&rc,
+		grpc.CallContentSubtype("json"),
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Method", rc.Method)
+	assert.Equal(t, []string{"json"}, rc.Context[":encoding"])
+}
+
+func Test_Proxy_ServiceDesc_Streams(t *testing.T) {
+	p := NewProxy("app.Service", "service.proto", nil)
+	p.RegisterMethod("Unary")
+	p.RegisterStream("Server", true, false)
+	p.RegisterStream("Client", false, true)
+	p.RegisterStream("Bidi", true, true)
+
+	desc := p.ServiceDesc()
+	assert.Equal(t, "app.Service", desc.ServiceName)
+	assert.Equal(t, "service.proto", desc.Metadata)
+
+	assert.Len(t, desc.Methods, 1)
+	assert.Equal(t, "Unary", desc.Methods[0].MethodName)
+
+	assert.Len(t, desc.Streams, 3)
+	assert.Equal(t, "Server", desc.Streams[0].StreamName)
+	assert.True(t, desc.Streams[0].ServerStreams)
+	assert.False(t, desc.Streams[0].ClientStreams)
+
+	assert.Equal(t, "Client", desc.Streams[1].StreamName)
+	assert.False(t, desc.Streams[1].ServerStreams)
+	assert.True(t, desc.Streams[1].ClientStreams)
+
+	assert.Equal(t, "Bidi", desc.Streams[2].StreamName)
+	assert.True(t, desc.Streams[2].ServerStreams)
+	assert.True(t, desc.Streams[2].ClientStreams)
+}
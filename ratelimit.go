@@ -0,0 +1,243 @@
+package grpc
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateUnits defines supported rate limit periods.
+var rateUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// parseRate parses rate limit in form of "1000/s" (also "/m" and "/h"), limit defines both the number of calls
+// allowed per period and the maximal burst.
+func parseRate(limit string) (count int, period time.Duration, err error) {
+	parts := strings.Split(strings.TrimSpace(limit), "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid rate limit '%s'", limit)
+	}
+
+	period, ok := rateUnits[strings.ToLower(strings.TrimSpace(parts[1]))]
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid rate limit period '%s'", limit)
+	}
+
+	count, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || count <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate limit '%s'", limit)
+	}
+
+	return count, period, nil
+}
+
+// bucket holds tokens available for the single key.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter implements token bucket limit for set of keys.
+type limiter struct {
+	mu      sync.Mutex
+	burst   float64
+	rate    float64 // tokens per nanosecond
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// newLimiter creates limiter allowing count calls per period for every key.
+func newLimiter(count int, period time.Duration) *limiter {
+	return &limiter{
+		burst:   float64(count),
+		rate:    float64(count) / float64(period),
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+	}
+}
+
+// allow consumes one token of the key, returns false if no tokens left.
+func (l *limiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// refill returns number of tokens available in bucket at given time.
+func (l *limiter) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + float64(now.Sub(b.last))*l.rate
+	if tokens > l.burst {
+		return l.burst
+	}
+
+	return tokens
+}
+
+// sweep removes full buckets (keys idle for long enough) once per refill period to keep memory bounded.
+func (l *limiter) sweep(now time.Time) {
+	if float64(now.Sub(l.swept)) < l.burst/l.rate {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.swept = now
+}
+
+// methodLimiter limits calls of the method, separately for every client when perClient is set.
+type methodLimiter struct {
+	*limiter
+	perClient bool
+}
+
+// rateLimiter rejects calls exceeding method or client limits with ResourceExhausted status.
+type rateLimiter struct {
+	methods   map[string]*methodLimiter
+	all       *methodLimiter
+	client    *limiter
+	clientKey string
+}
+
+// newRateLimiter creates rate limiter based on given configuration.
+func newRateLimiter(cfg RateLimit) (*rateLimiter, error) {
+	rl := &rateLimiter{methods: make(map[string]*methodLimiter), clientKey: strings.ToLower(cfg.ClientKey)}
+
+	for _, m := range cfg.Methods {
+		count, period, err := parseRate(m.Limit)
+		if err != nil {
+			return nil, err
+		}
+
+		ml := &methodLimiter{limiter: newLimiter(count, period), perClient: m.PerClient}
+		if m.Burst != 0 {
+			ml.burst = float64(m.Burst)
+		}
+
+		if m.Method == "*" {
+			rl.all = ml
+			continue
+		}
+
+		rl.methods[m.Method] = ml
+	}
+
+	if cfg.Client != "" {
+		count, period, err := parseRate(cfg.Client)
+		if err != nil {
+			return nil, err
+		}
+
+		rl.client = newLimiter(count, period)
+	}
+
+	return rl, nil
+}
+
+// allow checks limits of the call.
+func (rl *rateLimiter) allow(ctx context.Context, method string) error {
+	l, ok := rl.methods[method]
+	if !ok {
+		l = rl.all
+	}
+
+	if l != nil {
+		key := method
+		if l.perClient {
+			key = method + " " + rl.clientID(ctx)
+		}
+
+		if !l.allow(key) {
+			return status.Errorf(codes.ResourceExhausted, "rate limit of method %s exceeded", method)
+		}
+	}
+
+	if rl.client != nil && !rl.client.allow(rl.clientID(ctx)) {
+		return status.Error(codes.ResourceExhausted, "client rate limit exceeded")
+	}
+
+	return nil
+}
+
+// clientID identifies the client using configured metadata key or peer IP address.
+func (rl *rateLimiter) clientID(ctx context.Context) string {
+	if rl.clientKey != "" {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(rl.clientKey); len(v) != 0 {
+				return v[0]
+			}
+		}
+	}
+
+	pr, ok := peer.FromContext(ctx)
+	if !ok || pr.Addr == nil {
+		return ""
+	}
+
+	if host, _, err := net.SplitHostPort(pr.Addr.String()); err == nil {
+		return host
+	}
+
+	return pr.Addr.String()
+}
+
+// unaryInterceptor limits unary calls.
+func (rl *rateLimiter) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if err := rl.allow(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// streamInterceptor limits streaming calls.
+func (rl *rateLimiter) streamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := rl.allow(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
@@ -0,0 +1,178 @@
+package grpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_ParseRate(t *testing.T) {
+	count, period, err := parseRate("1000/s")
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, count)
+	assert.Equal(t, time.Second, period)
+
+	count, period, err = parseRate(" 60 / M ")
+	assert.NoError(t, err)
+	assert.Equal(t, 60, count)
+	assert.Equal(t, time.Minute, period)
+
+	for _, limit := range []string{"", "100", "100/d", "0/s", "-1/s", "a/s", "1/s/s"} {
+		_, _, err = parseRate(limit)
+		assert.Error(t, err, limit)
+	}
+}
+
+func Test_Limiter_Refill(t *testing.T) {
+	l := newLimiter(2, time.Millisecond*50)
+
+	assert.True(t, l.allow("a"))
+	assert.True(t, l.allow("a"))
+	assert.False(t, l.allow("a"))
+
+	// other keys have own buckets
+	assert.True(t, l.allow("b"))
+
+	time.Sleep(time.Millisecond * 30)
+	assert.True(t, l.allow("a"))
+	assert.False(t, l.allow("a"))
+}
+
+func Test_Limiter_Sweep(t *testing.T) {
+	l := newLimiter(1, time.Millisecond*10)
+
+	assert.True(t, l.allow("a"))
+	assert.True(t, l.allow("b"))
+	assert.Len(t, l.buckets, 2)
+
+	time.Sleep(time.Millisecond * 20)
+	assert.True(t, l.allow("c"))
+	assert.Len(t, l.buckets, 1)
+}
+
+func Test_Limiter_Concurrent(t *testing.T) {
+	l := newLimiter(100, time.Hour)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if l.allow("key") {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 100, allowed)
+}
+
+func Test_RateLimiter_Methods(t *testing.T) {
+	rl, err := newRateLimiter(RateLimit{Methods: []MethodLimit{
+		{Method: "/app.Service/Limited", Limit: "1/h"},
+		{Method: "*", Limit: "2/h"},
+	}})
+	assert.NoError(t, err)
+
+	called := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called++
+		return nil, nil
+	}
+
+	call := func(method string) error {
+		_, err := rl.unaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	assert.NoError(t, call("/app.Service/Limited"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("/app.Service/Limited")))
+
+	// every other method has own limit
+	assert.NoError(t, call("/app.Service/A"))
+	assert.NoError(t, call("/app.Service/A"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("/app.Service/A")))
+	assert.NoError(t, call("/app.Service/B"))
+
+	assert.Equal(t, 4, called)
+}
+
+func Test_RateLimiter_Client(t *testing.T) {
+	rl, err := newRateLimiter(RateLimit{Client: "1/h", ClientKey: "X-Api-Key"})
+	assert.NoError(t, err)
+
+	info := &grpc.StreamServerInfo{FullMethod: "/app.Service/Stream"}
+	handler := func(srv interface{}, stream grpc.ServerStream) error { return nil }
+
+	call := func(ctx context.Context) error {
+		return rl.streamInterceptor(nil, &mockStream{ctx: ctx}, info, handler)
+	}
+
+	withPeer := func(ip string, port int) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: port}})
+	}
+
+	// clients identified by IP address regardless of connection port
+	assert.NoError(t, call(withPeer("10.0.0.1", 1000)))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call(withPeer("10.0.0.1", 2000))))
+	assert.NoError(t, call(withPeer("10.0.0.2", 1000)))
+
+	// metadata key takes priority over peer address
+	ctx := metadata.NewIncomingContext(withPeer("10.0.0.1", 1000), metadata.Pairs("x-api-key", "client-a"))
+	assert.NoError(t, call(ctx))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call(ctx)))
+}
+
+func Test_RateLimiter_Burst(t *testing.T) {
+	rl, err := newRateLimiter(RateLimit{Methods: []MethodLimit{
+		{Method: "/app.Service/Burst", Limit: "1/h", Burst: 3},
+	}})
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, rl.allow(context.Background(), "/app.Service/Burst"))
+	}
+	assert.Equal(t, codes.ResourceExhausted, status.Code(rl.allow(context.Background(), "/app.Service/Burst")))
+}
+
+func Test_RateLimiter_PerClient(t *testing.T) {
+	rl, err := newRateLimiter(RateLimit{Methods: []MethodLimit{{Method: "*", Limit: "1/h", PerClient: true}}})
+	assert.NoError(t, err)
+
+	withPeer := func(ip string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1000}})
+	}
+
+	assert.NoError(t, rl.allow(withPeer("10.0.0.1"), "/app.Service/A"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(rl.allow(withPeer("10.0.0.1"), "/app.Service/A")))
+
+	// other clients and methods are limited separately
+	assert.NoError(t, rl.allow(withPeer("10.0.0.2"), "/app.Service/A"))
+	assert.NoError(t, rl.allow(withPeer("10.0.0.1"), "/app.Service/B"))
+}
+
+func Test_RateLimiter_Invalid(t *testing.T) {
+	_, err := newRateLimiter(RateLimit{Methods: []MethodLimit{{Method: "*", Limit: "invalid"}}})
+	assert.Error(t, err)
+
+	_, err = newRateLimiter(RateLimit{Client: "invalid"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"runtime/debug"
+)
+
+// EventPanic thrown when RPC call panicked, event context is *PanicContext.
+const EventPanic = iota + 9400
+
+// PanicContext describes recovered panic.
+type PanicContext struct {
+	// Method is full name of RPC method.
+	Method string
+
+	// Value passed to panic.
+	Value interface{}
+
+	// Stack trace of the panicked goroutine.
+	Stack []byte
+}
+
+// recovery converts panics of proxies and interceptors into Internal errors and reports them using throw function.
+type recovery struct {
+	cfg   Recovery
+	throw func(event int, ctx interface{})
+}
+
+// recover reports the panic and returns error to be sent to the client.
+func (r *recovery) recover(method string, value interface{}) error {
+	stack := debug.Stack()
+	r.throw(EventPanic, &PanicContext{Method: method, Value: value, Stack: stack})
+
+	if r.cfg.Stack {
+		return status.Errorf(codes.Internal, "%s: %v\n%s", r.cfg.message(), value, stack)
+	}
+
+	return status.Error(codes.Internal, r.cfg.message())
+}
+
+// unaryInterceptor recovers panics of unary calls.
+func (r *recovery) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp interface{}, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			resp, err = nil, r.recover(info.FullMethod, v)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// streamInterceptor recovers panics of streaming calls.
+func (r *recovery) streamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = r.recover(info.FullMethod, v)
+		}
+	}()
+
+	return handler(srv, ss)
+}
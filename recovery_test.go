@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+)
+
+func Test_Recovery_Unary(t *testing.T) {
+	var p *PanicContext
+	r := &recovery{throw: func(event int, ctx interface{}) {
+		assert.Equal(t, EventPanic, event)
+		p = ctx.(*PanicContext)
+	}}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+	resp, err := r.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("failure")
+	})
+	assert.Nil(t, resp)
+
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.Equal(t, "internal error", st.Message())
+
+	assert.Equal(t, "/service.Test/Echo", p.Method)
+	assert.Equal(t, "failure", p.Value)
+	assert.NotEmpty(t, p.Stack)
+
+	// no panic
+	resp, err = r.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
+
+func Test_Recovery_Stream(t *testing.T) {
+	r := &recovery{cfg: Recovery{Message: "oops", Stack: true}, throw: func(event int, ctx interface{}) {}}
+
+	info := &grpc.StreamServerInfo{FullMethod: "/service.Test/Stream"}
+	err := r.streamInterceptor(nil, &mockStream{ctx: context.Background()}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		var m map[string]string
+		m["key"] = "value"
+		return nil
+	})
+
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.True(t, strings.HasPrefix(st.Message(), "oops: assignment to entry in nil map"))
+	assert.Contains(t, st.Message(), "recovery_test.go")
+}
+
+func Test_Recovery_ProxyInterceptor(t *testing.T) {
+	var p *PanicContext
+	proxy := NewProxy("service.Test", "", nil)
+	proxy.recovery = &recovery{throw: func(event int, ctx interface{}) { p = ctx.(*PanicContext) }}
+
+	// interceptor of grpc.UnaryInterceptor option is outside of the interceptors of the service
+	option := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		panic("failure")
+	}
+
+	dec := func(interface{}) error { return nil }
+	resp, err := proxy.methodHandler("Echo")(nil, context.Background(), dec, option)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	assert.Equal(t, "/service.Test/Echo", p.Method)
+	assert.Equal(t, "failure", p.Value)
+}
@@ -0,0 +1,32 @@
+package grpc
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/spiral/php-grpc/parser"
+)
+
+// registerDescriptors builds descriptors of the proto file (and all of it's imports) and registers
+// them in golang/protobuf registry used by reflection service. Returns the name of the file
+// declaring each service.
+func registerDescriptors(file string, importPaths ...string) (map[string]string, error) {
+	files, err := parser.Descriptor(file, importPaths...)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]string)
+	for _, fd := range files {
+		data, err := parser.Compress(fd)
+		if err != nil {
+			return nil, err
+		}
+
+		proto.RegisterFile(fd.GetName(), data)
+
+		for _, s := range fd.Service {
+			services[parser.Service{Package: fd.GetPackage(), Name: s.GetName()}.FullName()] = fd.GetName()
+		}
+	}
+
+	return services, nil
+}
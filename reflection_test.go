@@ -0,0 +1,72 @@
+package grpc
+
+import (
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"net"
+	"testing"
+)
+
+func Test_Reflection_Descriptors(t *testing.T) {
+	files, err := registerDescriptors("parser/test_nested/test_import.proto", "parser/test_nested")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "test_import.proto", files["app.namespace.PingService"])
+	assert.Equal(t, "pong.proto", files["app.namespace.PongService"])
+
+	assert.NotNil(t, proto.FileDescriptor("message.proto"))
+}
+
+func Test_Reflection_Service(t *testing.T) {
+	files, err := registerDescriptors("parser/test.proto", "parser")
+	assert.NoError(t, err)
+
+	server := grpc.NewServer()
+	p := NewProxy("app.namespace.PingService", files["app.namespace.PingService"], nil)
+	p.RegisterMethod("Ping")
+	server.RegisterService(p.ServiceDesc(), p)
+	reflection.Register(server)
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+
+	go server.Serve(ln)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	assert.NoError(t, err)
+
+	assert.NoError(t, stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	}))
+
+	out, err := stream.Recv()
+	assert.NoError(t, err)
+
+	services := out.GetListServicesResponse().Service
+	assert.Len(t, services, 2)
+	assert.Equal(t, "app.namespace.PingService", services[0].Name)
+
+	assert.NoError(t, stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: "app.namespace.PingService",
+		},
+	}))
+
+	out, err = stream.Recv()
+	assert.NoError(t, err)
+
+	fd := &dpb.FileDescriptorProto{}
+	assert.NoError(t, proto.Unmarshal(out.GetFileDescriptorResponse().FileDescriptorProto[0], fd))
+	assert.Equal(t, "test.proto", fd.GetName())
+	assert.Equal(t, ".app.namespace.Message", fd.Service[0].Method[0].GetInputType())
+}
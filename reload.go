@@ -0,0 +1,174 @@
+package grpc
+
+import (
+	"errors"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"sync"
+	"time"
+)
+
+// errListenerClosed returned by listeners of replaced servers.
+var errListenerClosed = errors.New("listener closed")
+
+// sharedListener accepts connections of the service listeners and hands them to the listener of the active
+// server, allowing to replace the server without closing the sockets.
+type sharedListener struct {
+	listeners []net.Listener
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// shareListener starts accepting connections of the given listeners.
+func shareListener(listeners ...net.Listener) *sharedListener {
+	s := &sharedListener{listeners: listeners, conns: make(chan net.Conn), closed: make(chan struct{})}
+	for _, l := range listeners {
+		go s.serve(l)
+	}
+
+	return s
+}
+
+// serve accepts connections of the listener until it's closed, failure of any listener closes all of them.
+func (s *sharedListener) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(5 * time.Millisecond)
+				continue
+			}
+
+			s.close(err)
+			return
+		}
+
+		select {
+		case s.conns <- conn:
+		case <-s.closed:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// Close closes the underlying listeners.
+func (s *sharedListener) Close() error {
+	return s.close(nil)
+}
+
+// close closes the underlying listeners, reason is reported by server listeners.
+func (s *sharedListener) close(reason error) (err error) {
+	s.closeOnce.Do(func() {
+		s.err = reason
+		close(s.closed)
+		for _, l := range s.listeners {
+			if cerr := l.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	})
+
+	return err
+}
+
+// Addr returns address of the first listener.
+func (s *sharedListener) Addr() net.Addr {
+	return s.listeners[0].Addr()
+}
+
+// listener creates listener for the new server, closing it does not affect the shared listener.
+func (s *sharedListener) listener() net.Listener {
+	return &serverListener{shared: s, closed: make(chan struct{})}
+}
+
+// serverListener receives connections of the shared listener while server is active.
+type serverListener struct {
+	shared    *sharedListener
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// Accept waits for the next connection.
+func (l *serverListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.shared.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, errListenerClosed
+	case <-l.shared.closed:
+		if l.shared.err != nil {
+			return nil, l.shared.err
+		}
+
+		return nil, errListenerClosed
+	}
+}
+
+// Close stops receiving connections.
+func (l *serverListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+// Addr returns address of the shared listener.
+func (l *serverListener) Addr() net.Addr {
+	return l.shared.Addr()
+}
+
+// reload parses proto files again and replaces the server with the new one serving updated services. New
+// connections are accepted by the new server immediately while active calls of the previous server are
+// allowed to complete (clients reconnect transparently once their connection is drained). Parse errors
+// keep the current server running.
+func (svc *Service) reload() error {
+	svc.mu.Lock()
+
+	if svc.grpc == nil || svc.stopping != nil {
+		svc.mu.Unlock()
+		return errors.New("grpc server is not running")
+	}
+
+	server, err := svc.createGPRCServer()
+	if err != nil {
+		svc.mu.Unlock()
+		return err
+	}
+
+	prev := svc.grpc
+	svc.grpc = server
+	svc.retired.Add(1)
+
+	// new connections are accepted by the new server only
+	if svc.lis != nil {
+		svc.lis.Close()
+	}
+
+	// new health server is created with every server
+	if !svc.isDraining() {
+		svc.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+	}
+
+	svc.mu.Unlock()
+
+	go func() {
+		defer svc.retired.Done()
+		svc.retire(prev)
+	}()
+
+	return nil
+}
+
+// retire stops replaced server once it's active calls are complete, gRPC-Web calls must be complete before
+// the server is stopped gracefully.
+func (svc *Service) retire(server *grpc.Server) {
+	if svc.gw != nil && !svc.gw.wait(server, svc.cfg.GracefulTimeout) {
+		svc.throw(EventForceStop, "server")
+		server.Stop()
+		return
+	}
+
+	svc.gracefulStop(server)
+}
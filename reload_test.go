@@ -0,0 +1,137 @@
+package grpc
+
+import (
+	"github.com/spiral/roadrunner"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_SharedListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	shared := shareListener(l)
+	defer shared.Close()
+
+	first := shared.listener()
+	assert.Equal(t, l.Addr(), first.Addr())
+	assert.NoError(t, first.Close())
+
+	_, err = first.Accept()
+	assert.Equal(t, errListenerClosed, err)
+
+	second := shared.listener()
+	go func() {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		assert.NoError(t, err)
+		conn.Close()
+	}()
+
+	conn, err := second.Accept()
+	assert.NoError(t, err)
+	conn.Close()
+
+	shared.Close()
+	_, err = second.Accept()
+	assert.Error(t, err)
+}
+
+func Test_SharedListener_Multiple(t *testing.T) {
+	l1, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	shared := shareListener(l1, l2)
+	lis := shared.listener()
+	assert.Equal(t, l1.Addr(), lis.Addr())
+
+	for _, l := range []net.Listener{l1, l2} {
+		go func(addr string) {
+			conn, err := net.Dial("tcp", addr)
+			assert.NoError(t, err)
+			conn.Close()
+		}(l.Addr().String())
+
+		conn, err := lis.Accept()
+		assert.NoError(t, err)
+		conn.Close()
+	}
+
+	// every listener is closed
+	assert.NoError(t, shared.Close())
+	for _, l := range []net.Listener{l1, l2} {
+		_, err = net.Dial("tcp", l.Addr().String())
+		assert.Error(t, err)
+	}
+}
+
+func Test_Service_Reload(t *testing.T) {
+	svc := &Service{cfg: &Config{
+		Proto:  "parser/test.proto",
+		Health: true,
+		Workers: &roadrunner.ServerConfig{
+			Command: "php worker.php",
+			Relay:   "pipes",
+			Pool:    &roadrunner.Config{NumWorkers: 1, DestroyTimeout: time.Second},
+		},
+	}}
+
+	var err error
+	svc.grpc, err = svc.createGPRCServer()
+	assert.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	served := make(chan error)
+	go func() { served <- svc.serve(shareListener(l)) }()
+
+	check := func(service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+		conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+
+		out, err := healthpb.NewHealthClient(conn).Check(
+			context.Background(),
+			&healthpb.HealthCheckRequest{Service: service},
+			grpc.FailFast(false),
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		return out.Status, nil
+	}
+
+	_, err = check("app.namespace.PingService")
+	assert.NoError(t, err)
+
+	// invalid proto keeps current server
+	svc.cfg.Proto = "parser/missing.proto"
+	assert.Error(t, svc.reload())
+
+	svc.cfg.Proto = "parser/test_nested/pong.proto"
+	assert.NoError(t, svc.reload())
+
+	_, err = check("app.namespace.PingService")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	st, err := check("app.namespace.PongService")
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, st)
+
+	svc.Stop()
+	assert.NoError(t, <-served)
+	assert.Error(t, svc.reload())
+}
@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"github.com/spiral/roadrunner"
+	"golang.org/x/net/context"
+	"time"
+)
+
+// retryable returns true if the call failed due to worker or pool error (crashed worker, allocate timeout),
+// errors returned by the application are never retried.
+func retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	_, ok := err.(roadrunner.JobError)
+	return !ok
+}
+
+// retry executes the call, calls of idempotent methods failed due to worker errors are executed again with
+// exponential backoff. Retries stop once the call is cancelled or the next attempt would exceed the deadline.
+func (p *Proxy) retry(
+	ctx context.Context,
+	method string,
+	exec func() (*roadrunner.Payload, error),
+) (*roadrunner.Payload, error) {
+	resp, err := exec()
+	if !p.retries[method] {
+		return resp, err
+	}
+
+	backoff := p.retryBackoff
+	for attempt := 0; attempt < p.retryAttempts && retryable(err); attempt++ {
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(backoff).After(deadline) {
+			break
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		resp, err = exec()
+	}
+
+	return resp, err
+}
@@ -2,7 +2,7 @@ package grpc
 
 import (
 	"errors"
-	"github.com/spiral/roadrunner/util"
+	"time"
 )
 
 type rpcServer struct {
@@ -12,25 +12,144 @@ type rpcServer struct {
 // WorkerList contains list of workers.
 type WorkerList struct {
 	// Workers is list of workers.
-	Workers []*util.State `json:"workers"`
+	Workers []*WorkerState `json:"workers"`
 }
 
-// Reset resets underlying RR worker pool and restarts all of it's workers.
+// Status describes configuration loaded by the running server, it contains no secrets (certificates, keys and
+// tokens are omitted).
+type Status struct {
+	// Services is number of services proxied to PHP workers.
+	Services int `json:"services"`
+
+	// Methods is number of methods of proxied services (including streaming methods).
+	Methods int `json:"methods"`
+
+	// Listen lists addresses the server listens on (including endpoints).
+	Listen []string `json:"listen"`
+
+	// TLS is true when connections of Listen and Addresses are encrypted, endpoints use their own settings.
+	TLS bool `json:"tls"`
+
+	// Codec is name of the codec used to pass messages to workers.
+	Codec string `json:"codec"`
+
+	// Workers is number of active workers of every pool.
+	Workers int `json:"workers"`
+
+	// Proto lists loaded proto files.
+	Proto []string `json:"proto"`
+}
+
+// Reset resets underlying RR worker pools and restarts all of their workers (to pick up updated PHP code), server
+// keeps listening. Calls in progress are completed by workers of the previous pool, error is returned when the
+// new pool can not be started (previous pool is kept in this case).
 func (rpc *rpcServer) Reset(reset bool, r *string) error {
 	if rpc.svc == nil || rpc.svc.grpc == nil {
 		return errors.New("grpc server is not running")
 	}
 
+	if err := rpc.svc.resetPool(); err != nil {
+		return err
+	}
+
 	*r = "OK"
-	return rpc.svc.rr.Reset()
+	return nil
 }
 
-// Workers returns list of active workers and their stats.
+// Workers returns list of active workers of every pool and their stats (pid, status, number of executions, memory
+// usage, last error and pool name).
 func (rpc *rpcServer) Workers(list bool, r *WorkerList) (err error) {
 	if rpc.svc == nil || rpc.svc.grpc == nil {
 		return errors.New("grpc server is not running")
 	}
 
-	r.Workers, err = util.ServerState(rpc.svc.rr)
+	r.Workers, _, err = rpc.svc.workerStates()
 	return err
 }
+
+// Stats returns worker pool statistics (number of workers, busy workers, executed calls, memory usage and uptime of
+// every worker) of every pool with stats of individual pools, call is read-only.
+func (rpc *rpcServer) Stats(stats bool, r *PoolStats) error {
+	if rpc.svc == nil || rpc.svc.grpc == nil {
+		return errors.New("grpc server is not running")
+	}
+
+	states, pools, err := rpc.svc.workerStates()
+	if err != nil {
+		return err
+	}
+
+	*r = *poolsStats(states, pools, time.Now())
+	return nil
+}
+
+// ReloadTLS reloads TLS certificate and key from the disk, new certificate is used for new connections only.
+func (rpc *rpcServer) ReloadTLS(reload bool, r *string) error {
+	if rpc.svc == nil || rpc.svc.grpc == nil {
+		return errors.New("grpc server is not running")
+	}
+
+	if err := rpc.svc.reloadCertificate(); err != nil {
+		return err
+	}
+
+	*r = "OK"
+	return nil
+}
+
+// Drain switches server to NOT_SERVING health status and rejects new calls with Unavailable status, calls in
+// progress are allowed to complete.
+func (rpc *rpcServer) Drain(drain bool, r *string) error {
+	if rpc.svc == nil || rpc.svc.grpc == nil {
+		return errors.New("grpc server is not running")
+	}
+
+	rpc.svc.drain(true)
+
+	*r = "OK"
+	return nil
+}
+
+// Resume makes drained server accept new calls again.
+func (rpc *rpcServer) Resume(resume bool, r *string) error {
+	if rpc.svc == nil || rpc.svc.grpc == nil {
+		return errors.New("grpc server is not running")
+	}
+
+	rpc.svc.drain(false)
+
+	*r = "OK"
+	return nil
+}
+
+// Reload parses proto files again and replaces the server with the new one. Every change of proto files
+// (new, changed or removed services and methods) is applied to new calls, active calls are completed
+// by the previous server. Service configuration is not reloaded, use it to apply changes of proto files only.
+func (rpc *rpcServer) Reload(reload bool, r *string) error {
+	if rpc.svc == nil || rpc.svc.grpc == nil {
+		return errors.New("grpc server is not running")
+	}
+
+	if err := rpc.svc.reload(); err != nil {
+		return err
+	}
+
+	*r = "OK"
+	return nil
+}
+
+// Status returns summary of the configuration loaded by the running server (number of services and methods,
+// listen addresses, TLS, codec, number of workers and proto files), call is read-only.
+func (rpc *rpcServer) Status(status bool, r *Status) error {
+	if rpc.svc == nil || rpc.svc.grpc == nil {
+		return errors.New("grpc server is not running")
+	}
+
+	st, err := rpc.svc.status()
+	if err != nil {
+		return err
+	}
+
+	*r = *st
+	return nil
+}
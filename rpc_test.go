@@ -8,6 +8,8 @@ import (
 	"github.com/spiral/roadrunner/service/rpc"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"strconv"
 	"testing"
 	"time"
@@ -130,9 +132,111 @@ func Test_Workers(t *testing.T) {
 	assert.Len(t, r.Workers, 1)
 }
 
+func Test_Drain(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	c := service.NewContainer(logger)
+	c.Register(rpc.ID, &rpc.Service{})
+	c.Register(ID, &Service{})
+
+	assert.NoError(t, c.Init(&testCfg{
+		rpcCfg: `{"enable":true, "listen":"tcp://:5004"}`,
+		grpcCfg: `{
+				"listen": "tcp://:9080",
+				"tls": {
+					"key": "tests/server.key",
+					"cert": "tests/server.crt"
+				},
+				"proto": "tests/test.proto",
+				"workers":{
+					"command": "php tests/worker.php",
+					"relay": "pipes",
+					"pool": {
+						"numWorkers": 1,
+						"allocateTimeout": 10,
+						"destroyTimeout": 10
+					}
+				}
+		}`,
+	}))
+
+	s, _ := c.Get(ID)
+	ss := s.(*Service)
+
+	s2, _ := c.Get(rpc.ID)
+	rs := s2.(*rpc.Service)
+
+	go func() { assert.NoError(t, c.Serve()) }()
+	time.Sleep(time.Millisecond * 100)
+	defer c.Stop()
+
+	cl, cn := getClient("localhost:9080")
+	defer cn.Close()
+
+	rcl, err := rs.Client()
+	assert.NoError(t, err)
+
+	_, err = cl.Info(context.Background(), &tests.Message{Msg: "PID"})
+	assert.NoError(t, err)
+
+	r := ""
+	assert.NoError(t, rcl.Call("grpc.Drain", true, &r))
+	assert.Equal(t, "OK", r)
+
+	_, err = cl.Info(context.Background(), &tests.Message{Msg: "PID"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.True(t, ss.isDraining())
+
+	assert.NoError(t, rcl.Call("grpc.Resume", true, &r))
+	assert.Equal(t, "OK", r)
+
+	_, err = cl.Info(context.Background(), &tests.Message{Msg: "PID"})
+	assert.NoError(t, err)
+}
+
 func Test_Errors(t *testing.T) {
 	r := &rpcServer{nil}
 
 	assert.Error(t, r.Reset(true, nil))
 	assert.Error(t, r.Workers(true, nil))
+	assert.Error(t, r.Stats(true, nil))
+	assert.Error(t, r.Drain(true, nil))
+	assert.Error(t, r.Resume(true, nil))
+	assert.Error(t, r.Reload(true, nil))
+	assert.Error(t, r.Status(true, nil))
+
+	// pool is not started
+	assert.Error(t, (&Service{cfg: &Config{}}).resetPool())
+}
+
+func Test_Status(t *testing.T) {
+	svc := &Service{}
+	ok, err := svc.Init(&Config{
+		Listen:    "tcp://127.0.0.1:9098",
+		Addresses: []string{"tcp://127.0.0.1:9097"},
+		Proto:     "parser/test.proto",
+		Workers:   echoWorkers(2),
+	}, nil, nil)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	go func() { assert.NoError(t, svc.Serve()) }()
+	defer svc.Stop()
+
+	for i := 0; i < 50 && svc.server() == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rpc := &rpcServer{svc}
+	st := &Status{}
+	assert.NoError(t, rpc.Status(true, st))
+	assert.Equal(t, &Status{
+		Services: 2,
+		Methods:  2,
+		Listen:   []string{"tcp://127.0.0.1:9098", "tcp://127.0.0.1:9097"},
+		Codec:    "proto",
+		Workers:  2,
+		Proto:    []string{"parser/test.proto"},
+	}, st)
 }
@@ -1,32 +1,85 @@
 package grpc
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spiral/php-grpc/parser"
 	"github.com/spiral/roadrunner"
 	"github.com/spiral/roadrunner/service/env"
 	"github.com/spiral/roadrunner/service/rpc"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/encoding"
-	"path"
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"net"
+	"net/http"
+	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ID sets public GRPC service ID for roadrunner.Container.
 const ID = "grpc"
 
+// maxRecoverDelay limits the interval between worker pool restart attempts.
+const maxRecoverDelay = 30 * time.Second
+
+const (
+	// EventUnresolvedImport thrown when proto import can not be resolved and is skipped (see
+	// SkipUnresolvedImports), event context is *parser.Error pointing to the import.
+	EventUnresolvedImport = 9900
+
+	// EventRecoverError thrown when dead worker pool can not be restarted (see RecoverPool), event context is
+	// error. Restart is retried until the pool is restored or service is stopped.
+	EventRecoverError = 10000
+)
+
 // Service manages set of GPRC services, options and connections.
 type Service struct {
-	cfg      *Config
-	env      env.Environment
-	list     []func(event int, ctx interface{})
-	opts     []grpc.ServerOption
-	services []func(server *grpc.Server)
-	mu       sync.Mutex
-	rr       *roadrunner.Server
-	cr       roadrunner.Controller
-	grpc     *grpc.Server
+	cfg       *Config
+	env       env.Environment
+	list      []func(event int, ctx interface{})
+	opts      []grpc.ServerOption
+	unary     []grpc.UnaryServerInterceptor
+	stream    []grpc.StreamServerInterceptor
+	services  []func(server *grpc.Server)
+	values    []ValuesFunc
+	mu        sync.Mutex
+	rr        *roadrunner.Server
+	cr        roadrunner.Controller
+	errs      workerErrors
+	grpc      *grpc.Server
+	proxies   []*Proxy
+	health    *health.Server
+	certs     *certHolder
+	stopped   bool
+	draining  int32
+	metrics   *metrics
+	http      *http.Server
+	web       *http.Server
+	gw        *grpcWeb
+	lis       net.Listener
+	retired   sync.WaitGroup
+	tlsCfg    *tls.Config
+	stopping  chan struct{}
+	tracer    *tracer
+	provider  TracerProvider
+	access    *accessLog
+	limiter   *rateLimiter
+	limits    *concurrencyLimiter
+	sessions  chan struct{}
+	auth      *authenticator
+	memory    *memoryLimit
+	pools     []*workerPool
+	lifecycle workerLifecycle
+	endpoints []*endpoint
 }
 
 // Attach attaches cr. Currently only one cr is supported.
@@ -39,22 +92,110 @@ func (svc *Service) AddListener(l func(event int, ctx interface{})) {
 	svc.list = append(svc.list, l)
 }
 
+// AddEventListener attaches typed grpc event watcher, call events (EventUnaryCall, EventUnaryError and etc.)
+// carry *CallContext in Event.Call, worker lifecycle events carry *WorkerContext in Event.Worker.
+func (svc *Service) AddEventListener(l func(e Event)) {
+	svc.AddListener(func(event int, ctx interface{}) {
+		e := Event{Type: event, Context: ctx}
+		e.Call, _ = ctx.(*CallContext)
+		e.Worker, _ = ctx.(*WorkerContext)
+		l(e)
+	})
+}
+
 // AddService would be invoked after GRPC service creation.
 func (svc *Service) AddService(r func(server *grpc.Server)) error {
 	svc.services = append(svc.services, r)
 	return nil
 }
 
-// AddOption adds new GRPC server option. Codec and TLS options are controlled by service internally.
+// AddOption adds new GRPC server option. Codec and TLS options are controlled by service internally (configured
+// codec and TLS credentials take precedence over custom options). Interceptors set by grpc.UnaryInterceptor and
+// grpc.StreamInterceptor options wrap interceptors of the service, panics of unary option interceptors are recovered
+// for PHP services only, use AddUnaryInterceptor and AddStreamInterceptor to register interceptors covered by the
+// panic recovery of all services.
 func (svc *Service) AddOption(opt grpc.ServerOption) {
 	svc.opts = append(svc.opts, opt)
 }
 
+// AddUnaryInterceptor adds new unary interceptor, interceptors are invoked in order of registration
+// (first registered interceptor is the outermost).
+func (svc *Service) AddUnaryInterceptor(i grpc.UnaryServerInterceptor) {
+	svc.unary = append(svc.unary, i)
+}
+
+// AddStreamInterceptor adds new stream interceptor, interceptors are invoked in order of registration
+// (first registered interceptor is the outermost).
+func (svc *Service) AddStreamInterceptor(i grpc.StreamServerInterceptor) {
+	svc.stream = append(svc.stream, i)
+}
+
+// AddValues registers function computing values passed to PHP workers with every proxied call, workers read them
+// from :values context key. Values are computed after interceptors, functions are invoked in order of registration.
+func (svc *Service) AddValues(f ValuesFunc) {
+	svc.values = append(svc.values, f)
+}
+
+// SetTracerProvider sets provider receiving spans of traced calls, tracing must be enabled in configuration.
+// Provider is applied on the next server start.
+func (svc *Service) SetTracerProvider(tp TracerProvider) {
+	svc.provider = tp
+}
+
 // Init service.
 func (svc *Service) Init(cfg *Config, r *rpc.Service, e env.Environment) (ok bool, err error) {
+	var values map[string]string
+	if e != nil {
+		if values, err = e.GetEnv(); err != nil {
+			return false, err
+		}
+	}
+
+	cfg.overrideEnv(values)
+	if err := cfg.Valid(); err != nil {
+		return false, err
+	}
+
 	svc.cfg = cfg
 	svc.env = e
 
+	if cfg.Metrics.Enable {
+		if svc.metrics, err = newMetrics(cfg.Metrics.Namespace, svc.workers); err != nil {
+			return false, err
+		}
+	}
+
+	if cfg.Tracing.Enable {
+		svc.tracer = &tracer{throw: svc.throw}
+	}
+
+	if cfg.AccessLog.Enable {
+		if svc.access, err = newAccessLog(cfg.AccessLog, svc.throw); err != nil {
+			return false, err
+		}
+	}
+
+	if cfg.Auth.Enabled() {
+		if svc.auth, err = newAuthenticator(cfg.Auth); err != nil {
+			return false, err
+		}
+	}
+
+	if cfg.RateLimit.Enabled() {
+		if svc.limiter, err = newRateLimiter(cfg.RateLimit); err != nil {
+			return false, err
+		}
+	}
+
+	if len(cfg.Concurrency) != 0 {
+		svc.limits = newConcurrencyLimiter(cfg.Concurrency)
+	}
+
+	// session slots are shared by servers created on proto reload
+	if cfg.MaxSessions != 0 {
+		svc.sessions = make(chan struct{}, cfg.MaxSessions)
+	}
+
 	if r != nil {
 		if err := r.Register(ID, &rpcServer{svc}); err != nil {
 			return false, err
@@ -64,44 +205,204 @@ func (svc *Service) Init(cfg *Config, r *rpc.Service, e env.Environment) (ok boo
 	return true, nil
 }
 
+// Registry returns prometheus registry with service metrics or nil if metrics are disabled.
+func (svc *Service) Registry() *prometheus.Registry {
+	if svc.metrics == nil {
+		return nil
+	}
+
+	return svc.metrics.registry
+}
+
 // Serve GRPC grpc.
 func (svc *Service) Serve() (err error) {
+	shared, err := svc.prepare()
+	if err != nil {
+		return err
+	}
+	defer shared.Close()
+
+	if err := svc.startServer(svc.rr, defaultPool); err != nil {
+		return err
+	}
+	defer svc.stopPool()
+
+	for _, p := range svc.pools {
+		if err := svc.startServer(p.rr, p.cfg.Name); err != nil {
+			return fmt.Errorf("unable to start worker pool '%s': %s", p.cfg.Name, err)
+		}
+	}
+
+	if svc.memory != nil {
+		go svc.memory.watch()
+		defer svc.memory.close()
+	}
+
+	if svc.cfg.ValidateMethods {
+		if err := svc.validateMethods(); err != nil {
+			return err
+		}
+	}
+
+	if svc.cfg.Warmup.Count != 0 {
+		svc.warmup()
+	}
+
+	svc.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+
+	return svc.serve(shared)
+}
+
+// prepare creates worker pools, grpc server and listeners of the service, every listener is closed on error.
+func (svc *Service) prepare() (shared *sharedListener, err error) {
 	svc.mu.Lock()
+	defer svc.mu.Unlock()
 
 	if svc.env != nil {
 		if err := svc.env.Copy(svc.cfg.Workers); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
+	svc.stopped = false
+	svc.stopping = nil
+	atomic.StoreInt32(&svc.draining, 0)
 	svc.cfg.Workers.SetEnv("RR_GRPC", "true")
 
 	svc.rr = roadrunner.NewServer(svc.cfg.Workers)
-	svc.rr.Listen(svc.throw)
+	svc.rr.Listen(svc.poolListener(defaultPool, svc.cfg.Workers.Pool.MaxJobs))
 
 	if svc.cr != nil {
 		svc.rr.Attach(svc.cr)
 	}
 
+	svc.pools = nil
+	for _, p := range svc.cfg.Pools {
+		rr := roadrunner.NewServer(p.serverConfig(svc.cfg.Workers))
+		rr.Listen(svc.poolListener(p.Name, p.Pool.MaxJobs))
+
+		if svc.cr != nil {
+			rr.Attach(svc.cr)
+		}
+
+		svc.pools = append(svc.pools, &workerPool{cfg: p, rr: rr})
+	}
+
+	svc.memory = nil
+	if limit, _ := parseSize(svc.cfg.MaxMemory); limit != 0 {
+		svc.memory = newMemoryLimit(uint64(limit), svc.servers(), svc.throw)
+	}
+
+	svc.endpoints = nil
+	for _, cfg := range svc.cfg.Endpoints {
+		e, err := newEndpoint(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		svc.endpoints = append(svc.endpoints, e)
+	}
+
 	if svc.grpc, err = svc.createGPRCServer(); err != nil {
-		return err
+		return nil, err
 	}
 
-	lis, err := svc.cfg.Listener()
+	listeners, err := svc.cfg.Listeners()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer lis.Close()
+	endpoints, err := svc.endpointListeners()
+	if err != nil {
+		for _, l := range listeners {
+			l.Close()
+		}
 
-	svc.mu.Unlock()
+		return nil, err
+	}
+	listeners = append(listeners, endpoints...)
 
-	if err := svc.rr.Start(); err != nil {
-		return err
+	// listeners are shared by servers created on proto reload
+	shared = shareListener(listeners...)
+
+	if svc.metrics != nil && svc.cfg.Metrics.Address != "" {
+		if svc.http, err = svc.metrics.listen(svc.cfg.Metrics.Address); err != nil {
+			shared.Close()
+			return nil, err
+		}
 	}
-	defer svc.rr.Stop()
 
-	return svc.grpc.Serve(lis)
+	if svc.cfg.GRPCWeb.Address != "" {
+		svc.gw = &grpcWeb{cfg: svc.cfg.GRPCWeb, server: svc.server}
+		if svc.web, err = svc.gw.listen(svc.tlsCfg); err != nil {
+			shared.Close()
+			if svc.http != nil {
+				svc.http.Close()
+				svc.http = nil
+			}
+
+			return nil, err
+		}
+	}
+
+	return shared, nil
+}
+
+// serve serves connections of the shared listener by the current server until it's stopped.
+func (svc *Service) serve(shared *sharedListener) (err error) {
+	for {
+		svc.mu.Lock()
+		server, lis := svc.grpc, shared.listener()
+		svc.lis = lis
+		svc.mu.Unlock()
+
+		// server stops accepting connections once it's replaced by reload
+		if err = server.Serve(lis); svc.server() == server {
+			break
+		}
+	}
+
+	// Serve returns as soon as the server is stopped, workers must be kept until active calls are complete
+	svc.waitStop()
+
+	return err
+}
+
+// server returns current grpc server.
+func (svc *Service) server() *grpc.Server {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	return svc.grpc
+}
+
+// status summarizes configuration loaded by the server.
+func (svc *Service) status() (*Status, error) {
+	files, err := svc.cfg.ProtoFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Status{
+		Listen: svc.cfg.listenAddresses(),
+		TLS:    svc.cfg.EnableTLS(),
+		Codec:  svc.cfg.codecName(),
+		Proto:  files,
+	}
+
+	svc.mu.Lock()
+	for _, e := range svc.endpoints {
+		st.Listen = append(st.Listen, e.cfg.Address)
+	}
+
+	for _, p := range svc.proxies {
+		st.Services++
+		st.Methods += len(p.methods) + len(p.streams)
+	}
+	svc.mu.Unlock()
+
+	st.Workers = len(svc.workers())
+	return st, nil
 }
 
 // Stop the service.
@@ -112,7 +413,66 @@ func (svc *Service) Stop() {
 		return
 	}
 
-	go svc.grpc.GracefulStop()
+	svc.stopped = true
+	if svc.health != nil {
+		svc.health.Shutdown()
+	}
+
+	if svc.http != nil {
+		svc.http.Close()
+		svc.http = nil
+	}
+
+	if svc.stopping != nil {
+		return
+	}
+
+	stopping, server, web := make(chan struct{}), svc.grpc, svc.web
+	svc.stopping, svc.web = stopping, nil
+
+	go func() {
+		// grpc server can not drain gRPC-Web calls, they must be complete before the server is stopped
+		if web != nil && !svc.stopWeb(web) {
+			server.Stop()
+		} else {
+			svc.gracefulStop(server)
+		}
+
+		close(stopping)
+	}()
+}
+
+// stopWeb stops gRPC-Web server waiting for active calls to complete, remaining connections are closed
+// once GracefulTimeout is reached. Returns false when server was stopped forcibly.
+func (svc *Service) stopWeb(web *http.Server) bool {
+	ctx := context.Background()
+	if svc.cfg.GracefulTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, svc.cfg.GracefulTimeout)
+		defer cancel()
+	}
+
+	if err := web.Shutdown(ctx); err != nil {
+		svc.throw(EventForceStop, "grpc-web")
+		web.Close()
+		return false
+	}
+
+	return true
+}
+
+// waitStop waits for graceful stop of the server to complete, if the server is being stopped.
+func (svc *Service) waitStop() {
+	svc.mu.Lock()
+	stopping := svc.stopping
+	svc.mu.Unlock()
+
+	if stopping != nil {
+		<-stopping
+	}
+
+	// servers replaced by reload
+	svc.retired.Wait()
 }
 
 // throw handles service, grpc and pool events.
@@ -121,12 +481,195 @@ func (svc *Service) throw(event int, ctx interface{}) {
 		l(event, ctx)
 	}
 
+	svc.errs.handle(event, ctx)
+
+	switch event {
+	case roadrunner.EventPoolConstruct:
+		// new pool is constructed on start and every reset, draining server remains NOT_SERVING
+		if !svc.isDraining() {
+			svc.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+		}
+	case roadrunner.EventServerStop, roadrunner.EventServerFailure:
+		svc.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	case EventMaxMemory:
+		if we, ok := ctx.(roadrunner.WorkerError); ok {
+			svc.lifecycle.removed(we.Worker, StopReasonMaxMemory)
+		}
+	}
+
 	if event == roadrunner.EventServerFailure {
+		if svc.cfg.RecoverPool {
+			go svc.recoverPool()
+			return
+		}
+
 		// underlying rr grpc is dead
 		svc.Stop()
 	}
 }
 
+// resetPool replaces worker pool with the new one, previous pool is destroyed once it's active calls are complete.
+func (svc *Service) resetPool() error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if svc.rr == nil || svc.stopped {
+		return errors.New("grpc server is not running")
+	}
+
+	if err := svc.rr.Reconfigure(svc.cfg.Workers); err != nil {
+		return err
+	}
+
+	for _, p := range svc.pools {
+		if err := p.rr.Reset(); err != nil {
+			return fmt.Errorf("unable to reset worker pool '%s': %s", p.cfg.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// recoverPool restarts dead worker pools until they are restored or service is stopped, server reports NOT_SERVING
+// health status until then.
+func (svc *Service) recoverPool() {
+	svc.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	for delay := time.Second; ; delay *= 2 {
+		if delay > maxRecoverDelay {
+			delay = maxRecoverDelay
+		}
+		time.Sleep(delay)
+
+		svc.mu.Lock()
+		if svc.stopped {
+			svc.mu.Unlock()
+			return
+		}
+
+		err := svc.startFailed()
+		svc.mu.Unlock()
+
+		if err != nil {
+			svc.throw(EventRecoverError, fmt.Errorf("unable to restore worker pool: %s", err))
+			continue
+		}
+
+		// draining server remains NOT_SERVING
+		if !svc.isDraining() {
+			svc.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+		}
+
+		return
+	}
+}
+
+// workers returns list of active workers of every pool.
+func (svc *Service) workers() []*roadrunner.Worker {
+	svc.mu.Lock()
+	if svc.rr == nil {
+		svc.mu.Unlock()
+		return nil
+	}
+	servers := svc.servers()
+	svc.mu.Unlock()
+
+	workers := make([]*roadrunner.Worker, 0)
+	for _, rr := range servers {
+		workers = append(workers, rr.Workers()...)
+	}
+
+	return workers
+}
+
+// stopPool stops worker pools and prevents their recovery. Pools wait for active calls to complete, workers are
+// killed once GracefulTimeout is reached.
+func (svc *Service) stopPool() {
+	svc.mu.Lock()
+	svc.stopped = true
+	svc.mu.Unlock()
+
+	// pool is locked while being stopped
+	workers := svc.workers()
+
+	done := make(chan struct{})
+	go func() {
+		for _, rr := range svc.servers() {
+			rr.Stop()
+		}
+		close(done)
+	}()
+
+	if svc.cfg.GracefulTimeout == 0 {
+		<-done
+		return
+	}
+
+	select {
+	case <-done:
+	case <-time.After(svc.cfg.GracefulTimeout):
+		svc.throw(EventForceStop, "pool")
+		for _, w := range workers {
+			w.Kill()
+		}
+		<-done
+	}
+}
+
+// gracefulStop stops the server waiting for active calls to complete, remaining connections are closed
+// once GracefulTimeout is reached.
+func (svc *Service) gracefulStop(server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	if svc.cfg.GracefulTimeout == 0 {
+		<-done
+		return
+	}
+
+	select {
+	case <-done:
+	case <-time.After(svc.cfg.GracefulTimeout):
+		svc.throw(EventForceStop, "server")
+		server.Stop()
+	}
+}
+
+// reloadCertificate reloads TLS certificate pairs of the server and TLS endpoints from the disk, server keeps
+// using previous certificate when new pair can not be loaded.
+func (svc *Service) reloadCertificate() error {
+	svc.mu.Lock()
+	var certs []*certHolder
+	if svc.certs != nil {
+		certs = append(certs, svc.certs)
+	}
+
+	for _, e := range svc.endpoints {
+		if e.certs != nil {
+			certs = append(certs, e.certs)
+		}
+	}
+	svc.mu.Unlock()
+
+	if len(certs) == 0 {
+		return errors.New("tls is not enabled")
+	}
+
+	for _, h := range certs {
+		if err := h.reload(); err != nil {
+			svc.throw(EventCertError, err)
+			return err
+		}
+
+		svc.throw(EventCertReload, h.cert)
+	}
+
+	return nil
+}
+
 // new configured GRPC server
 func (svc *Service) createGPRCServer() (*grpc.Server, error) {
 	opts, err := svc.serverOptions()
@@ -135,20 +678,114 @@ func (svc *Service) createGPRCServer() (*grpc.Server, error) {
 	}
 
 	server := grpc.NewServer(opts...)
+	svc.proxies = nil
 
 	// php proxy services
-	services, err := parser.File(svc.cfg.Proto, path.Dir(svc.cfg.Proto))
+	services, err := svc.parseServices()
+	if err != nil {
+		return nil, err
+	}
+
+	sf, err := svc.sessionFactory()
 	if err != nil {
 		return nil, err
 	}
 
+	// message types of validated methods
+	var messages map[string]*methodMessages
+	if svc.cfg.ValidateMessages {
+		if messages, err = svc.describeMethods(); err != nil {
+			return nil, err
+		}
+	}
+
+	// file declaring every proxied service
+	files := make(map[string]string)
+	if svc.cfg.Reflection {
+		if files, err = svc.registerDescriptors(); err != nil {
+			return nil, err
+		}
+	}
+
 	for _, service := range services {
-		p := NewProxy(fmt.Sprintf("%s.%s", service.Package, service.Name), svc.cfg.Proto, svc.rr)
+		name := service.FullName()
+		if !svc.cfg.exposed(name) {
+			continue
+		}
+
+		metadata, ok := files[name]
+		if !ok {
+			metadata = service.file
+		}
+
+		p := NewProxy(name, metadata, svc.rr)
+		p.sf = sf
+		p.streamSessions = svc.cfg.StreamSessions
+		p.bufferedStreams = svc.cfg.BufferedStreams
+		p.forwardedHeader = svc.cfg.ForwardedHeader
+		p.recovery = &recovery{cfg: svc.cfg.Recovery, throw: svc.throw}
+		p.values = svc.values
+		p.maxRequest, _ = parseSize(svc.cfg.MaxRequestBytes)
+
+		p.retryAttempts, p.retryBackoff = svc.cfg.Retry.Attempts, svc.cfg.Retry.backoff()
+
+		if svc.memory != nil {
+			p.executed = svc.memory.executed
+		}
+		p.removed = svc.lifecycle.removed
+
+		p.timeout = svc.cfg.MaxExecutionTime
+		for _, t := range svc.cfg.Timeouts {
+			if t.Method == "*" {
+				p.timeout = t.Timeout
+			}
+		}
+
 		for _, m := range service.Methods {
+			for _, method := range svc.cfg.KillOnCancel {
+				if method == fmt.Sprintf("/%s/%s", name, m.Name) {
+					p.killOnCancel[m.Name] = true
+				}
+			}
+
+			for _, t := range svc.cfg.Timeouts {
+				if t.Method == fmt.Sprintf("/%s/%s", name, m.Name) {
+					p.timeouts[m.Name] = t.Timeout
+				}
+			}
+
+			for _, wp := range svc.pools {
+				if wp.cfg.serves(fmt.Sprintf("/%s/%s", name, m.Name)) {
+					p.pools[m.Name] = wp.rr
+				}
+			}
+
+			if msg, ok := messages[fmt.Sprintf("/%s/%s", name, m.Name)]; ok && !m.StreamsRequest {
+				p.messages[m.Name] = msg
+			}
+
+			for _, method := range svc.cfg.Retry.Methods {
+				if method == "*" || method == fmt.Sprintf("/%s/%s", name, m.Name) {
+					p.retries[m.Name] = true
+				}
+			}
+
+			if m.StreamsReturns || m.StreamsRequest {
+				p.RegisterStream(m.Name, m.StreamsReturns, m.StreamsRequest)
+				continue
+			}
+
 			p.RegisterMethod(m.Name)
 		}
 
 		server.RegisterService(p.ServiceDesc(), p)
+		svc.proxies = append(svc.proxies, p)
+	}
+
+	if svc.cfg.Health {
+		svc.health = health.NewServer()
+		svc.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		healthpb.RegisterHealthServer(server, svc.health)
 	}
 
 	// external services
@@ -156,22 +793,299 @@ func (svc *Service) createGPRCServer() (*grpc.Server, error) {
 		r(server)
 	}
 
+	if svc.cfg.Reflection {
+		reflection.Register(server)
+	}
+
 	return server, nil
 }
 
+// proxied service and the proto file it was found in
+type protoService struct {
+	parser.Service
+	file string
+}
+
+// parseServices parses services declared in every proto file (and their imports). Services imported
+// multiple times are registered once, services declared with conflicting methods or duplicate method
+// names cause an error.
+func (svc *Service) parseServices() ([]protoService, error) {
+	files, err := svc.cfg.ProtoFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// unresolved imports fail the parse unless they are skipped
+	var skip func(e *parser.Error)
+	if svc.cfg.SkipUnresolvedImports {
+		skip = func(e *parser.Error) { svc.throw(EventUnresolvedImport, e) }
+	}
+
+	services := make([]protoService, 0)
+	known := make(map[string]protoService)
+
+	for _, file := range files {
+		parsed, err := parser.FileSkipping(file, skip, svc.cfg.importPaths(file)...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range parsed {
+			name := s.FullName()
+			if prev, ok := known[name]; ok {
+				if reflect.DeepEqual(prev.Methods, s.Methods) {
+					continue
+				}
+
+				if prev.file == file {
+					return nil, fmt.Errorf("service '%s' is declared multiple times in '%s'", name, file)
+				}
+
+				return nil, fmt.Errorf("service '%s' declared in '%s' conflicts with '%s'", name, file, prev.file)
+			}
+
+			methods := make(map[string]bool)
+			for _, m := range s.Methods {
+				if methods[m.Name] {
+					return nil, fmt.Errorf("method '%s' of service '%s' is declared multiple times", m.Name, name)
+				}
+				methods[m.Name] = true
+			}
+
+			known[name] = protoService{Service: s, file: file}
+			services = append(services, known[name])
+		}
+	}
+
+	return services, nil
+}
+
+// registerDescriptors registers descriptors of every proto file for reflection service.
+func (svc *Service) registerDescriptors() (map[string]string, error) {
+	files, err := svc.cfg.ProtoFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]string)
+	for _, file := range files {
+		found, err := registerDescriptors(file, svc.cfg.importPaths(file)...)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, f := range found {
+			services[name] = f
+		}
+	}
+
+	return services, nil
+}
+
+// describeMethods builds message types of methods declared by proto files.
+func (svc *Service) describeMethods() (map[string]*methodMessages, error) {
+	files, err := svc.cfg.ProtoFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	methods := make(map[string]*methodMessages)
+	for _, file := range files {
+		descriptors, err := parser.Descriptor(file, svc.cfg.importPaths(file)...)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, msg := range describeMethods(descriptors) {
+			methods[name] = msg
+		}
+	}
+
+	return methods, nil
+}
+
+// setServingStatus updates health status of the server and every proxied service.
+func (svc *Service) setServingStatus(st healthpb.HealthCheckResponse_ServingStatus) {
+	if svc.health == nil {
+		return
+	}
+
+	svc.health.SetServingStatus("", st)
+	for _, p := range svc.proxies {
+		svc.health.SetServingStatus(p.name, st)
+	}
+}
+
+// creates factory for bidirectional stream workers
+func (svc *Service) sessionFactory() (*sessionFactory, error) {
+	values := make(map[string]string)
+	if svc.env != nil {
+		var err error
+		if values, err = svc.env.GetEnv(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &sessionFactory{
+		cmd:     sessionCommand(svc.cfg.Workers, values),
+		timeout: svc.cfg.Workers.Pool.DestroyTimeout,
+		slots:   svc.sessions,
+	}, nil
+}
+
 // server options
 func (svc *Service) serverOptions() (opts []grpc.ServerOption, err error) {
+	var creds credentials.TransportCredentials
 	if svc.cfg.EnableTLS() {
-		creds, err := credentials.NewServerTLSFromFile(svc.cfg.TLS.Cert, svc.cfg.TLS.Key)
+		tlsCfg, err := svc.cfg.TLSConfig()
 		if err != nil {
 			return nil, err
 		}
 
-		opts = append(opts, grpc.Creds(creds))
+		svc.certs = holdCertificate(tlsCfg, svc.cfg.TLS.Cert, svc.cfg.TLS.Key)
+		svc.tlsCfg = tlsCfg
+
+		creds = credentials.NewTLS(tlsCfg)
+	}
+
+	if len(svc.endpoints) != 0 {
+		// connections of endpoints are secured by their own credentials
+		creds = &endpointCreds{server: creds}
+	}
+
+	if creds != nil && len(svc.list) != 0 {
+		creds = &handshakeEvents{TransportCredentials: creds, throw: svc.throw}
 	}
 
+	if len(svc.list) != 0 {
+		opts = append(opts, grpc.StatsHandler(&connEvents{throw: svc.throw}))
+	}
+
+	if svc.cfg.Compression == "gzip" {
+		opts = append(opts, grpc.RPCCompressor(grpc.NewGZIPCompressor()))
+	}
+
+	recvSize, err := parseSize(svc.cfg.MaxRecvMsgSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if recvSize != 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(recvSize))
+	}
+
+	sendSize, err := parseSize(svc.cfg.MaxSendMsgSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if sendSize != 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(sendSize))
+	}
+
+	readSize, err := parseSize(svc.cfg.ReadBufferSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if readSize != 0 {
+		opts = append(opts, grpc.ReadBufferSize(readSize))
+	}
+
+	writeSize, err := parseSize(svc.cfg.WriteBufferSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if writeSize != 0 {
+		opts = append(opts, grpc.WriteBufferSize(writeSize))
+	}
+
+	if svc.cfg.MaxConcurrentStreams != 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(svc.cfg.MaxConcurrentStreams))
+	}
+
+	if svc.cfg.ConnectionTimeout != 0 {
+		opts = append(opts, grpc.ConnectionTimeout(svc.cfg.ConnectionTimeout))
+	}
+
+	if svc.cfg.NumStreamWorkers != 0 {
+		opts = append(opts, grpc.NumStreamWorkers(uint32(svc.cfg.NumStreamWorkers)))
+	}
+
+	if params := svc.cfg.Keepalive.ServerParameters(); params != nil {
+		opts = append(opts, grpc.KeepaliveParams(*params))
+	}
+
+	if policy := svc.cfg.Keepalive.EnforcementPolicy(); policy != nil {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(*policy))
+	}
+
+	// draining server rejects calls before they reach user interceptors and workers
+	unary := append([]grpc.UnaryServerInterceptor{svc.unaryDrain}, svc.unary...)
+	stream := append([]grpc.StreamServerInterceptor{svc.streamDrain}, svc.stream...)
+
+	if svc.limits != nil {
+		// calls rejected by rate limits do not occupy slots
+		unary = append([]grpc.UnaryServerInterceptor{svc.limits.unaryInterceptor}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{svc.limits.streamInterceptor}, stream...)
+	}
+
+	if svc.limiter != nil {
+		// rejected calls are still logged and measured
+		unary = append([]grpc.UnaryServerInterceptor{svc.limiter.unaryInterceptor}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{svc.limiter.streamInterceptor}, stream...)
+	}
+
+	if svc.auth != nil {
+		// unauthenticated calls do not consume rate limits
+		unary = append([]grpc.UnaryServerInterceptor{svc.auth.unaryInterceptor}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{svc.auth.streamInterceptor}, stream...)
+	}
+
+	if svc.access != nil {
+		unary = append([]grpc.UnaryServerInterceptor{svc.access.unaryInterceptor}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{svc.access.streamInterceptor}, stream...)
+	}
+
+	if svc.metrics != nil {
+		// metrics cover the complete call including user interceptors
+		unary = append([]grpc.UnaryServerInterceptor{svc.metrics.unaryInterceptor}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{svc.metrics.streamInterceptor}, stream...)
+	}
+
+	if len(svc.list) != 0 {
+		unary = append([]grpc.UnaryServerInterceptor{svc.unaryEvents}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{svc.streamEvents}, stream...)
+	}
+
+	if svc.tracer != nil {
+		// span covers metrics and user interceptors
+		svc.tracer.provider = svc.provider
+		unary = append([]grpc.UnaryServerInterceptor{svc.tracer.unaryInterceptor}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{svc.tracer.streamInterceptor}, stream...)
+	}
+
+	// outermost interceptor, panics of proxies and any interceptor fail the call with Internal status
+	rc := &recovery{cfg: svc.cfg.Recovery, throw: svc.throw}
+	unary = append([]grpc.UnaryServerInterceptor{rc.unaryInterceptor}, unary...)
+	stream = append([]grpc.StreamServerInterceptor{rc.streamInterceptor}, stream...)
+
+	// chain options leave UnaryInterceptor and StreamInterceptor options available to custom options
+	opts = append(opts, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+
 	opts = append(opts, svc.opts...)
 
+	// configured TLS and codec can not be overridden by custom options
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	base := encoding.GetCodec(svc.cfg.codecName())
+	if base == nil {
+		return nil, fmt.Errorf("codec '%s' is not registered", svc.cfg.codecName())
+	}
+
 	// custom codec is required to bypass protobuf
-	return append(opts, grpc.CustomCodec(&codec{encoding.GetCodec("proto")})), nil
+	return append(opts, grpc.CustomCodec(&codec{base})), nil
 }
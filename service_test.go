@@ -2,8 +2,12 @@ package grpc
 
 import (
 	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiral/php-grpc/parser"
 	"github.com/spiral/php-grpc/tests"
 	"github.com/spiral/php-grpc/tests/ext"
 	"github.com/spiral/roadrunner"
@@ -13,7 +17,21 @@ import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 	ngrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -206,6 +224,23 @@ func Test_Service_Invalid_Proto(t *testing.T) {
 	assert.Error(t, c.Serve())
 }
 
+func Test_Service_Proto_SyntaxError(t *testing.T) {
+	svc := &Service{}
+	ok, err := svc.Init(&Config{
+		Listen:  "tcp://:9080",
+		Proto:   "parser/test_invalid/service.proto",
+		Workers: echoWorkers(1),
+	}, nil, nil)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	// location of the problem and the importing file are reported
+	err = svc.Serve()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "parser/test_invalid/broken.proto:7:1: ")
+	assert.Contains(t, err.Error(), "(imported by parser/test_invalid/service.proto)")
+}
+
 func Test_Service_Echo(t *testing.T) {
 	logger, _ := test.NewNullLogger()
 	logger.SetLevel(logrus.DebugLevel)
@@ -253,6 +288,50 @@ func Test_Service_Echo(t *testing.T) {
 	assert.Equal(t, "ping", out.Msg)
 }
 
+func Test_Service_MaxRecvMsgSize(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	c := service.NewContainer(logger)
+	c.Register(ID, &Service{})
+
+	assert.NoError(t, c.Init(&testCfg{
+		grpcCfg: `{
+			"listen": "tcp://:9080",
+			"tls": {
+				"key": "tests/server.key",
+				"cert": "tests/server.crt"
+			},
+			"proto": "tests/test.proto",
+			"maxRecvMsgSize": "64",
+			"workers":{
+				"command": "php tests/worker.php",
+				"relay": "pipes",
+				"pool": {
+					"numWorkers": 1, 
+					"allocateTimeout": 10,
+					"destroyTimeout": 10 
+				}
+			}
+	}`,
+	}))
+
+	go func() { assert.NoError(t, c.Serve()) }()
+	time.Sleep(time.Millisecond * 100)
+	defer c.Stop()
+
+	cl, cn := getClient("localhost:9080")
+	defer cn.Close()
+
+	out, err := cl.Echo(context.Background(), &tests.Message{Msg: "ping"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", out.Msg)
+
+	_, err = cl.Echo(context.Background(), &tests.Message{Msg: strings.Repeat("a", 128)})
+	assert.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
 func Test_Service_Empty(t *testing.T) {
 	logger, _ := test.NewNullLogger()
 	logger.SetLevel(logrus.DebugLevel)
@@ -556,6 +635,502 @@ func Test_Service_Kill(t *testing.T) {
 	s.(*Service).throw(roadrunner.EventServerFailure, nil)
 }
 
+func Test_Service_RecoverPool(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	c := service.NewContainer(logger)
+	c.Register(ID, &Service{})
+
+	assert.NoError(t, c.Init(&testCfg{grpcCfg: `{
+			"listen": "tcp://:9080",
+			"tls": {
+				"key": "tests/server.key",
+				"cert": "tests/server.crt"
+			},
+			"proto": "tests/test.proto",
+			"recoverPool": true,
+			"health": true,
+			"workers":{
+				"command": "php tests/worker.php",
+				"relay": "pipes",
+				"pool": {
+					"numWorkers": 1, 
+					"allocateTimeout": 10,
+					"destroyTimeout": 10 
+				}
+			}
+	}`}))
+
+	s, st := c.Get(ID)
+	assert.NotNil(t, s)
+	assert.Equal(t, service.StatusOK, st)
+
+	go func() { assert.NoError(t, c.Serve()) }()
+	time.Sleep(time.Millisecond * 100)
+	defer c.Stop()
+
+	// server must remain available while the dead pool is restarted
+	s.(*Service).rr.Stop()
+	s.(*Service).throw(roadrunner.EventServerFailure, nil)
+
+	cl, cn := getClient("localhost:9080")
+	defer cn.Close()
+
+	hc := healthpb.NewHealthClient(cn)
+	hs, err := hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "service.Test"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, hs.Status)
+
+	// first restart attempt is made after a second
+	time.Sleep(time.Second * 2)
+
+	hs, err = hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "service.Test"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, hs.Status)
+
+	out, err := cl.Echo(context.Background(), &tests.Message{Msg: "ping"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", out.Msg)
+}
+
+func Test_Service_RecoverPool_Error(t *testing.T) {
+	workers := echoWorkers(1)
+	workers.Command = "invalid-command"
+
+	svc := &Service{cfg: &Config{}, health: health.NewServer(), rr: roadrunner.NewServer(workers)}
+
+	errs := make(chan interface{}, 10)
+	svc.AddListener(func(event int, ctx interface{}) {
+		if event == EventRecoverError {
+			errs <- ctx
+		}
+	})
+
+	go svc.recoverPool()
+	defer func() {
+		svc.mu.Lock()
+		svc.stopped = true
+		svc.mu.Unlock()
+	}()
+
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.(error).Error(), "unable to restore worker pool")
+	case <-time.After(3 * time.Second):
+		t.Fatal("failed restart must be reported")
+	}
+
+	st, err := svc.health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, st.Status)
+}
+
+func Test_Service_RecoverPool_Serving(t *testing.T) {
+	rr := roadrunner.NewServer(echoWorkers(1))
+	defer rr.Stop()
+
+	svc := &Service{cfg: &Config{RecoverPool: true}, health: health.NewServer(), rr: rr}
+	svc.throw(roadrunner.EventServerFailure, nil)
+
+	status := func() healthpb.HealthCheckResponse_ServingStatus {
+		st, err := svc.health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.NoError(t, err)
+		return st.Status
+	}
+
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status())
+
+	for deadline := time.Now().Add(5 * time.Second); status() != healthpb.HealthCheckResponse_SERVING; {
+		if time.Now().After(deadline) {
+			t.Fatal("restored pool must be reported as serving")
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+
+	assert.NotNil(t, rr.Pool())
+}
+
+func Test_Service_Health(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	c := service.NewContainer(logger)
+	c.Register(ID, &Service{})
+
+	assert.NoError(t, c.Init(&testCfg{
+		grpcCfg: `{
+			"listen": "tcp://:9080",
+			"tls": {
+				"key": "tests/server.key",
+				"cert": "tests/server.crt"
+			},
+			"proto": "tests/test.proto",
+			"health": true,
+			"workers":{
+				"command": "php tests/worker.php",
+				"relay": "pipes",
+				"pool": {
+					"numWorkers": 1, 
+					"allocateTimeout": 10,
+					"destroyTimeout": 10 
+				}
+			}
+	}`,
+	}))
+
+	go func() { assert.NoError(t, c.Serve()) }()
+	time.Sleep(time.Millisecond * 100)
+	defer c.Stop()
+
+	_, cn := getClient("localhost:9080")
+	defer cn.Close()
+
+	hc := healthpb.NewHealthClient(cn)
+
+	out, err := hc.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, out.Status)
+
+	out, err = hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "service.Test"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, out.Status)
+
+	_, err = hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "service.Unknown"})
+	assert.Error(t, err)
+
+	watch, err := hc.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: "service.Test"})
+	assert.NoError(t, err)
+
+	update, err := watch.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, update.Status)
+
+	s, _ := c.Get(ID)
+	s.(*Service).throw(roadrunner.EventServerStop, nil)
+
+	update, err = watch.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, update.Status)
+}
+
+func Test_Service_Health_Events(t *testing.T) {
+	svc := &Service{health: health.NewServer(), proxies: []*Proxy{NewProxy("service.Test", "", nil)}}
+
+	svc.throw(roadrunner.EventPoolConstruct, nil)
+	out, err := svc.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "service.Test"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, out.Status)
+
+	svc.throw(roadrunner.EventServerStop, nil)
+	out, err = svc.health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, out.Status)
+}
+
+func Test_Service_ParseServices(t *testing.T) {
+	svc := &Service{cfg: &Config{
+		Proto:  "parser/test_nested/*.proto",
+		Protos: []string{"parser/test.proto"},
+	}}
+
+	services, err := svc.parseServices()
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+
+	names := make([]string, 0)
+	for _, s := range services {
+		names = append(names, s.Package+"."+s.Name)
+	}
+
+	assert.Contains(t, names, "app.namespace.PingService")
+	assert.Contains(t, names, "app.namespace.PongService")
+}
+
+func Test_Service_ParseServices_ImportPaths(t *testing.T) {
+	svc := &Service{cfg: &Config{Proto: "parser/test_include/api/service.proto"}}
+
+	skipped := make([]interface{}, 0)
+	svc.AddListener(func(event int, ctx interface{}) {
+		if event == EventUnresolvedImport {
+			skipped = append(skipped, ctx)
+		}
+	})
+
+	// unresolved import fails the parse naming the import and searched paths
+	_, err := svc.parseServices()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "parser/test_include/api/service.proto:4:1: unable to resolve import")
+	assert.Contains(t, err.Error(), "common/types.proto")
+	assert.Len(t, skipped, 0)
+
+	svc.cfg.SkipUnresolvedImports = true
+	services, err := svc.parseServices()
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	if assert.Len(t, skipped, 1) {
+		assert.Equal(t, "parser/test_include/api/service.proto", skipped[0].(*parser.Error).File)
+	}
+
+	svc.cfg.ImportPaths = []string{"parser/test_include/shared"}
+
+	services, err = svc.parseServices()
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	assert.Equal(t, "parser/test_include/api/service.proto", services[0].file)
+}
+
+func Test_Service_ParseServices_Conflict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proto")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ping.proto"), []byte(`syntax = "proto3";
+package app.namespace;
+
+service PingService {
+    rpc Other (Message) returns (Message) {
+    }
+}
+
+message Message {
+    string msg = 1;
+}`), 0644))
+
+	svc := &Service{cfg: &Config{
+		Proto:  "parser/test.proto",
+		Protos: []string{filepath.Join(dir, "ping.proto")},
+	}}
+
+	_, err = svc.parseServices()
+	assert.Error(t, err)
+}
+
+func Test_Service_MultipleServices(t *testing.T) {
+	svc := &Service{cfg: &Config{
+		Proto:  "parser/test_multi.proto",
+		Protos: []string{"parser/test.proto"},
+		Workers: &roadrunner.ServerConfig{
+			Command: "php worker.php",
+			Relay:   "pipes",
+			Pool:    &roadrunner.Config{NumWorkers: 1, DestroyTimeout: time.Second},
+		},
+	}}
+
+	server, err := svc.createGPRCServer()
+	assert.NoError(t, err)
+
+	methods := make(map[string][]string)
+	for name, info := range server.GetServiceInfo() {
+		for _, m := range info.Methods {
+			methods[name] = append(methods[name], m.Name)
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"Get", "List", "Delete"}, methods["app.multi.Users"])
+	assert.ElementsMatch(t, []string{"Create", "Watch"}, methods["app.multi.Orders"])
+	assert.Equal(t, []string{"Ping"}, methods["app.namespace.PingService"])
+	assert.Equal(t, []string{"Pong"}, methods["app.namespace.PongService"])
+}
+
+func Test_Service_ParseServices_Duplicate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proto")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "service.proto"), []byte(`syntax = "proto3";
+package app.namespace;
+
+service PingService {
+    rpc Ping (Message) returns (Message);
+}
+
+service PingService {
+    rpc Other (Message) returns (Message);
+}
+
+message Message {
+    string msg = 1;
+}`), 0644))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "method.proto"), []byte(`syntax = "proto3";
+package app.namespace;
+
+service PongService {
+    rpc Pong (Message) returns (Message);
+    rpc Pong (Message) returns (Message);
+}
+
+message Message {
+    string msg = 1;
+}`), 0644))
+
+	svc := &Service{cfg: &Config{Proto: filepath.Join(dir, "service.proto")}}
+	_, err = svc.parseServices()
+	assert.EqualError(t, err, fmt.Sprintf(
+		"service 'app.namespace.PingService' is declared multiple times in '%s'",
+		filepath.Join(dir, "service.proto"),
+	))
+
+	svc = &Service{cfg: &Config{Proto: filepath.Join(dir, "method.proto")}}
+	_, err = svc.parseServices()
+	assert.EqualError(t, err, "method 'Pong' of service 'app.namespace.PongService' is declared multiple times")
+}
+
+func Test_Service_ExposeServices(t *testing.T) {
+	svc := &Service{cfg: &Config{
+		Proto:          "parser/test.proto",
+		ExposeServices: []string{"app.namespace.PingService"},
+		Workers: &roadrunner.ServerConfig{
+			Command: "php worker.php",
+			Relay:   "pipes",
+			Pool:    &roadrunner.Config{NumWorkers: 1, DestroyTimeout: time.Second},
+		},
+	}}
+
+	var err error
+	svc.grpc, err = svc.createGPRCServer()
+	assert.NoError(t, err)
+
+	info := svc.grpc.GetServiceInfo()
+	assert.Contains(t, info, "app.namespace.PingService")
+	assert.NotContains(t, info, "app.namespace.PongService")
+	assert.Len(t, svc.proxies, 1)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go svc.grpc.Serve(l)
+	defer svc.grpc.Stop()
+
+	conn, err := ngrpc.Dial(l.Addr().String(), ngrpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := conn.NewStream(
+		context.Background(),
+		&ngrpc.StreamDesc{ServerStreams: true, ClientStreams: true},
+		"/app.namespace.PongService/Pong",
+	)
+	assert.NoError(t, err)
+
+	assert.NoError(t, stream.CloseSend())
+	err = stream.RecvMsg(&healthpb.HealthCheckResponse{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func Test_Service_Packages(t *testing.T) {
+	svc := &Service{cfg: &Config{
+		Proto:  "parser/test_deep_package.proto",
+		Protos: []string{"parser/test_no_package.proto"},
+		Workers: &roadrunner.ServerConfig{
+			Command: "php worker.php",
+			Relay:   "pipes",
+			Pool:    &roadrunner.Config{NumWorkers: 1, DestroyTimeout: time.Second},
+		},
+	}}
+
+	server, err := svc.createGPRCServer()
+	assert.NoError(t, err)
+
+	info := server.GetServiceInfo()
+	assert.Contains(t, info, "com.acme.api.v1.Greeter")
+	assert.Contains(t, info, "Greeter")
+	assert.Len(t, info, 2)
+
+	// method paths clients call
+	paths := make([]string, 0)
+	for _, p := range svc.proxies {
+		for _, m := range p.ServiceDesc().Methods {
+			paths = append(paths, fmt.Sprintf("/%s/%s", p.ServiceDesc().ServiceName, m.MethodName))
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"/com.acme.api.v1.Greeter/SayHello", "/Greeter/SayHello"}, paths)
+}
+
+func Test_Service_OptionalOneof(t *testing.T) {
+	svc := &Service{cfg: &Config{
+		Proto: "parser/test_features.proto",
+		Workers: &roadrunner.ServerConfig{
+			Command: "php worker.php",
+			Relay:   "pipes",
+			Pool:    &roadrunner.Config{NumWorkers: 1, DestroyTimeout: time.Second},
+		},
+	}}
+
+	server, err := svc.createGPRCServer()
+	assert.NoError(t, err)
+
+	info, ok := server.GetServiceInfo()["app.features.Profiles"]
+	assert.True(t, ok)
+
+	methods := make([]string, 0)
+	for _, m := range info.Methods {
+		methods = append(methods, m.Name)
+	}
+
+	// method order of service info is not defined
+	assert.ElementsMatch(t, []string{"Get", "Update"}, methods)
+}
+
+func Test_Service_Reflection(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	c := service.NewContainer(logger)
+	c.Register(ID, &Service{})
+
+	assert.NoError(t, c.Init(&testCfg{
+		grpcCfg: `{
+			"listen": "tcp://:9080",
+			"tls": {
+				"key": "tests/server.key",
+				"cert": "tests/server.crt"
+			},
+			"proto": "tests/test.proto",
+			"reflection": true,
+			"workers":{
+				"command": "php tests/worker.php",
+				"relay": "pipes",
+				"pool": {
+					"numWorkers": 1, 
+					"allocateTimeout": 10,
+					"destroyTimeout": 10 
+				}
+			}
+	}`,
+	}))
+
+	go func() { assert.NoError(t, c.Serve()) }()
+	time.Sleep(time.Millisecond * 100)
+	defer c.Stop()
+
+	_, cn := getClient("localhost:9080")
+	defer cn.Close()
+
+	stream, err := rpb.NewServerReflectionClient(cn).ServerReflectionInfo(context.Background())
+	assert.NoError(t, err)
+
+	assert.NoError(t, stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: "service.Test"},
+	}))
+
+	out, err := stream.Recv()
+	assert.NoError(t, err)
+
+	fd := &dpb.FileDescriptorProto{}
+	assert.NoError(t, proto.Unmarshal(out.GetFileDescriptorResponse().FileDescriptorProto[0], fd))
+	assert.Equal(t, "service", fd.GetPackage())
+	assert.Equal(t, "Test", fd.Service[0].GetName())
+
+	methods := make([]string, 0)
+	for _, m := range fd.Service[0].Method {
+		methods = append(methods, m.GetName())
+	}
+	assert.Contains(t, methods, "Echo")
+}
+
 func getClient(addr string) (client tests.TestClient, conn *ngrpc.ClientConn) {
 	creds, err := credentials.NewClientTLSFromFile("tests/server.crt", "")
 	if err != nil {
@@ -591,3 +1166,451 @@ type externalService struct{}
 func (s *externalService) Echo(ctx context.Context, ping *ext.Ping) (*ext.Pong, error) {
 	return &ext.Pong{Value: ping.Value * 10}, nil
 }
+
+func Test_Service_Codec(t *testing.T) {
+	svc := &Service{cfg: &Config{}}
+	_, err := svc.serverOptions()
+	assert.NoError(t, err)
+
+	svc = &Service{cfg: &Config{Codec: "proto"}}
+	_, err = svc.serverOptions()
+	assert.NoError(t, err)
+
+	svc = &Service{cfg: &Config{Codec: "unknown"}}
+	_, err = svc.serverOptions()
+	assert.Error(t, err)
+}
+
+func Test_Service_BufferSize(t *testing.T) {
+	svc := &Service{cfg: &Config{ReadBufferSize: "64KB", WriteBufferSize: "1MB"}}
+	opts, err := svc.serverOptions()
+	assert.NoError(t, err)
+
+	def, err := (&Service{cfg: &Config{}}).serverOptions()
+	assert.NoError(t, err)
+	assert.Len(t, opts, len(def)+2)
+
+	svc.cfg.ReadBufferSize = "invalid"
+	_, err = svc.serverOptions()
+	assert.Error(t, err)
+}
+
+func Test_Service_NumStreamWorkers(t *testing.T) {
+	svc := &Service{cfg: &Config{NumStreamWorkers: 2}}
+	opts, err := svc.serverOptions()
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	active := make(chan struct{}, 4)
+
+	// server starts configured number of stream workers
+	workers := streamWorkers()
+	server := ngrpc.NewServer(opts...)
+	assert.Equal(t, workers+2, streamWorkers())
+
+	server.RegisterService(&ngrpc.ServiceDesc{
+		ServiceName: "app.Slow",
+		HandlerType: (*proxyService)(nil),
+		Methods: []ngrpc.MethodDesc{{
+			MethodName: "Wait",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ ngrpc.UnaryServerInterceptor) (interface{}, error) {
+				in := rawMessage{}
+				if err := dec(&in); err != nil {
+					return nil, err
+				}
+
+				active <- struct{}{}
+				<-release
+				return in, nil
+			},
+		}},
+	}, &Proxy{})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(l)
+	defer server.Stop()
+
+	conn, err := ngrpc.Dial(
+		l.Addr().String(),
+		ngrpc.WithInsecure(),
+		ngrpc.WithDefaultCallOptions(ngrpc.CallCustomCodec(&codec{encoding.GetCodec("proto")})),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			out := rawMessage{}
+			errs <- conn.Invoke(context.Background(), "/app.Slow/Wait", rawMessage("request"), &out)
+		}()
+	}
+
+	// calls exceeding the number of stream workers are not limited
+	for i := 0; i < 4; i++ {
+		select {
+		case <-active:
+		case <-time.After(time.Second):
+			t.Fatal("calls must be served concurrently")
+		}
+	}
+
+	close(release)
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, <-errs)
+	}
+}
+
+func Test_Service_InterceptorOption(t *testing.T) {
+	calls := make(chan string, 3)
+
+	svc := &Service{cfg: &Config{}}
+	svc.AddOption(ngrpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *ngrpc.UnaryServerInfo, handler ngrpc.UnaryHandler) (interface{}, error) {
+		calls <- "option"
+		return handler(ctx, req)
+	}))
+	svc.AddUnaryInterceptor(func(ctx context.Context, req interface{}, info *ngrpc.UnaryServerInfo, handler ngrpc.UnaryHandler) (interface{}, error) {
+		calls <- "service"
+		return handler(ctx, req)
+	})
+
+	opts, err := svc.serverOptions()
+	assert.NoError(t, err)
+
+	server := ngrpc.NewServer(opts...)
+	server.RegisterService(&ngrpc.ServiceDesc{
+		ServiceName: "app.Echo",
+		HandlerType: (*proxyService)(nil),
+		Methods: []ngrpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor ngrpc.UnaryServerInterceptor) (interface{}, error) {
+				in := rawMessage{}
+				if err := dec(&in); err != nil {
+					return nil, err
+				}
+
+				info := &ngrpc.UnaryServerInfo{Server: srv, FullMethod: "/app.Echo/Echo"}
+				return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+					calls <- "handler"
+					return req, nil
+				})
+			},
+		}},
+	}, &Proxy{})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(l)
+	defer server.Stop()
+
+	conn, err := ngrpc.Dial(
+		l.Addr().String(),
+		ngrpc.WithInsecure(),
+		ngrpc.WithDefaultCallOptions(ngrpc.CallCustomCodec(&codec{encoding.GetCodec("proto")})),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	out := rawMessage{}
+	assert.NoError(t, conn.Invoke(context.Background(), "/app.Echo/Echo", rawMessage("request"), &out))
+	assert.Equal(t, rawMessage("request"), out)
+
+	// interceptor of the option wraps interceptors of the service
+	assert.Equal(t, "option", <-calls)
+	assert.Equal(t, "service", <-calls)
+	assert.Equal(t, "handler", <-calls)
+}
+
+func Test_Service_MaxConcurrentStreams(t *testing.T) {
+	svc := &Service{cfg: &Config{MaxConcurrentStreams: 1}}
+	opts, err := svc.serverOptions()
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	active := make(chan struct{}, 2)
+
+	server := ngrpc.NewServer(opts...)
+	server.RegisterService(&ngrpc.ServiceDesc{
+		ServiceName: "app.Slow",
+		HandlerType: (*proxyService)(nil),
+		Methods: []ngrpc.MethodDesc{{
+			MethodName: "Wait",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ ngrpc.UnaryServerInterceptor) (interface{}, error) {
+				in := rawMessage{}
+				if err := dec(&in); err != nil {
+					return nil, err
+				}
+
+				active <- struct{}{}
+				<-release
+				return in, nil
+			},
+		}},
+	}, &Proxy{})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(l)
+	defer server.Stop()
+
+	conn, err := ngrpc.Dial(
+		l.Addr().String(),
+		ngrpc.WithInsecure(),
+		ngrpc.WithDefaultCallOptions(ngrpc.CallCustomCodec(&codec{encoding.GetCodec("proto")})),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			out := rawMessage{}
+			errs <- conn.Invoke(context.Background(), "/app.Slow/Wait", rawMessage("request"), &out)
+		}()
+	}
+
+	<-active
+
+	// second call is queued by the client rather than rejected
+	select {
+	case <-active:
+		t.Error("second call must wait for the first one")
+	case err := <-errs:
+		t.Errorf("call must not fail: %v", err)
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	close(release)
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+}
+
+// counts bytes received by the client
+type countingConn struct {
+	net.Conn
+	read *int64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(c.read, int64(n))
+	return n, err
+}
+
+func Test_Service_Compression(t *testing.T) {
+	body := rawMessage(strings.Repeat("a", 10000))
+
+	call := func(cfg *Config, opts ...ngrpc.CallOption) int64 {
+		svc := &Service{cfg: cfg}
+		sopts, err := svc.serverOptions()
+		assert.NoError(t, err)
+
+		server := ngrpc.NewServer(sopts...)
+		server.RegisterService(&ngrpc.ServiceDesc{
+			ServiceName: "app.Large",
+			HandlerType: (*proxyService)(nil),
+			Methods: []ngrpc.MethodDesc{{
+				MethodName: "Get",
+				Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ ngrpc.UnaryServerInterceptor) (interface{}, error) {
+					in := rawMessage{}
+					if err := dec(&in); err != nil {
+						return nil, err
+					}
+
+					return body, nil
+				},
+			}},
+		}, &Proxy{})
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		go server.Serve(l)
+		defer server.Stop()
+
+		var read int64
+		conn, err := ngrpc.Dial(
+			l.Addr().String(),
+			ngrpc.WithInsecure(),
+			ngrpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+				c, err := net.DialTimeout("tcp", addr, timeout)
+				return &countingConn{Conn: c, read: &read}, err
+			}),
+			ngrpc.WithDefaultCallOptions(ngrpc.CallCustomCodec(&codec{encoding.GetCodec("proto")})),
+		)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		out := rawMessage{}
+		assert.NoError(t, conn.Invoke(context.Background(), "/app.Large/Get", rawMessage("request"), &out, opts...))
+		assert.Equal(t, body, out)
+
+		return atomic.LoadInt64(&read)
+	}
+
+	// uncompressed
+	assert.True(t, call(&Config{}) > int64(len(body)))
+
+	// response compressed same way as request
+	assert.True(t, call(&Config{}, ngrpc.UseCompressor("gzip")) < int64(len(body)/2))
+
+	// every response compressed
+	assert.True(t, call(&Config{Compression: "gzip"}) < int64(len(body)/2))
+}
+
+func Test_Service_Compression_Worker(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	svc := &Service{cfg: &Config{}}
+	opts, err := svc.serverOptions()
+	assert.NoError(t, err)
+
+	// cat worker mirrors the payload it has received
+	p := NewProxy("app.Echo", "", nil)
+	p.sf = &sessionFactory{
+		cmd:     func() *exec.Cmd { return exec.Command("cat") },
+		timeout: time.Millisecond * 100,
+	}
+	p.killOnCancel["Echo"] = true
+	p.RegisterMethod("Echo")
+
+	server := ngrpc.NewServer(opts...)
+	server.RegisterService(p.ServiceDesc(), p)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(l)
+	defer server.Stop()
+
+	conn, err := ngrpc.Dial(
+		l.Addr().String(),
+		ngrpc.WithInsecure(),
+		ngrpc.WithDefaultCallOptions(ngrpc.CallCustomCodec(&codec{encoding.GetCodec("proto")})),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// compressed request is delivered to the worker decompressed
+	in := rawMessage(strings.Repeat("b", 10000))
+	out := rawMessage{}
+	assert.NoError(t, conn.Invoke(context.Background(), "/app.Echo/Echo", in, &out, ngrpc.UseCompressor("gzip")))
+	assert.Equal(t, in, out)
+}
+
+func Test_Service_GracefulTimeout(t *testing.T) {
+	events := make(chan int, 10)
+	svc := &Service{cfg: &Config{GracefulTimeout: time.Millisecond * 100}}
+	svc.AddListener(func(event int, ctx interface{}) { events <- event })
+
+	server := ngrpc.NewServer(ngrpc.CustomCodec(&codec{encoding.GetCodec("proto")}))
+	server.RegisterService(&ngrpc.ServiceDesc{
+		ServiceName: "app.Hold",
+		HandlerType: (*proxyService)(nil),
+		Streams: []ngrpc.StreamDesc{{
+			StreamName:    "Hold",
+			ServerStreams: true,
+			ClientStreams: true,
+			Handler: func(srv interface{}, stream ngrpc.ServerStream) error {
+				<-stream.Context().Done()
+				return nil
+			},
+		}},
+	}, &Proxy{})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(l)
+
+	conn, err := ngrpc.Dial(l.Addr().String(), ngrpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// client holds the stream open
+	_, err = conn.NewStream(
+		context.Background(),
+		&ngrpc.StreamDesc{ServerStreams: true, ClientStreams: true},
+		"/app.Hold/Hold",
+	)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 50)
+
+	start := time.Now()
+	svc.gracefulStop(server)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, EventForceStop, <-events)
+}
+
+func Test_Service_WaitStop(t *testing.T) {
+	svc := &Service{cfg: &Config{}}
+
+	release := make(chan struct{})
+	active := make(chan struct{})
+
+	svc.grpc = ngrpc.NewServer(ngrpc.CustomCodec(&codec{encoding.GetCodec("proto")}))
+	svc.grpc.RegisterService(&ngrpc.ServiceDesc{
+		ServiceName: "app.Slow",
+		HandlerType: (*proxyService)(nil),
+		Methods: []ngrpc.MethodDesc{{
+			MethodName: "Wait",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ ngrpc.UnaryServerInterceptor) (interface{}, error) {
+				in := rawMessage{}
+				if err := dec(&in); err != nil {
+					return nil, err
+				}
+
+				close(active)
+				<-release
+				return in, nil
+			},
+		}},
+	}, &Proxy{})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	served := make(chan struct{})
+	go func() {
+		svc.grpc.Serve(l)
+		svc.waitStop()
+		close(served)
+	}()
+
+	conn, err := ngrpc.Dial(
+		l.Addr().String(),
+		ngrpc.WithInsecure(),
+		ngrpc.WithDefaultCallOptions(ngrpc.CallCustomCodec(&codec{encoding.GetCodec("proto")})),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	result := make(chan error)
+	go func() {
+		out := rawMessage{}
+		result <- conn.Invoke(context.Background(), "/app.Slow/Wait", rawMessage("request"), &out)
+	}()
+
+	<-active
+	svc.Stop()
+	svc.Stop()
+
+	// workers must be kept while the call is active
+	select {
+	case <-served:
+		t.Error("serve must wait for active calls")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	close(release)
+	assert.NoError(t, <-result)
+	<-served
+}
+
+// streamWorkers counts stream worker goroutines of running grpc servers.
+func streamWorkers() int {
+	buf := make([]byte, 1<<20)
+	stack := string(buf[:runtime.Stack(buf, true)])
+
+	return strings.Count(stack, "created by google.golang.org/grpc.(*Server).initServerWorkers")
+}
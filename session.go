@@ -0,0 +1,389 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spiral/goridge"
+	"github.com/spiral/roadrunner"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bidirectional and server streams (and client streams when StreamSessions is enabled) are served by dedicated PHP
+// worker (session) spawned for the lifetime of the stream, worker receives RR_GRPC_STREAM=true env variable.
+// Session uses the same header/body protocol as regular RoadRunner workers: every frame is a control (JSON) header
+// followed by message body.
+//
+// Proxy sends rpcContext header for every client message and header with frame "close"
+// (and empty body) once the client closes its side of the stream. Worker responds with frames "data"
+// (or empty header) for every message to be sent to the client and frame "close" to complete the call,
+// errors are reported using standard worker error with "code|:|message|:|details" agreement. Data frames (and
+// "close" frame of streams) carry response headers and trailers the same way as regular worker responses, headers
+// must be set before the first message is sent, trailers are merged and sent once the call is complete.
+//
+// Client streaming calls are complete once the worker sends single "data" frame followed by "close" (usually
+// after receiving "close" frame from the proxy), client messages are fed to the worker as they arrive.
+//
+// Next worker frame is read only after the previous message is accepted by the client, slow clients block the
+// worker on write instead of being buffered by the proxy. Messages in every direction are delivered in order,
+// no ordering is guaranteed between directions. Session worker is stopped once the call is complete, killed
+// on client cancellation or when it fails to stop within the pool destroy timeout. No frames are sent to the
+// worker once it's asked to stop.
+const (
+	frameData  = "data"
+	frameClose = "close"
+)
+
+// sessionLimitError is returned to calls exceeding the number of session workers (MaxSessions).
+var sessionLimitError = status.Error(codes.ResourceExhausted, "too many active sessions")
+
+// errSessionClosed is returned when frame is sent to the stopping worker.
+var errSessionClosed = errors.New("session is closed")
+
+// session frame header
+type frameContext struct {
+	Frame string `json:"frame"`
+}
+
+// sessionFactory creates session workers.
+type sessionFactory struct {
+	cmd     func() *exec.Cmd
+	timeout time.Duration
+
+	// slots limits number of running session workers, nil when not limited
+	slots chan struct{}
+}
+
+// session is PHP worker attached to a single stream.
+type session struct {
+	cmd     *exec.Cmd
+	rl      goridge.Relay
+	stderr  *bytes.Buffer
+	timeout time.Duration
+	once    sync.Once
+
+	// mu serializes frames sent to the worker, no frames are sent once the worker is closing
+	mu      sync.Mutex
+	closing bool
+
+	// releases session slot
+	release func()
+}
+
+// newSession spawns new session worker, errors are converted into grpc errors. Workers exceeding the number of
+// slots are not started.
+func (f *sessionFactory) newSession() (*session, error) {
+	s := &session{cmd: f.cmd(), stderr: &bytes.Buffer{}, timeout: f.timeout, release: func() {}}
+	s.cmd.Stderr = s.stderr
+
+	if f.slots != nil {
+		select {
+		case f.slots <- struct{}{}:
+			s.release = func() { <-f.slots }
+		default:
+			return nil, sessionLimitError
+		}
+	}
+
+	in, err := s.cmd.StdoutPipe()
+	if err != nil {
+		s.release()
+		return nil, wrapError(err)
+	}
+
+	out, err := s.cmd.StdinPipe()
+	if err != nil {
+		s.release()
+		return nil, wrapError(err)
+	}
+
+	if err := s.cmd.Start(); err != nil {
+		s.release()
+		return nil, wrapError(err)
+	}
+
+	s.rl = goridge.NewPipeRelay(in, out)
+	return s, nil
+}
+
+// send sends single frame to the worker, frames can be sent concurrently.
+func (s *session) send(header []byte, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return errSessionClosed
+	}
+
+	if err := s.rl.Send(header, goridge.PayloadControl|goridge.PayloadRaw); err != nil {
+		return err
+	}
+
+	return s.rl.Send(body, goridge.PayloadRaw)
+}
+
+// receive reads next frame from the worker, frame header is returned as payload context.
+func (s *session) receive() (frame string, resp *roadrunner.Payload, err error) {
+	header, pr, err := s.rl.Receive()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !pr.HasFlag(goridge.PayloadControl) {
+		return "", nil, errors.New("mailformed worker response")
+	}
+
+	if pr.HasFlag(goridge.PayloadError) {
+		return "", nil, roadrunner.JobError(header)
+	}
+
+	fc := frameContext{Frame: frameData}
+	if len(header) != 0 {
+		if err := json.Unmarshal(header, &fc); err != nil {
+			return "", nil, err
+		}
+	}
+
+	body, _, err := s.rl.Receive()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return fc.Frame, &roadrunner.Payload{Context: header, Body: body}, nil
+}
+
+// kill terminates the worker immediately.
+func (s *session) kill() {
+	s.cmd.Process.Kill()
+}
+
+// close stops the worker and waits for the process to exit, worker is killed if it fails to stop in time. Frame
+// being forwarded is completed before the stop command, forwarding of further frames is stopped.
+func (s *session) close() (err error) {
+	s.once.Do(func() {
+		defer s.release()
+
+		done := make(chan error, 1)
+		go func() {
+			s.mu.Lock()
+			s.closing = true
+
+			// same as roadrunner worker stop command
+			s.rl.Send([]byte(`{"stop":true}`), goridge.PayloadControl)
+			s.mu.Unlock()
+
+			done <- s.cmd.Wait()
+		}()
+
+		select {
+		case err = <-done:
+		case <-time.After(s.timeout):
+			// unblocks frames written to the stuck worker
+			s.kill()
+			err = <-done
+		}
+	})
+
+	return err
+}
+
+// error returns worker error produced by crashed process.
+func (s *session) error(err error) error {
+	if _, ok := err.(roadrunner.JobError); ok {
+		return err
+	}
+
+	s.close()
+	if msg := strings.TrimSpace(s.stderr.String()); msg != "" {
+		return fmt.Errorf("worker error: %s", msg)
+	}
+
+	return fmt.Errorf("worker error: %s", err)
+}
+
+// Generate session (bidirectional stream) handler proxy.
+func (p *Proxy) sessionHandler(m streamMethod) grpc.StreamHandler {
+	return func(srv interface{}, stream grpc.ServerStream) error {
+		if p.sf == nil {
+			return status.Error(codes.Unimplemented, "stream requires session worker")
+		}
+
+		s, err := p.sf.newSession()
+		if err != nil {
+			return err
+		}
+		defer s.close()
+
+		if sp := spanFromContext(stream.Context()); sp != nil {
+			sp.PID = s.cmd.Process.Pid
+		}
+
+		ctx, cancel := context.WithCancel(stream.Context())
+		defer cancel()
+
+		// kill the worker as soon as the client is gone
+		go func() {
+			<-ctx.Done()
+			if stream.Context().Err() != nil {
+				s.kill()
+			}
+		}()
+
+		go p.forwardFrames(stream, s, m.name)
+
+		for sent := 0; ; sent++ {
+			frame, resp, err := s.receive()
+			if err != nil {
+				if stream.Context().Err() != nil {
+					return status.FromContextError(stream.Context().Err()).Err()
+				}
+
+				return wrapError(s.error(err))
+			}
+
+			header, trailer, err := responseMetadata(resp)
+			if err != nil {
+				return err
+			}
+
+			if header.Len() != 0 {
+				if err := setStreamHeader(stream, header); err != nil {
+					return err
+				}
+			}
+
+			stream.SetTrailer(trailer)
+
+			if frame == frameClose {
+				if !m.serverStreams && sent == 0 {
+					return status.Error(codes.Internal, "worker closed the stream without response")
+				}
+
+				return nil
+			}
+
+			// client streaming calls produce exactly one response
+			if !m.serverStreams && sent != 0 {
+				return status.Error(codes.Internal, "worker sent multiple responses to client stream")
+			}
+
+			if err := stream.SendMsg(rawMessage(resp.Body)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// forwardFrames forwards client messages to the session worker until client closes the stream or the worker is
+// closed.
+func (p *Proxy) forwardFrames(stream grpc.ServerStream, s *session, method string) {
+	for {
+		in := rawMessage{}
+		if err := stream.RecvMsg(&in); err != nil {
+			if err == io.EOF {
+				header, _ := json.Marshal(frameContext{Frame: frameClose})
+				s.send(header, nil)
+			}
+
+			return
+		}
+
+		payload, err := p.makePayload(stream.Context(), method, in)
+		if err != nil {
+			return
+		}
+
+		if err := s.send(payload.Context, payload.Body); err != nil {
+			return
+		}
+	}
+}
+
+// sessionExec executes single call using dedicated session worker, worker receives the payload followed by
+// "close" frame and must respond with single "data" frame. Worker is killed as soon as the call is cancelled.
+func (p *Proxy) sessionExec(ctx context.Context, payload *roadrunner.Payload) (*roadrunner.Payload, error) {
+	if p.sf == nil {
+		return nil, status.Error(codes.Unimplemented, "call requires session worker")
+	}
+
+	s, err := p.sf.newSession()
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+
+	if sp := spanFromContext(ctx); sp != nil {
+		sp.PID = s.cmd.Process.Pid
+	}
+
+	// done is closed before the session is stopped, killing completed (or exited) process is a no-op
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.kill()
+		case <-done:
+		}
+	}()
+
+	// worker failures are reported on receive
+	header, _ := json.Marshal(frameContext{Frame: frameClose})
+	if err := s.send(payload.Context, payload.Body); err == nil {
+		s.send(header, nil)
+	}
+
+	var resp *roadrunner.Payload
+	for {
+		frame, data, err := s.receive()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, status.FromContextError(ctx.Err()).Err()
+			}
+
+			return nil, wrapError(s.error(err))
+		}
+
+		if frame == frameClose {
+			break
+		}
+
+		if resp != nil {
+			return nil, status.Error(codes.Internal, "worker sent multiple responses to unary call")
+		}
+
+		// response metadata is carried by the data frame
+		resp = data
+	}
+
+	if resp == nil {
+		return nil, status.Error(codes.Internal, "worker closed the session without response")
+	}
+
+	return resp, nil
+}
+
+// sessionCommand creates command factory for session workers.
+func sessionCommand(cfg *roadrunner.ServerConfig, env map[string]string) func() *exec.Cmd {
+	var args = strings.Split(cfg.Command, " ")
+	return func() *exec.Cmd {
+		cmd := exec.Command(args[0], args[1:]...)
+
+		cmd.Env = append(os.Environ(), "RR_RELAY=pipes", "RR_GRPC=true", "RR_GRPC_STREAM=true")
+		for k, v := range env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", strings.ToUpper(k), v))
+		}
+
+		return cmd
+	}
+}
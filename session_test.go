@@ -0,0 +1,369 @@
+package grpc
+
+import (
+	"bytes"
+	"github.com/spiral/goridge"
+	"github.com/spiral/roadrunner"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func Test_Session_Echo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	// cat mirrors every frame back to the proxy
+	p := NewProxy("app.Echo", "", nil)
+	p.sf = &sessionFactory{
+		cmd:     func() *exec.Cmd { return exec.Command("cat") },
+		timeout: time.Millisecond * 100,
+	}
+
+	s := &mockStream{ctx: context.Background(), in: [][]byte{[]byte("a"), []byte("b")}}
+	assert.NoError(t, p.sessionHandler(streamMethod{name: "Echo", serverStreams: true, clientStreams: true})(nil, s))
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b")}, s.out)
+}
+
+func Test_Session_Dead(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	p := NewProxy("app.Echo", "", nil)
+	p.sf = &sessionFactory{
+		cmd:     sessionCommand(&roadrunner.ServerConfig{Command: "false"}, nil),
+		timeout: time.Millisecond * 100,
+	}
+
+	// crashed worker is reported as internal error
+	s := &mockStream{ctx: context.Background(), in: [][]byte{[]byte("a")}}
+	err := p.sessionHandler(streamMethod{name: "Echo", serverStreams: true, clientStreams: true})(nil, s)
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func Test_Session_Cancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	p := NewProxy("app.Echo", "", nil)
+	p.sf = &sessionFactory{
+		cmd:     func() *exec.Cmd { return exec.Command("sleep", "10") },
+		timeout: time.Second * 10,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*50, cancel)
+
+	start := time.Now()
+	s := &mockStream{ctx: ctx, in: [][]byte{[]byte("a")}}
+	err := p.sessionHandler(streamMethod{name: "Echo", serverStreams: true, clientStreams: true})(nil, s)
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func Test_Session_NoFactory(t *testing.T) {
+	p := NewProxy("app.Echo", "", nil)
+
+	s := &mockStream{ctx: context.Background()}
+	assert.Error(t, p.sessionHandler(streamMethod{name: "Echo", serverStreams: true, clientStreams: true})(nil, s))
+}
+
+func Test_Session_ServerStream(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	p := NewProxy("app.Echo", "", nil)
+	p.sf = &sessionFactory{
+		cmd:     func() *exec.Cmd { return exec.Command("cat") },
+		timeout: time.Millisecond * 100,
+	}
+	p.RegisterStream("Echo", true, false)
+
+	// pool is not configured, call must be served by session worker
+	s := &mockStream{ctx: context.Background(), in: [][]byte{[]byte("a")}}
+	assert.NoError(t, p.ServiceDesc().Streams[0].Handler(nil, s))
+	assert.Equal(t, [][]byte{[]byte("a")}, s.out)
+
+	// buffered streams are served by pool workers, request is rejected before dispatching
+	p.bufferedStreams = true
+	p.maxRequest = 1
+
+	s = &mockStream{ctx: context.Background(), in: [][]byte{[]byte("ab")}}
+	assert.Equal(t, codes.InvalidArgument, status.Code(p.ServiceDesc().Streams[0].Handler(nil, s)))
+}
+
+func Test_Session_ClientStream(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	p := NewProxy("app.Echo", "", nil)
+	p.sf = &sessionFactory{
+		cmd:     func() *exec.Cmd { return exec.Command("cat") },
+		timeout: time.Millisecond * 100,
+	}
+	p.streamSessions = true
+	p.RegisterStream("Echo", false, true)
+	handler := p.ServiceDesc().Streams[0].Handler
+
+	s := &mockStream{ctx: context.Background(), in: [][]byte{[]byte("a")}}
+	assert.NoError(t, handler(nil, s))
+	assert.Equal(t, [][]byte{[]byte("a")}, s.out)
+
+	// cat mirrors every message, client stream must produce exactly one response
+	s = &mockStream{ctx: context.Background(), in: [][]byte{[]byte("a"), []byte("b")}}
+	assert.Error(t, handler(nil, s))
+	assert.Equal(t, [][]byte{[]byte("a")}, s.out)
+
+	s = &mockStream{ctx: context.Background()}
+	assert.Error(t, handler(nil, s))
+	assert.Len(t, s.out, 0)
+}
+
+func Test_Session_Exec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	p := NewProxy("app.Echo", "", nil)
+	p.sf = &sessionFactory{
+		cmd:     func() *exec.Cmd { return exec.Command("cat") },
+		timeout: time.Millisecond * 100,
+	}
+	p.killOnCancel["Echo"] = true
+
+	resp, err := p.exec(context.Background(), "Echo", rawMessage("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a"), resp.Body)
+}
+
+func Test_Session_Exec_Cancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	var cmd *exec.Cmd
+	p := NewProxy("app.Report", "", nil)
+	p.sf = &sessionFactory{
+		cmd: func() *exec.Cmd {
+			cmd = exec.Command("sleep", "10")
+			return cmd
+		},
+		timeout: time.Second * 10,
+	}
+	p.killOnCancel["Report"] = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*50, cancel)
+
+	start := time.Now()
+	_, err := p.exec(ctx, "Report", rawMessage("a"))
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	assert.True(t, time.Since(start) < time.Second)
+
+	// worker is killed, not waited for
+	assert.NotNil(t, cmd.ProcessState)
+	assert.False(t, cmd.ProcessState.Success())
+}
+
+func Test_Session_Exec_Timeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	var cmd *exec.Cmd
+	p := NewProxy("app.Report", "", nil)
+	p.sf = &sessionFactory{
+		cmd: func() *exec.Cmd {
+			cmd = exec.Command("sleep", "10")
+			return cmd
+		},
+		timeout: time.Second * 10,
+	}
+	p.killOnCancel["Report"] = true
+	p.timeout = time.Millisecond * 50
+
+	start := time.Now()
+	_, err := p.exec(context.Background(), "Report", rawMessage("a"))
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.True(t, time.Since(start) < time.Second)
+
+	assert.NotNil(t, cmd.ProcessState)
+	assert.False(t, cmd.ProcessState.Success())
+}
+
+func Test_Session_Exec_ClientDeadline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	p := NewProxy("app.Report", "", nil)
+	p.sf = &sessionFactory{
+		cmd:     func() *exec.Cmd { return exec.Command("sleep", "10") },
+		timeout: time.Second * 10,
+	}
+	p.killOnCancel["Report"] = true
+	p.timeout = time.Second * 10
+
+	// shorter client deadline takes precedence
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.exec(ctx, "Report", rawMessage("a"))
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func Test_Session_Limit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	p := NewProxy("app.Echo", "", nil)
+	p.sf = &sessionFactory{
+		cmd:     func() *exec.Cmd { return exec.Command("cat") },
+		timeout: time.Millisecond * 100,
+		slots:   make(chan struct{}, 1),
+	}
+	p.killOnCancel["Echo"] = true
+
+	active, err := p.sf.newSession()
+	assert.NoError(t, err)
+
+	// sessions exceeding the limit are rejected
+	s := &mockStream{ctx: context.Background(), in: [][]byte{[]byte("a")}}
+	err = p.sessionHandler(streamMethod{name: "Echo", serverStreams: true, clientStreams: true})(nil, s)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = p.exec(context.Background(), "Echo", rawMessage("a"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// slot is released once the session is closed
+	active.close()
+
+	resp, err := p.exec(context.Background(), "Echo", rawMessage("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a"), resp.Body)
+	assert.Len(t, p.sf.slots, 0)
+}
+
+func Test_Session_CloseForwarding(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	// worker consumes frames without responding, it's killed once it ignores the stop command
+	f := &sessionFactory{
+		cmd:     func() *exec.Cmd { return exec.Command("dd", "of=/dev/null", "status=none") },
+		timeout: time.Millisecond * 100,
+	}
+
+	s, err := f.newSession()
+	assert.NoError(t, err)
+
+	// frames are sent concurrently with close, stop command must not interleave with them
+	stopped := make(chan error)
+	go func() {
+		for {
+			if err := s.send([]byte(`{}`), []byte("a")); err != nil {
+				stopped <- err
+				return
+			}
+		}
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+	s.close()
+	assert.Equal(t, errSessionClosed, <-stopped)
+}
+
+// Test_SessionWorker is session worker process started by the tests, skipped otherwise. Worker responds to every
+// message with data frame carrying response metadata and completes the call once the proxy closes the stream.
+func Test_SessionWorker(t *testing.T) {
+	if os.Getenv("GRPC_TEST_WORKER") == "" {
+		t.Skip("worker process")
+	}
+
+	rl := goridge.NewPipeRelay(os.Stdin, os.Stdout)
+	for {
+		header, p, err := rl.Receive()
+		if err != nil || !p.HasFlag(goridge.PayloadRaw) {
+			// stop command
+			os.Exit(0)
+		}
+
+		body, _, err := rl.Receive()
+		if err != nil {
+			os.Exit(0)
+		}
+
+		if bytes.Contains(header, []byte(`"frame":"close"`)) {
+			rl.Send([]byte(`{"frame":"close","trailers":{"x-done":["1"]}}`), goridge.PayloadControl|goridge.PayloadRaw)
+			rl.Send(nil, goridge.PayloadRaw)
+			continue
+		}
+
+		data := `{"frame":"data","headers":{"x-page":["1"]},"trailers":{"token-bin":["AP8="]}}`
+		rl.Send([]byte(data), goridge.PayloadControl|goridge.PayloadRaw)
+		rl.Send(body, goridge.PayloadRaw)
+	}
+}
+
+// sessionWorker creates session factory running Test_SessionWorker.
+func sessionWorker() *sessionFactory {
+	return &sessionFactory{
+		cmd: func() *exec.Cmd {
+			cmd := exec.Command(os.Args[0], "-test.run=^Test_SessionWorker$")
+			cmd.Env = append(os.Environ(), "GRPC_TEST_WORKER=1")
+			return cmd
+		},
+		timeout: time.Second,
+	}
+}
+
+func Test_Session_ResponseMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	p := NewProxy("app.Echo", "", nil)
+	p.sf = sessionWorker()
+
+	s := &mockStream{ctx: context.Background(), in: [][]byte{[]byte("a"), []byte("b")}}
+	assert.NoError(t, p.sessionHandler(streamMethod{name: "Echo", serverStreams: true, clientStreams: true})(nil, s))
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b")}, s.out)
+
+	assert.Equal(t, []string{"1", "1"}, s.header.Get("x-page"))
+	assert.Equal(t, []string{"\x00\xff", "\x00\xff"}, s.trailer.Get("token-bin"))
+	assert.Equal(t, []string{"1"}, s.trailer.Get("x-done"))
+}
+
+func Test_Session_Exec_ResponseMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+
+	p := NewProxy("app.Echo", "", nil)
+	p.sf = sessionWorker()
+	p.killOnCancel["Echo"] = true
+
+	resp, err := p.exec(context.Background(), "Echo", rawMessage("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a"), resp.Body)
+
+	header, trailer, err := responseMetadata(resp)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, header.Get("x-page"))
+	assert.Equal(t, []string{"\x00\xff"}, trailer.Get("token-bin"))
+}
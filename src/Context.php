@@ -9,17 +9,24 @@ declare(strict_types=1);
 
 namespace Spiral\GRPC;
 
-final class Context implements ContextInterface
+final class Context implements ResponseContextInterface
 {
+    /** Suffix of binary metadata keys. */
+    private const BINARY_SUFFIX = '-bin';
+
     /** @var array */
     private $values;
 
+    /** @var \stdClass Response headers and trailers, shared by copies of the context. */
+    private $response;
+
     /**
      * @param array $values
      */
     public function __construct(array $values)
     {
         $this->values = $values;
+        $this->response = (object)['headers' => [], 'trailers' => []];
     }
 
     /**
@@ -48,4 +55,53 @@ final class Context implements ContextInterface
     {
         return $this->values;
     }
+
+    /**
+     * @inheritdoc
+     */
+    public function setHeader(string $key, $value)
+    {
+        $this->response->headers[strtolower($key)] = $this->encode($key, $value);
+    }
+
+    /**
+     * @inheritdoc
+     */
+    public function setTrailer(string $key, $value)
+    {
+        $this->response->trailers[strtolower($key)] = $this->encode($key, $value);
+    }
+
+    /**
+     * @inheritdoc
+     */
+    public function getHeaders(): array
+    {
+        return $this->response->headers;
+    }
+
+    /**
+     * @inheritdoc
+     */
+    public function getTrailers(): array
+    {
+        return $this->response->trailers;
+    }
+
+    /**
+     * Convert metadata value into list of strings, values of binary keys are base64 encoded.
+     *
+     * @param string          $key
+     * @param string|string[] $value
+     * @return array
+     */
+    private function encode(string $key, $value): array
+    {
+        $values = array_map('strval', array_values((array)$value));
+        if (substr(strtolower($key), -strlen(self::BINARY_SUFFIX)) === self::BINARY_SUFFIX) {
+            $values = array_map('base64_encode', $values);
+        }
+
+        return $values;
+    }
 }
\ No newline at end of file
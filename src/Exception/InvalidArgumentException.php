@@ -0,0 +1,17 @@
+<?php
+/**
+ * Spiral Framework.
+ *
+ * @license   MIT
+ * @author    Anton Titov (Wolfy-J)
+ */
+declare(strict_types=1);
+
+namespace Spiral\GRPC\Exception;
+
+use Spiral\GRPC\StatusCode;
+
+class InvalidArgumentException extends GRPCException
+{
+    protected const CODE = StatusCode::INVALID_ARGUMENT;
+}
\ No newline at end of file
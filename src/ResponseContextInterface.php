@@ -0,0 +1,48 @@
+<?php
+/**
+ * Spiral Framework.
+ *
+ * @license   MIT
+ * @author    Anton Titov (Wolfy-J)
+ */
+declare(strict_types=1);
+
+namespace Spiral\GRPC;
+
+/**
+ * Call context able to carry response metadata (headers and trailers) back to the client.
+ */
+interface ResponseContextInterface extends ContextInterface
+{
+    /**
+     * Set response header sent to the client, previous values of the header are replaced. Values of binary
+     * headers (keys ending with -bin) are base64 encoded. Response metadata is shared by every context of the call.
+     *
+     * @param string          $key
+     * @param string|string[] $value
+     */
+    public function setHeader(string $key, $value);
+
+    /**
+     * Set response trailer sent to the client after the response, previous values of the trailer are replaced.
+     * Values of binary trailers (keys ending with -bin) are base64 encoded.
+     *
+     * @param string          $key
+     * @param string|string[] $value
+     */
+    public function setTrailer(string $key, $value);
+
+    /**
+     * Return response headers (lowercase keys, encoded values).
+     *
+     * @return array
+     */
+    public function getHeaders(): array;
+
+    /**
+     * Return response trailers (lowercase keys, encoded values).
+     *
+     * @return array
+     */
+    public function getTrailers(): array;
+}
\ No newline at end of file
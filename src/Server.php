@@ -21,6 +21,18 @@ use Spiral\RoadRunner\Worker;
  */
 final class Server
 {
+    /** Method reporting registered services and their methods (startup validation). */
+    public const MANIFEST_METHOD = ':manifest';
+
+    /** Built-in no-op method used to warm up workers. */
+    public const WARMUP_METHOD = ':warmup';
+
+    /** Session frame carrying the response message. */
+    public const FRAME_DATA = 'data';
+
+    /** Session frame completing the call (sent by both sides). */
+    public const FRAME_CLOSE = 'close';
+
     /** @var InvokerInterface */
     private $invoker;
 
@@ -52,29 +64,51 @@ final class Server
     }
 
     /**
-     * Serve GRPC over given RoadRunner worker.
+     * Serve GRPC over given RoadRunner worker. Session workers (started with RR_GRPC_STREAM env variable for calls
+     * killed on cancellation) respond with "data" frame followed by "close" frame, "close" frames of the server
+     * are skipped since the call is complete once the response is sent. Streaming methods are not supported.
      *
      * @param Worker        $worker
      * @param callable|null $finalize
      */
     public function serve(Worker $worker, callable $finalize = null)
     {
+        $session = getenv('RR_GRPC_STREAM') === 'true';
+
         while (true) {
             $body = $worker->receive($ctx);
             if (empty($body) && empty($ctx)) {
                 return;
             }
 
+            $ctx = json_decode($ctx, true);
+            if ($session && ($ctx['frame'] ?? null) === self::FRAME_CLOSE) {
+                continue;
+            }
+
             try {
-                $ctx = json_decode($ctx, true);
+
+                // call values computed by the server are available under :values key
+                $values = $ctx['context'] ?? [];
+                if (!empty($ctx['values'])) {
+                    $values[':values'] = $ctx['values'];
+                }
+
+                $context = new Context($values);
+
                 $resp = $this->invoke(
                     $ctx['service'],
                     $ctx['method'],
-                    $ctx['context'] ?? [],
+                    $context,
                     $body
                 );
 
-                $worker->send($resp);
+                if ($session) {
+                    $worker->send($resp, $this->packResponse($context, isset($values[':trace.id']), self::FRAME_DATA));
+                    $worker->send('', json_encode(['frame' => self::FRAME_CLOSE]));
+                } else {
+                    $worker->send($resp, $this->packResponse($context, isset($values[':trace.id'])));
+                }
             } catch (GRPCException $e) {
                 $worker->error($this->packError($e));
             } catch (\Throwable $e) {
@@ -90,10 +124,10 @@ final class Server
     /**
      * Invoke service method with binary payload and return the response.
      *
-     * @param string $service
-     * @param string $method
-     * @param array  $context
-     * @param string $body
+     * @param string           $service
+     * @param string           $method
+     * @param ContextInterface $context
+     * @param string           $body
      * @return string
      *
      * @throws GRPCException
@@ -102,14 +136,72 @@ final class Server
     protected function invoke(
         string $service,
         string $method,
-        array $context,
+        ContextInterface $context,
         ?string $body
     ): string {
+        if ($method === self::MANIFEST_METHOD) {
+            return json_encode((object)$this->getManifest());
+        }
+
+        if ($method === self::WARMUP_METHOD) {
+            return '';
+        }
+
         if (!isset($this->services[$service])) {
             throw new NotFoundException("Service `{$service}` not found.", StatusCode::NOT_FOUND);
         }
 
-        return $this->services[$service]->invoke($method, new Context($context ?? []), $body);
+        return $this->services[$service]->invoke($method, $context, $body);
+    }
+
+    /**
+     * Packs response headers and trailers set by the service into response header, worker PID is reported for
+     * traced calls. Session responses are marked with the frame name. Returns null when there is nothing to report.
+     *
+     * @param ContextInterface $context
+     * @param bool             $traced
+     * @param string|null      $frame
+     * @return string|null
+     */
+    private function packResponse(ContextInterface $context, bool $traced, string $frame = null): ?string
+    {
+        $header = [];
+        if ($frame !== null) {
+            $header['frame'] = $frame;
+        }
+
+        if ($context instanceof ResponseContextInterface && $context->getHeaders() !== []) {
+            $header['headers'] = $context->getHeaders();
+        }
+
+        if ($context instanceof ResponseContextInterface && $context->getTrailers() !== []) {
+            $header['trailers'] = $context->getTrailers();
+        }
+
+        if ($traced) {
+            $header['pid'] = getmypid();
+        }
+
+        return $header === [] ? null : json_encode($header);
+    }
+
+    /**
+     * List of methods of every registered service.
+     *
+     * @return array
+     */
+    protected function getManifest(): array
+    {
+        $manifest = [];
+        foreach ($this->services as $name => $service) {
+            $manifest[$name] = [];
+            foreach ($service->getMethods() as $method) {
+                /** @var Method $method */
+                $manifest[$name][] = $method->getName();
+            }
+        }
+
+        return $manifest;
     }
 
     /**
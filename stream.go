@@ -0,0 +1,144 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+)
+
+// Streaming messages are exchanged with PHP workers as a sequence of frames packed into
+// a single payload body. Every frame starts with 4 byte big-endian length of the message
+// followed by the message itself (same as GRPC message framing but without compression flag).
+//
+// Server streaming methods are served by session workers by default (see session.go), worker sends every
+// message as a separate frame and blocks once the client stops reading, no response is buffered by the proxy.
+// With BufferedStreams enabled server streams are served by pool workers, RoadRunner workers produce exactly
+// one response per request and the worker must return all of the stream messages in one response body. Proxy
+// forwards frames one by one using stream.SendMsg which blocks until the client is ready to accept more data
+// (HTTP/2 flow control), the whole worker response is held in memory until the stream is complete.
+//
+// For client streaming methods proxy reads all of the client messages until the client closes
+// its side of the stream and sends them to the worker as one frame sequence, the worker responds
+// with a single message. Entire upload is held in memory while the call is being processed, use
+// MaxRecvMsgSize and worker pool limits to protect the server from large uploads. With StreamSessions
+// enabled client messages are fed to the session worker one by one as they arrive, memory usage depends
+// only on what the worker aggregates.
+const frameHeader = 4
+
+// errFrame indicates that worker returned malformed stream frame.
+var errFrame = errors.New("malformed stream frame")
+
+// packFrames packs set of messages into one frame sequence.
+func packFrames(messages ...[]byte) []byte {
+	size := 0
+	for _, m := range messages {
+		size += frameHeader + len(m)
+	}
+
+	data := make([]byte, 0, size)
+	for _, m := range messages {
+		var h [frameHeader]byte
+		binary.BigEndian.PutUint32(h[:], uint32(len(m)))
+
+		data = append(data, h[:]...)
+		data = append(data, m...)
+	}
+
+	return data
+}
+
+// nextFrame reads first frame from the given sequence and returns the rest of the data.
+func nextFrame(data []byte) (frame rawMessage, tail []byte, err error) {
+	if len(data) < frameHeader {
+		return nil, nil, errFrame
+	}
+
+	size := binary.BigEndian.Uint32(data)
+	if uint64(len(data)-frameHeader) < uint64(size) {
+		return nil, nil, errFrame
+	}
+
+	return rawMessage(data[frameHeader : frameHeader+size]), data[frameHeader+size:], nil
+}
+
+// Generate stream handler proxy.
+func (p *Proxy) streamHandler(m streamMethod) grpc.StreamHandler {
+	return func(srv interface{}, stream grpc.ServerStream) error {
+		var (
+			in  rawMessage
+			err error
+		)
+
+		if m.clientStreams {
+			in, err = recvFrames(stream)
+		} else {
+			err = stream.RecvMsg(&in)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		resp, err := p.exec(stream.Context(), m.name, in)
+		if err != nil {
+			return err
+		}
+
+		header, trailer, err := responseMetadata(resp)
+		if err != nil {
+			return err
+		}
+
+		// headers must be set before the first message is sent
+		if header.Len() != 0 {
+			if err := setStreamHeader(stream, header); err != nil {
+				return err
+			}
+		}
+
+		stream.SetTrailer(trailer)
+
+		if !m.serverStreams {
+			return stream.SendMsg(rawMessage(resp.Body))
+		}
+
+		return sendFrames(stream, resp.Body)
+	}
+}
+
+// recvFrames reads all client messages and packs them into one frame sequence.
+func recvFrames(stream grpc.ServerStream) (rawMessage, error) {
+	messages := make([][]byte, 0)
+	for {
+		in := rawMessage{}
+		if err := stream.RecvMsg(&in); err != nil {
+			if err == io.EOF {
+				return packFrames(messages...), nil
+			}
+
+			return nil, err
+		}
+
+		messages = append(messages, in)
+	}
+}
+
+// sendFrames sends every frame of worker response to the client stream.
+func sendFrames(stream grpc.ServerStream, data []byte) (err error) {
+	var frame rawMessage
+	for len(data) != 0 {
+		if frame, data, err = nextFrame(data); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		// blocks until client is able to receive the message, fails if client is gone
+		if err = stream.SendMsg(frame); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+	"io"
+	"testing"
+)
+
+func TestStream_Frames(t *testing.T) {
+	data := packFrames([]byte("hello"), []byte{}, []byte("world"))
+
+	frame, data, err := nextFrame(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(frame))
+
+	frame, data, err = nextFrame(data)
+	assert.NoError(t, err)
+	assert.Len(t, frame, 0)
+
+	frame, data, err = nextFrame(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(frame))
+	assert.Len(t, data, 0)
+}
+
+func TestStream_Frames_Malformed(t *testing.T) {
+	_, _, err := nextFrame([]byte{0, 0})
+	assert.Error(t, err)
+
+	_, _, err = nextFrame([]byte{0, 0, 0, 10, 1, 2})
+	assert.Error(t, err)
+}
+
+type mockStream struct {
+	ctx     context.Context
+	in      [][]byte
+	out     [][]byte
+	fail    error
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *mockStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+func (s *mockStream) SetTrailer(md metadata.MD)       { s.trailer = metadata.Join(s.trailer, md) }
+func (s *mockStream) Context() context.Context        { return s.ctx }
+
+func (s *mockStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *mockStream) SendMsg(m interface{}) error {
+	if s.fail != nil {
+		return s.fail
+	}
+
+	s.out = append(s.out, m.(rawMessage))
+	return nil
+}
+
+func (s *mockStream) RecvMsg(m interface{}) error {
+	if len(s.in) == 0 {
+		return io.EOF
+	}
+
+	*(m.(*rawMessage)) = s.in[0]
+	s.in = s.in[1:]
+	return nil
+}
+
+func TestStream_SendFrames(t *testing.T) {
+	s := &mockStream{ctx: context.Background()}
+
+	assert.NoError(t, sendFrames(s, packFrames([]byte("a"), []byte("b"))))
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b")}, s.out)
+
+	assert.Error(t, sendFrames(s, []byte{0, 0, 0, 10}))
+
+	s.fail = io.ErrClosedPipe
+	assert.Equal(t, io.ErrClosedPipe, sendFrames(s, packFrames([]byte("a"))))
+}
+
+func TestStream_RecvFrames(t *testing.T) {
+	s := &mockStream{ctx: context.Background(), in: [][]byte{[]byte("a"), []byte("b")}}
+
+	data, err := recvFrames(s)
+	assert.NoError(t, err)
+	assert.Equal(t, packFrames([]byte("a"), []byte("b")), []byte(data))
+}
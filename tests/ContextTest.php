@@ -0,0 +1,49 @@
+<?php
+/**
+ * Spiral Framework.
+ *
+ * @license   MIT
+ * @author    Anton Titov (Wolfy-J)
+ */
+declare(strict_types=1);
+
+namespace Spiral\GRPC\Tests;
+
+use PHPUnit\Framework\TestCase;
+use Spiral\GRPC\Context;
+
+class ContextTest extends TestCase
+{
+    public function testHeaders()
+    {
+        $ctx = new Context([]);
+        $this->assertSame([], $ctx->getHeaders());
+
+        $ctx->setHeader('X-Rate-Limit', 10);
+        $ctx->setHeader('x-page', ['1', '2']);
+
+        $this->assertSame(['x-rate-limit' => ['10'], 'x-page' => ['1', '2']], $ctx->getHeaders());
+        $this->assertSame([], $ctx->getTrailers());
+
+        // values are replaced
+        $ctx->setHeader('x-page', '3');
+        $this->assertSame(['3'], $ctx->getHeaders()['x-page']);
+    }
+
+    public function testBinaryTrailers()
+    {
+        $ctx = new Context([]);
+        $ctx->setTrailer('Token-Bin', "\x00\xff");
+        $ctx->setTrailer('token', "\x00\xff");
+
+        $this->assertSame(['token-bin' => ['AP8='], 'token' => ["\x00\xff"]], $ctx->getTrailers());
+    }
+
+    public function testSharedByCopies()
+    {
+        $ctx = new Context([]);
+        $ctx->withValue('key', 'value')->setHeader('x-page', '1');
+
+        $this->assertSame(['x-page' => ['1']], $ctx->getHeaders());
+    }
+}
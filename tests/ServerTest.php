@@ -0,0 +1,79 @@
+<?php
+/**
+ * Spiral Framework.
+ *
+ * @license   MIT
+ * @author    Anton Titov (Wolfy-J)
+ */
+declare(strict_types=1);
+
+namespace Spiral\GRPC\Tests;
+
+use Metadata\MetadataInterface;
+use Metadata\MetadataService;
+use PHPUnit\Framework\TestCase;
+use Spiral\GRPC\Context;
+use Spiral\GRPC\Server;
+
+class ServerTest extends TestCase
+{
+    public function testResponseMetadata()
+    {
+        $server = new Server();
+        $server->registerService(MetadataInterface::class, new MetadataService());
+
+        $ctx = new Context([]);
+        $this->call($server, 'invoke', MetadataInterface::NAME, 'Paginate', $ctx, '');
+
+        $this->assertSame(
+            '{"headers":{"x-page":["2"]},"trailers":{"token-bin":["AP8="]}}',
+            $this->call($server, 'packResponse', $ctx, false)
+        );
+    }
+
+    public function testEmptyResponseMetadata()
+    {
+        $server = new Server();
+
+        $this->assertNull($this->call($server, 'packResponse', new Context([]), false));
+        $this->assertSame(
+            json_encode(['pid' => getmypid()]),
+            $this->call($server, 'packResponse', new Context([]), true)
+        );
+    }
+
+    public function testSessionResponseMetadata()
+    {
+        $server = new Server();
+        $server->registerService(MetadataInterface::class, new MetadataService());
+
+        $ctx = new Context([]);
+        $this->call($server, 'invoke', MetadataInterface::NAME, 'Paginate', $ctx, '');
+
+        $this->assertSame(
+            '{"frame":"data","headers":{"x-page":["2"]},"trailers":{"token-bin":["AP8="]}}',
+            $this->call($server, 'packResponse', $ctx, false, Server::FRAME_DATA)
+        );
+
+        $this->assertSame(
+            '{"frame":"data"}',
+            $this->call($server, 'packResponse', new Context([]), false, Server::FRAME_DATA)
+        );
+    }
+
+    /**
+     * Calls internal method of the server.
+     *
+     * @param Server $server
+     * @param string $method
+     * @param mixed  ...$args
+     * @return mixed
+     */
+    private function call(Server $server, string $method, ...$args)
+    {
+        $m = new \ReflectionMethod(Server::class, $method);
+        $m->setAccessible(true);
+
+        return $m->invokeArgs($server, $args);
+    }
+}
@@ -0,0 +1,26 @@
+<?php
+/**
+ * Spiral Framework.
+ *
+ * @license   MIT
+ * @author    Anton Titov (Wolfy-J)
+ */
+declare(strict_types=1);
+
+namespace Metadata;
+
+use Google\Protobuf\GPBEmpty;
+use Spiral\GRPC\ContextInterface;
+use Spiral\GRPC\ServiceInterface;
+
+interface MetadataInterface extends ServiceInterface
+{
+    const NAME = "metadata.Metadata";
+
+    /**
+     * @param ContextInterface $ctx
+     * @param GPBEmpty         $in
+     * @return GPBEmpty
+     */
+    public function Paginate(ContextInterface $ctx, GPBEmpty $in): GPBEmpty;
+}
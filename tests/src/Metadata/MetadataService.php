@@ -0,0 +1,31 @@
+<?php
+/**
+ * Spiral Framework.
+ *
+ * @license   MIT
+ * @author    Anton Titov (Wolfy-J)
+ */
+declare(strict_types=1);
+
+namespace Metadata;
+
+use Google\Protobuf\GPBEmpty;
+use Spiral\GRPC\ContextInterface;
+
+class MetadataService implements MetadataInterface
+{
+    /**
+     * Sets response header using copy of the context and binary trailer.
+     *
+     * @param ContextInterface $ctx
+     * @param GPBEmpty         $in
+     * @return GPBEmpty
+     */
+    public function Paginate(ContextInterface $ctx, GPBEmpty $in): GPBEmpty
+    {
+        $ctx->withValue('page', 2)->setHeader('X-Page', 2);
+        $ctx->setTrailer('token-bin', "\x00\xff");
+
+        return new GPBEmpty();
+    }
+}
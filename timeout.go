@@ -0,0 +1,147 @@
+package grpc
+
+import (
+	"fmt"
+	"github.com/spiral/roadrunner"
+	"time"
+)
+
+// runawayPoll defines how often pool workers are inspected to locate the worker of timed out call.
+const runawayPoll = time.Millisecond * 50
+
+// execTimeout returns maximal execution time of the method, zero means no limit.
+func (p *Proxy) execTimeout(method string) time.Duration {
+	if timeout, ok := p.timeouts[method]; ok {
+		return timeout
+	}
+
+	return p.timeout
+}
+
+// workerJob identifies job executed by the worker.
+type workerJob struct {
+	execs int64
+	busy  bool
+}
+
+// workerJobs returns jobs of pool workers before the call is dispatched.
+func workerJobs(rr *roadrunner.Server) map[*roadrunner.Worker]workerJob {
+	jobs := make(map[*roadrunner.Worker]workerJob)
+	for _, w := range rr.Workers() {
+		jobs[w] = workerJob{execs: w.State().NumExecs(), busy: w.State().Value() != roadrunner.StateReady}
+	}
+
+	return jobs
+}
+
+// killRunaway kills pool worker executing the call which exceeded it's execution time. Pool does not expose
+// the worker of the call, the worker is the one which took the next job after the call was dispatched and
+// still works on it (idle workers on the same job, busy workers on the next one, new workers on the first one).
+// Workers are inspected until single candidate remains (concurrent calls complete) or the call returns by
+// itself (done is closed). Single candidate is killed only once it's confirmed by the next inspection as the
+// worker of the call may complete the job right before done is closed. Killed worker is reported to removed
+// function when set.
+func killRunaway(
+	rr *roadrunner.Server,
+	jobs map[*roadrunner.Worker]workerJob,
+	timeout time.Duration,
+	done chan struct{},
+	removed func(w *roadrunner.Worker, reason string),
+) {
+	ticker := time.NewTicker(runawayPoll)
+	defer ticker.Stop()
+
+	// candidates of the previous inspection, workers taking jobs later are not related to the call
+	var known map[*roadrunner.Worker]bool
+	var single *roadrunner.Worker
+
+	for {
+		pool := rr.Pool()
+		if pool == nil {
+			return
+		}
+
+		var candidates []*roadrunner.Worker
+		for _, w := range pool.Workers() {
+			if w.State().Value() != roadrunner.StateWorking || (known != nil && !known[w]) {
+				continue
+			}
+
+			next := int64(0)
+			if job, ok := jobs[w]; ok {
+				next = job.execs
+				if job.busy {
+					next++
+				}
+			}
+
+			if w.State().NumExecs() == next {
+				candidates = append(candidates, w)
+			}
+		}
+
+		if len(candidates) == 0 {
+			return
+		}
+
+		if len(candidates) == 1 && single == candidates[0] {
+			killWorker(pool, candidates[0], timeout, done, removed)
+			return
+		}
+
+		known = make(map[*roadrunner.Worker]bool)
+		for _, w := range candidates {
+			known[w] = true
+		}
+
+		single = nil
+		if len(candidates) == 1 {
+			single = candidates[0]
+		}
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// killWorker removes the worker from the pool and kills it unless the call returned meanwhile.
+func killWorker(
+	pool roadrunner.Pool,
+	w *roadrunner.Worker,
+	timeout time.Duration,
+	done chan struct{},
+	removed func(w *roadrunner.Worker, reason string),
+) {
+	if isDone(done) {
+		return
+	}
+
+	// make sure worker is still on the call
+	execs := w.State().NumExecs()
+	if !pool.Remove(w, fmt.Errorf("max execution time reached (%s)", timeout)) {
+		return
+	}
+
+	if w.State().NumExecs() != execs || isDone(done) {
+		return
+	}
+
+	if removed != nil {
+		removed(w, StopReasonMaxExecutionTime)
+	}
+
+	go w.Kill()
+}
+
+// isDone returns true when the channel is closed.
+func isDone(done chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"github.com/spiral/roadrunner"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_KillRunaway(t *testing.T) {
+	rr := roadrunner.NewServer(echoWorkers(1))
+	assert.NoError(t, rr.Start())
+	defer rr.Stop()
+
+	jobs := workerJobs(rr)
+
+	result := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := rr.Exec(&roadrunner.Payload{Context: []byte("{}"), Body: []byte("sleep:10s")})
+		result <- err
+	}()
+
+	for rr.Workers()[0].State().Value() != roadrunner.StateWorking {
+		time.Sleep(time.Millisecond)
+	}
+
+	reasons := make(map[*roadrunner.Worker]string)
+	killRunaway(rr, jobs, time.Second, done, func(w *roadrunner.Worker, reason string) {
+		reasons[w] = reason
+	})
+
+	select {
+	case err := <-result:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("runaway worker was not killed")
+	}
+
+	assert.Len(t, reasons, 1)
+	for _, reason := range reasons {
+		assert.Equal(t, StopReasonMaxExecutionTime, reason)
+	}
+}
+
+func Test_KillRunaway_Done(t *testing.T) {
+	rr := roadrunner.NewServer(echoWorkers(2))
+	assert.NoError(t, rr.Start())
+	defer rr.Stop()
+
+	jobs := workerJobs(rr)
+
+	// the timed out call completes right before the call of other client, done is closed with a delay
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rr.Exec(&roadrunner.Payload{Context: []byte("{}"), Body: []byte("sleep:100ms")})
+		time.Sleep(20 * time.Millisecond)
+	}()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := rr.Exec(&roadrunner.Payload{Context: []byte("{}"), Body: []byte("sleep:500ms")})
+		result <- err
+	}()
+
+	for _, w := range rr.Workers() {
+		for w.State().Value() != roadrunner.StateWorking {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	killRunaway(rr, jobs, time.Second, done, func(w *roadrunner.Worker, reason string) {
+		t.Error("worker of unrelated call must not be killed")
+	})
+
+	assert.NoError(t, <-result)
+}
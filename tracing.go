@@ -0,0 +1,251 @@
+package grpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/spiral/roadrunner"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+	"time"
+)
+
+// EventSpan thrown when traced RPC call is complete, event context is *Span.
+const EventSpan = iota + 9100
+
+// Trace context is propagated using W3C Trace Context format (traceparent header) used by OpenTelemetry
+// by default, binary gRPC propagation format (grpc-trace-bin) is accepted when traceparent is missing.
+// Inbound trace is continued by every call, new trace is started otherwise.
+const (
+	traceparent = "traceparent"
+	tracestate  = "tracestate"
+	traceBinary = "grpc-trace-bin"
+)
+
+// TracerProvider bridges spans to the tracing SDK of your choice (OpenTelemetry and etc). Start is invoked
+// before the call is handled, returned context is passed down to interceptors and the worker proxy (use it
+// to carry the SDK span). End is invoked with the same context once the call is complete and span Code is set.
+type TracerProvider interface {
+	// Start starts SDK span for the given call span.
+	Start(ctx context.Context, s *Span) context.Context
+
+	// End ends SDK span of the call.
+	End(ctx context.Context, s *Span)
+}
+
+// Span describes single traced RPC call. Spans are delivered to service listeners (EventSpan), forward them
+// to the tracing backend of your choice.
+type Span struct {
+	// TraceID is hex encoded 16 bytes trace identifier.
+	TraceID string
+
+	// SpanID is hex encoded 8 bytes span identifier.
+	SpanID string
+
+	// ParentID identifies the inbound span, empty for root spans.
+	ParentID string
+
+	// Sampled indicates that caller recorded the trace (or that call started new trace).
+	Sampled bool
+
+	// State is inbound W3C tracestate value, forwarded to the worker as is.
+	State string
+
+	// Method is full name of RPC method.
+	Method string
+
+	// Code is call status code.
+	Code codes.Code
+
+	// PID of the PHP worker served the call, zero when unknown.
+	PID int
+
+	// Start time of the call.
+	Start time.Time
+
+	// Duration of the call.
+	Duration time.Duration
+}
+
+// Traceparent returns W3C traceparent value of the span.
+func (s *Span) Traceparent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", s.TraceID, s.SpanID, flags)
+}
+
+type spanKey struct{}
+
+// spanFromContext returns span of the current call or nil if tracing is disabled.
+func spanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// tracer creates spans for RPC calls and reports complete spans using throw function and provider (if any).
+type tracer struct {
+	throw    func(event int, ctx interface{})
+	provider TracerProvider
+}
+
+// startSpan creates new span continuing inbound trace if any.
+func (t *tracer) startSpan(ctx context.Context, method string) (context.Context, *Span) {
+	s := &Span{Method: method, Start: time.Now(), SpanID: randomID(8)}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(traceparent); len(v) != 0 {
+			s.TraceID, s.ParentID, s.Sampled, _ = parseTraceparent(v[0])
+		} else if v := md.Get(traceBinary); len(v) != 0 {
+			s.TraceID, s.ParentID, s.Sampled, _ = parseTraceBinary([]byte(v[0]))
+		}
+
+		if v := md.Get(tracestate); s.TraceID != "" && len(v) != 0 {
+			s.State = strings.Join(v, ",")
+		}
+	}
+
+	if s.TraceID == "" {
+		s.TraceID, s.Sampled = randomID(16), true
+	}
+
+	ctx = context.WithValue(ctx, spanKey{}, s)
+	if t.provider != nil {
+		ctx = t.provider.Start(ctx, s)
+	}
+
+	return ctx, s
+}
+
+// finishSpan completes the span and reports it, span code reflects the final status of the call.
+func (t *tracer) finishSpan(ctx context.Context, s *Span, err error) {
+	s.Code = status.Code(err)
+	s.Duration = time.Since(s.Start)
+
+	if t.provider != nil {
+		t.provider.End(ctx, s)
+	}
+
+	t.throw(EventSpan, s)
+}
+
+// unaryInterceptor traces unary calls.
+func (t *tracer) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx, s := t.startSpan(ctx, info.FullMethod)
+	resp, err := handler(ctx, req)
+	t.finishSpan(ctx, s, err)
+
+	return resp, err
+}
+
+// streamInterceptor traces streaming calls.
+func (t *tracer) streamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, s := t.startSpan(ss.Context(), info.FullMethod)
+	err := handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+	t.finishSpan(ctx, s, err)
+
+	return err
+}
+
+// tracedStream exposes span of the call via stream context.
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns stream context carrying the span.
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
+
+// parseTraceparent parses W3C traceparent value, invalid values are ignored.
+func parseTraceparent(value string) (traceID, parentID string, sampled, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return "", "", false, false
+	}
+
+	if !validID(parts[0], 1) || !validID(parts[1], 16) || !validID(parts[2], 8) || !validID(parts[3], 1) {
+		return "", "", false, false
+	}
+
+	flags, _ := hex.DecodeString(parts[3])
+	return parts[1], parts[2], flags[0]&1 == 1, true
+}
+
+// parseTraceBinary parses binary gRPC trace context (version 0: field 0 - trace id, field 1 - span id,
+// field 2 - trace options), invalid values are ignored.
+func parseTraceBinary(value []byte) (traceID, parentID string, sampled, ok bool) {
+	if len(value) != 29 || value[0] != 0 || value[1] != 0 || value[18] != 1 || value[27] != 2 {
+		return "", "", false, false
+	}
+
+	traceID, parentID = hex.EncodeToString(value[2:18]), hex.EncodeToString(value[19:27])
+	if !validID(traceID, 16) || !validID(parentID, 8) {
+		return "", "", false, false
+	}
+
+	return traceID, parentID, value[28]&1 == 1, true
+}
+
+// validID checks that value is lower case hex encoded id of given size (in bytes) and not all zeros.
+func validID(value string, size int) bool {
+	if len(value) != size*2 || strings.ToLower(value) != value {
+		return false
+	}
+
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		return false
+	}
+
+	// flags and version are allowed to be zero
+	if size == 1 {
+		return true
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// randomID generates hex encoded random id of given size (in bytes).
+func randomID(size int) string {
+	data := make([]byte, size)
+	rand.Read(data)
+
+	return hex.EncodeToString(data)
+}
+
+// workerPID returns PID reported by the worker in the response context.
+func workerPID(resp *roadrunner.Payload) int {
+	if len(resp.Context) == 0 {
+		return 0
+	}
+
+	rc := responseContext{}
+	json.Unmarshal(resp.Context, &rc)
+
+	return rc.PID
+}
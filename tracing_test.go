@@ -0,0 +1,211 @@
+package grpc
+
+import (
+	"encoding/json"
+	"github.com/spiral/roadrunner"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func Test_ParseTraceparent(t *testing.T) {
+	traceID, parentID, sampled, ok := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+	assert.Equal(t, "00f067aa0ba902b7", parentID)
+	assert.True(t, sampled)
+
+	_, _, sampled, ok = parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	assert.True(t, ok)
+	assert.False(t, sampled)
+
+	// future versions might carry additional fields
+	_, _, _, ok = parseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.True(t, ok)
+
+	for _, value := range []string{
+		"",
+		"invalid",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	} {
+		_, _, _, ok = parseTraceparent(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func Test_ParseTraceBinary(t *testing.T) {
+	value := []byte{0, 0}
+	value = append(value, 0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36)
+	value = append(value, 1, 0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7)
+	value = append(value, 2, 1)
+
+	traceID, parentID, sampled, ok := parseTraceBinary(value)
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+	assert.Equal(t, "00f067aa0ba902b7", parentID)
+	assert.True(t, sampled)
+
+	_, _, _, ok = parseTraceBinary(value[:20])
+	assert.False(t, ok)
+
+	zero := make([]byte, len(value))
+	copy(zero, value)
+	copy(zero[2:18], make([]byte, 16))
+
+	_, _, _, ok = parseTraceBinary(zero)
+	assert.False(t, ok)
+}
+
+func Test_Tracer_Unary(t *testing.T) {
+	spans := make([]*Span, 0)
+	tr := &tracer{throw: func(event int, ctx interface{}) {
+		assert.Equal(t, EventSpan, event)
+		spans = append(spans, ctx.(*Span))
+	}}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	))
+
+	_, err := tr.unaryInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		s := spanFromContext(ctx)
+		assert.NotNil(t, s)
+		s.PID = 100
+
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	assert.Error(t, err)
+
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "/service.Test/Echo", spans[0].Method)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].ParentID)
+	assert.Len(t, spans[0].SpanID, 16)
+	assert.NotEqual(t, spans[0].ParentID, spans[0].SpanID)
+	assert.True(t, spans[0].Sampled)
+	assert.Equal(t, codes.NotFound, spans[0].Code)
+	assert.Equal(t, 100, spans[0].PID)
+}
+
+func Test_Tracer_Root(t *testing.T) {
+	var span *Span
+	tr := &tracer{throw: func(event int, ctx interface{}) { span = ctx.(*Span) }}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", "invalid"))
+
+	_, err := tr.unaryInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, span.TraceID, 32)
+	assert.Equal(t, "", span.ParentID)
+	assert.True(t, span.Sampled)
+	assert.Equal(t, codes.OK, span.Code)
+
+	_, _, _, ok := parseTraceparent(span.Traceparent())
+	assert.True(t, ok)
+}
+
+type providerKey struct{}
+
+type mockProvider struct {
+	ended []*Span
+}
+
+func (p *mockProvider) Start(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, providerKey{}, s.SpanID)
+}
+
+func (p *mockProvider) End(ctx context.Context, s *Span) {
+	if ctx.Value(providerKey{}) == s.SpanID {
+		p.ended = append(p.ended, s)
+	}
+}
+
+func Test_Tracer_Provider(t *testing.T) {
+	provider := &mockProvider{}
+	tr := &tracer{throw: func(event int, ctx interface{}) {}, provider: provider}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/service.Test/Echo"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"tracestate", "vendor=value",
+	))
+
+	_, err := tr.unaryInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.NotNil(t, ctx.Value(providerKey{}))
+		return nil, status.Error(codes.PermissionDenied, "denied")
+	})
+	assert.Error(t, err)
+
+	assert.Len(t, provider.ended, 1)
+	assert.Equal(t, codes.PermissionDenied, provider.ended[0].Code)
+	assert.Equal(t, "vendor=value", provider.ended[0].State)
+}
+
+func Test_Tracer_Stream(t *testing.T) {
+	var span *Span
+	tr := &tracer{throw: func(event int, ctx interface{}) { span = ctx.(*Span) }}
+
+	info := &grpc.StreamServerInfo{FullMethod: "/service.Test/Stream"}
+
+	err := tr.streamInterceptor(nil, &mockStream{ctx: context.Background()}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		assert.NotNil(t, spanFromContext(stream.Context()))
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+	assert.Error(t, err)
+
+	assert.Equal(t, "/service.Test/Stream", span.Method)
+	assert.Equal(t, codes.Unavailable, span.Code)
+}
+
+func Test_Tracer_Payload(t *testing.T) {
+	tr := &tracer{throw: func(event int, ctx interface{}) {}}
+	ctx, s := tr.startSpan(context.Background(), "/app.Service/Method")
+
+	p := NewProxy("app.Service", "", nil)
+	payload, err := p.makePayload(ctx, "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc := rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+
+	assert.Equal(t, []string{s.TraceID}, rc.Context[":trace.id"])
+	assert.Equal(t, []string{s.SpanID}, rc.Context[":span.id"])
+	assert.Equal(t, []string{s.Traceparent()}, rc.Context[":traceparent"])
+	assert.NotContains(t, rc.Context, ":tracestate")
+
+	s.State = "vendor=value"
+	payload, err = p.makePayload(ctx, "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc = rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+	assert.Equal(t, []string{"vendor=value"}, rc.Context[":tracestate"])
+
+	// not traced
+	payload, err = p.makePayload(context.Background(), "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc = rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+	assert.NotContains(t, rc.Context, ":trace.id")
+}
+
+func Test_WorkerPID(t *testing.T) {
+	assert.Equal(t, 0, workerPID(&roadrunner.Payload{}))
+	assert.Equal(t, 0, workerPID(&roadrunner.Payload{Context: []byte(`{"headers":{}}`)}))
+	assert.Equal(t, 42, workerPID(&roadrunner.Payload{Context: []byte(`{"pid":42}`)}))
+}
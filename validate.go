@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spiral/roadrunner"
+	"sort"
+	"strings"
+)
+
+// manifestMethod asks the worker for services and methods it implements, worker responds with JSON object
+// mapping service names to method names.
+const manifestMethod = ":manifest"
+
+// validateMethods makes sure every proxied method is implemented by PHP workers, error lists missing methods.
+func (svc *Service) validateMethods() error {
+	ctx, err := json.Marshal(rpcContext{Method: manifestMethod})
+	if err != nil {
+		return err
+	}
+
+	resp, err := svc.rr.Exec(&roadrunner.Payload{Context: ctx})
+	if err != nil {
+		return fmt.Errorf("unable to fetch methods implemented by worker: %s", err)
+	}
+
+	manifest := make(map[string][]string)
+	if err := json.Unmarshal(resp.Body, &manifest); err != nil {
+		return fmt.Errorf("invalid worker manifest: %s", err)
+	}
+
+	svc.mu.Lock()
+	missing := missingMethods(svc.proxies, manifest)
+	svc.mu.Unlock()
+
+	if len(missing) != 0 {
+		return fmt.Errorf("methods are not implemented by worker: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// missingMethods returns full names of proxied methods not listed in the worker manifest.
+func missingMethods(proxies []*Proxy, manifest map[string][]string) []string {
+	missing := make([]string, 0)
+	for _, p := range proxies {
+		implemented := make(map[string]bool)
+		for _, m := range manifest[p.name] {
+			implemented[m] = true
+		}
+
+		names := append([]string{}, p.methods...)
+		for _, m := range p.streams {
+			names = append(names, m.name)
+		}
+
+		for _, m := range names {
+			if !implemented[m] {
+				missing = append(missing, fmt.Sprintf("/%s/%s", p.name, m))
+			}
+		}
+	}
+
+	sort.Strings(missing)
+	return missing
+}
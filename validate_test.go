@@ -0,0 +1,26 @@
+package grpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_MissingMethods(t *testing.T) {
+	echo := NewProxy("app.Echo", "", nil)
+	echo.RegisterMethod("Ping")
+	echo.RegisterMethod("Info")
+	echo.RegisterStream("Watch", true, false)
+
+	report := NewProxy("app.Report", "", nil)
+	report.RegisterMethod("Build")
+
+	assert.Equal(t, []string{}, missingMethods([]*Proxy{echo, report}, map[string][]string{
+		"app.Echo":   {"Ping", "Info", "Watch"},
+		"app.Report": {"Build", "Other"},
+	}))
+
+	assert.Equal(t, []string{"/app.Echo/Info", "/app.Echo/Watch", "/app.Report/Build"}, missingMethods(
+		[]*Proxy{echo, report},
+		map[string][]string{"app.Echo": {"Ping"}},
+	))
+}
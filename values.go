@@ -0,0 +1,36 @@
+package grpc
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ValuesFunc computes values passed to PHP worker with the call (for example tenant ID derived from metadata).
+// Error fails the call, status errors are returned to the client as is and reported as Internal otherwise.
+type ValuesFunc func(ctx context.Context) (map[string]string, error)
+
+// callValues merges values computed by every function, values of later functions take precedence.
+func callValues(ctx context.Context, funcs []ValuesFunc) (map[string]string, error) {
+	if len(funcs) == 0 {
+		return nil, nil
+	}
+
+	values := make(map[string]string)
+	for _, f := range funcs {
+		v, err := f(ctx)
+		if err != nil {
+			if _, ok := status.FromError(err); ok {
+				return nil, err
+			}
+
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		for k, value := range v {
+			values[k] = value
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func tenant(ctx context.Context) (map[string]string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("x-tenant")) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	return map[string]string{"tenant": md.Get("x-tenant")[0]}, nil
+}
+
+func Test_Values(t *testing.T) {
+	p := NewProxy("app.Service", "", nil)
+	p.values = []ValuesFunc{tenant, func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{"region": "eu"}, nil
+	}}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "acme"))
+
+	payload, err := p.makePayload(ctx, "Method", rawMessage("body"))
+	assert.NoError(t, err)
+
+	rc := rpcContext{}
+	assert.NoError(t, json.Unmarshal(payload.Context, &rc))
+	assert.Equal(t, map[string]string{"tenant": "acme", "region": "eu"}, rc.Values)
+
+	// status errors are returned as is
+	_, err = p.makePayload(context.Background(), "Method", rawMessage("body"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_Values_Error(t *testing.T) {
+	_, err := callValues(context.Background(), []ValuesFunc{func(ctx context.Context) (map[string]string, error) {
+		return nil, errors.New("failure")
+	}})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	// no values are passed by default
+	p := NewProxy("app.Service", "", nil)
+	payload, err := p.makePayload(context.Background(), "Method", rawMessage("body"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(payload.Context), "values")
+}
@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spiral/roadrunner"
+	"strings"
+	"sync"
+)
+
+// EventWarmupError thrown when warmup call failed, event context is error. Warmup failures are not fatal.
+const EventWarmupError = iota + 9500
+
+// warmupMethod is built-in no-op call of PHP worker.
+const warmupMethod = ":warmup"
+
+// warmup issues configured number of synthetic calls to every worker of every pool before the server accepts calls,
+// calls are made concurrently by as many callers as there are workers so every worker receives it's share.
+func (svc *Service) warmup() {
+	service, method := "", warmupMethod
+	if svc.cfg.Warmup.Method != "" {
+		chunks := strings.Split(svc.cfg.Warmup.Method, "/")
+		service, method = chunks[1], chunks[2]
+	}
+
+	ctx, err := json.Marshal(rpcContext{
+		Service: service,
+		Method:  method,
+		Context: map[string][]string{":warmup": {"true"}},
+	})
+	if err != nil {
+		svc.throw(EventWarmupError, err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, rr := range svc.servers() {
+		for i := 0; i < len(rr.Workers()); i++ {
+			wg.Add(1)
+			go func(rr *roadrunner.Server) {
+				defer wg.Done()
+				for n := 0; n < svc.cfg.Warmup.Count; n++ {
+					if _, err := rr.Exec(&roadrunner.Payload{Context: ctx}); err != nil {
+						svc.throw(EventWarmupError, fmt.Errorf("warmup call %s failed: %s", svc.cfg.Warmup.name(), err))
+						return
+					}
+				}
+			}(rr)
+		}
+	}
+
+	wg.Wait()
+}
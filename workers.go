@@ -0,0 +1,165 @@
+package grpc
+
+import (
+	"github.com/spiral/roadrunner"
+	"github.com/spiral/roadrunner/util"
+	"sync"
+	"time"
+)
+
+// PoolStatsVersion is the version of PoolStats structure, incremented on incompatible changes.
+const PoolStatsVersion = 1
+
+// WorkerState describes worker of the pool, same as RoadRunner worker state with the last worker error.
+type WorkerState struct {
+	util.State
+
+	// Error contains last error reported by the worker (crash, stop failure and etc), empty if none.
+	Error string `json:"error,omitempty"`
+
+	// Pool is name of the worker pool ("default" for Workers pool), empty unless dedicated pools are configured.
+	Pool string `json:"pool,omitempty"`
+}
+
+// PoolStats describes worker pool state.
+type PoolStats struct {
+	// Version of the structure (PoolStatsVersion).
+	Version int `json:"version"`
+
+	// Workers is number of pool workers.
+	Workers int `json:"workers"`
+
+	// Busy is number of workers executing calls.
+	Busy int `json:"busy"`
+
+	// Executions is total number of calls executed by current pool workers.
+	Executions int64 `json:"executions"`
+
+	// MemoryUsage is total memory used by pool workers in bytes.
+	MemoryUsage uint64 `json:"memoryUsage"`
+
+	// WorkerStats describes every pool worker.
+	WorkerStats []*WorkerStats `json:"workerStats"`
+
+	// Name of the pool, set for stats of individual pools.
+	Name string `json:"name,omitempty"`
+
+	// Pools lists stats of individual pools when dedicated pools are configured, top level stats cover workers
+	// of every pool.
+	Pools []*PoolStats `json:"pools,omitempty"`
+}
+
+// WorkerStats describes pool worker.
+type WorkerStats struct {
+	// Pid contains process id.
+	Pid int `json:"pid"`
+
+	// Status of the worker (ready, working and etc).
+	Status string `json:"status"`
+
+	// Executions is number of calls executed by the worker.
+	Executions int64 `json:"executions"`
+
+	// MemoryUsage is worker memory usage (RSS) in bytes.
+	MemoryUsage uint64 `json:"memoryUsage"`
+
+	// Uptime is number of seconds since the worker was created.
+	Uptime int64 `json:"uptime"`
+}
+
+// poolStats aggregates states of pool workers.
+func poolStats(states []*WorkerState, now time.Time) *PoolStats {
+	stats := &PoolStats{Version: PoolStatsVersion, WorkerStats: make([]*WorkerStats, 0, len(states))}
+
+	for _, s := range states {
+		stats.Workers++
+		if s.Status == "working" {
+			stats.Busy++
+		}
+		stats.Executions += s.NumJobs
+		stats.MemoryUsage += s.MemoryUsage
+
+		stats.WorkerStats = append(stats.WorkerStats, &WorkerStats{
+			Pid:         s.Pid,
+			Status:      s.Status,
+			Executions:  s.NumJobs,
+			MemoryUsage: s.MemoryUsage,
+			Uptime:      int64(now.Sub(time.Unix(0, s.Created)) / time.Second),
+		})
+	}
+
+	return stats
+}
+
+// poolsStats aggregates states of workers of every pool and lists stats of the named pools.
+func poolsStats(states []*WorkerState, pools []string, now time.Time) *PoolStats {
+	stats := poolStats(states, now)
+	for _, name := range pools {
+		pool := make([]*WorkerState, 0)
+		for _, s := range states {
+			if s.Pool == name {
+				pool = append(pool, s)
+			}
+		}
+
+		ps := poolStats(pool, now)
+		ps.Name = name
+		stats.Pools = append(stats.Pools, ps)
+	}
+
+	return stats
+}
+
+// workerErrors keeps last error of pool workers.
+type workerErrors struct {
+	mu     sync.Mutex
+	errors map[*roadrunner.Worker]string
+}
+
+// handle records worker errors and forgets destroyed workers.
+func (e *workerErrors) handle(event int, ctx interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch event {
+	case roadrunner.EventWorkerError:
+		we, ok := ctx.(roadrunner.WorkerError)
+		if !ok || we.Worker == nil {
+			return
+		}
+
+		if e.errors == nil {
+			e.errors = make(map[*roadrunner.Worker]string)
+		}
+		e.errors[we.Worker] = we.Caused.Error()
+
+	case roadrunner.EventWorkerDestruct, roadrunner.EventWorkerKill:
+		if w, ok := ctx.(*roadrunner.Worker); ok {
+			delete(e.errors, w)
+		}
+	}
+}
+
+// states returns states of given workers, errors of workers no longer present are dropped.
+func (e *workerErrors) states(workers []*roadrunner.Worker) ([]*WorkerState, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	active := make(map[*roadrunner.Worker]string, len(workers))
+	result := make([]*WorkerState, 0, len(workers))
+	for _, w := range workers {
+		state, err := util.WorkerState(w)
+		if err != nil {
+			return nil, err
+		}
+
+		if msg, ok := e.errors[w]; ok {
+			active[w] = msg
+		}
+
+		result = append(result, &WorkerState{State: *state, Error: active[w]})
+	}
+
+	e.errors = active
+	return result, nil
+}
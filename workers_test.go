@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"errors"
+	"github.com/spiral/roadrunner"
+	"github.com/spiral/roadrunner/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_WorkerErrors(t *testing.T) {
+	e := &workerErrors{}
+	w1, w2 := &roadrunner.Worker{}, &roadrunner.Worker{}
+
+	e.handle(roadrunner.EventWorkerError, roadrunner.WorkerError{Worker: w1, Caused: errors.New("first")})
+	e.handle(roadrunner.EventWorkerError, roadrunner.WorkerError{Worker: w1, Caused: errors.New("last")})
+	e.handle(roadrunner.EventWorkerError, roadrunner.WorkerError{Worker: w2, Caused: errors.New("error")})
+	e.handle(roadrunner.EventWorkerError, errors.New("pool error"))
+
+	assert.Equal(t, "last", e.errors[w1])
+	assert.Equal(t, "error", e.errors[w2])
+
+	e.handle(roadrunner.EventWorkerDestruct, w2)
+	assert.Len(t, e.errors, 1)
+
+	// errors of workers no longer in the pool are dropped
+	states, err := e.states(nil)
+	assert.NoError(t, err)
+	assert.Len(t, states, 0)
+	assert.Len(t, e.errors, 0)
+}
+
+func Test_PoolStats(t *testing.T) {
+	now := time.Now()
+	stats := poolStats([]*WorkerState{
+		{State: util.State{Pid: 1, Status: "working", NumJobs: 10, MemoryUsage: 100, Created: now.Add(-time.Minute).UnixNano()}},
+		{State: util.State{Pid: 2, Status: "ready", NumJobs: 5, MemoryUsage: 50, Created: now.UnixNano()}},
+	}, now)
+
+	assert.Equal(t, PoolStatsVersion, stats.Version)
+	assert.Equal(t, 2, stats.Workers)
+	assert.Equal(t, 1, stats.Busy)
+	assert.Equal(t, int64(15), stats.Executions)
+	assert.Equal(t, uint64(150), stats.MemoryUsage)
+
+	assert.Len(t, stats.WorkerStats, 2)
+	assert.Equal(t, &WorkerStats{Pid: 1, Status: "working", Executions: 10, MemoryUsage: 100, Uptime: 60}, stats.WorkerStats[0])
+	assert.Equal(t, int64(0), stats.WorkerStats[1].Uptime)
+
+	empty := poolStats(nil, now)
+	assert.Equal(t, 0, empty.Workers)
+	assert.NotNil(t, empty.WorkerStats)
+}